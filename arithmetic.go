@@ -0,0 +1,34 @@
+package lmc
+
+// Arithmetic decides what happens when the accumulator holds a number with more digits than fit in a mailbox.
+type Arithmetic string
+
+// Arithmetic definitions
+const (
+	// ArithmeticUnbounded lets the accumulator hold any number, and storing one that doesn't fit writes more digits
+	// than a mailbox has. It's the default.
+	ArithmeticUnbounded Arithmetic = "unbounded"
+
+	// ArithmeticWrap keeps only as many of the low digits of the accumulator as fit in a mailbox, along with its sign,
+	// so that with three digit mailboxes adding 1 to 999 gives 0.
+	ArithmeticWrap Arithmetic = "wrap"
+
+	// ArithmeticChecked stops the program with ErrAccumulatorRange if the accumulator doesn't fit in a mailbox.
+	ArithmeticChecked Arithmetic = "checked"
+)
+
+// arithmetic applies the computer's arithmetic mode to the accumulator once an instruction has been executed.
+func (c *Computer) arithmetic() error {
+	limit := pow(c.Mailboxes.radix, c.Mailboxes.width)
+
+	switch c.Arithmetic {
+	case ArithmeticWrap:
+		c.Accumulator %= limit
+	case ArithmeticChecked:
+		if c.Accumulator >= limit || c.Accumulator <= -limit {
+			return ErrAccumulatorRange{c.ProgramCounter, c.Accumulator}
+		}
+	}
+
+	return nil
+}
@@ -1,43 +1,195 @@
 package lmc
 
-import (
-	"fmt"
-	"strings"
-)
+import "sort"
+
+// SourceMap maps mailbox addresses back to the instructions that were assembled into them.
+type SourceMap map[int]Instruction
+
+// Line returns the zero-indexed line of the source that the instruction in a mailbox came from.
+func (s SourceMap) Line(addr int) (int, bool) {
+	instruction, ok := s[addr]
+	if !ok || instruction.MnemonicToken.Type == "" {
+		return 0, false
+	}
+
+	return instruction.MnemonicToken.Line, true
+}
+
+// Addresses returns the mailboxes holding instructions from a zero-indexed line of the source, in ascending order.
+func (s SourceMap) Addresses(line int) []int {
+	addrs := []int{}
+
+	for addr := range s {
+		if l, ok := s.Line(addr); ok && l == line {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	sort.Ints(addrs)
+	return addrs
+}
 
 // Assemble takes in a list of instructions and outputs mailboxes with those instructions loaded.
 func Assemble(instructions []Instruction, opcodeSize, operandSize int) *Mailboxes {
-	mailboxes := NewMailboxes(opcodeSize, operandSize)
+	return DefaultInstructionSet.Assemble(instructions, opcodeSize, operandSize)
+}
+
+// AssembleWithSourceMap is like Assemble, but also returns a map from each mailbox that was loaded to the instruction
+// that was assembled into it.
+func AssembleWithSourceMap(instructions []Instruction, opcodeSize, operandSize int) (*Mailboxes, SourceMap) {
+	return DefaultInstructionSet.AssembleWithSourceMap(instructions, opcodeSize, operandSize)
+}
+
+// Validate checks a list of instructions for problems that Assemble doesn't report, such as undefined labels or
+// operands that don't fit into a mailbox. It returns every problem found, in the order they appear.
+func Validate(instructions []Instruction, opcodeSize, operandSize int) []error {
+	return DefaultInstructionSet.Validate(instructions, opcodeSize, operandSize)
+}
 
-	labelMap := make(map[string]string)
+// isData returns true if an instruction stores its operand as it is, like DAT, rather than being assembled.
+func (s *InstructionSet) isData(instruction Instruction) bool {
+	def, ok := s.Lookup(instruction.Mnemonic)
+	return ok && def.Kind == OperandData
+}
+
+// Assemble is like the Assemble function, but for programs written using this instruction set.
+func (s *InstructionSet) Assemble(instructions []Instruction, opcodeSize, operandSize int) *Mailboxes {
+	mailboxes, _ := s.AssembleWithSourceMap(instructions, opcodeSize, operandSize)
+	return mailboxes
+}
+
+// AssembleWithSourceMap is like the AssembleWithSourceMap function, but for programs written using this instruction
+// set.
+func (s *InstructionSet) AssembleWithSourceMap(
+	instructions []Instruction, opcodeSize, operandSize int,
+) (*Mailboxes, SourceMap) {
+	return s.AssembleRadix(instructions, 10, opcodeSize, operandSize)
+}
+
+// AssembleRadix is like AssembleWithSourceMap, but for a computer whose mailboxes hold numbers in the radix given,
+// where the sizes are numbers of digits in that radix. Operands in the program can be written in any radix, using
+// 0x, 0o and 0b for hexadecimal, octal and binary.
+func (s *InstructionSet) AssembleRadix(
+	instructions []Instruction, radix, opcodeSize, operandSize int,
+) (*Mailboxes, SourceMap) {
+	mailboxes := NewRadixMailboxes(radix, opcodeSize, operandSize)
+	sourceMap := make(SourceMap)
+
+	// pad writes an operand with the digits given, leaving it as it is if it isn't a number.
+	pad := func(operand string, digits int) string {
+		n, err := ParseInt(operand)
+		if err != nil {
+			return leftPad(operand, digits)
+		}
+
+		return formatInt(n, radix, digits)
+	}
+
+	labelMap := make(map[string]int)
 
 	for i, instruction := range instructions {
 		if instruction.Label != "" {
-			labelMap[instruction.Label] = leftPadInt(i, (opcodeSize + operandSize))
+			labelMap[instruction.Label] = i
 		}
 	}
 
+	// Undefined labels and operands that are too long are assembled as they are, it's up to Validate to report them.
 	for i, instruction := range instructions {
-		if instruction.Mnemonic == "DAT" {
-			if instruction.Operand != "" && isIdentifier(instruction.Operand) {
-				mailboxes.Set(i, labelMap[instruction.Operand]) // TODO: check if label is valid
+		sourceMap[i] = instruction
+
+		if s.isData(instruction) {
+			if isIdentifier(instruction.Operand) {
+				mailboxes.Set(i, formatInt(labelMap[instruction.Operand], radix, opcodeSize+operandSize))
 			} else {
-				// TODO: check if number isn't valid
-				mailboxes.Set(i, strings.Repeat("0", (operandSize+opcodeSize)-len(instruction.Operand))+instruction.Operand)
+				mailboxes.Set(i, pad(instruction.Operand, opcodeSize+operandSize))
 			}
 		} else {
-			opcode := fmt.Sprint(instruction.Opcode)
-			operand := fmt.Sprint(instruction.Operand)
+			operand := instruction.Operand
 
-			if instruction.Operand != "" && isIdentifier(operand) {
-				operand = fmt.Sprint(labelMap[instruction.Operand])[opcodeSize:] // TODO: check if label is valid
+			if isIdentifier(operand) {
+				operand = formatInt(labelMap[instruction.Operand], radix, operandSize)
+			} else {
+				operand = pad(operand, operandSize)
 			}
 
-			operand = strings.Repeat("0", operandSize-len(operand)) + operand
-			opcode = strings.Repeat("0", opcodeSize-len(opcode)) + opcode
-			mailboxes.Set(i, opcode+operand)
+			mailboxes.Set(i, formatInt(instruction.Opcode, radix, opcodeSize)+operand)
 		}
 	}
 
-	return mailboxes
+	return mailboxes, sourceMap
+}
+
+// MaxMailboxes is the most mailboxes a computer can have. Memory has a mailbox for every number that fits in one, so
+// this limits how many digits they can have.
+const MaxMailboxes = 1000000
+
+// Validate is like the Validate function, but for programs written using this instruction set.
+func (s *InstructionSet) Validate(instructions []Instruction, opcodeSize, operandSize int) []error {
+	return s.ValidateRadix(instructions, 10, opcodeSize, operandSize)
+}
+
+// ValidateRadix is like Validate, but for a computer whose mailboxes hold numbers in the radix given, as assembled by
+// AssembleRadix.
+func (s *InstructionSet) ValidateRadix(instructions []Instruction, radix, opcodeSize, operandSize int) []error {
+	size := 1
+	for i := 0; i < opcodeSize+operandSize && size <= MaxMailboxes; i++ {
+		size *= radix
+	}
+
+	if radix < 2 || radix > 36 || opcodeSize < 1 || operandSize < 1 || size > MaxMailboxes {
+		return []error{ErrMailboxSize{radix, opcodeSize, operandSize}}
+	}
+
+	errs := []error{}
+	labels := make(map[string]bool)
+
+	for _, instruction := range instructions {
+		if instruction.Label == "" {
+			continue
+		}
+
+		if labels[instruction.Label] {
+			errs = append(errs, ErrDuplicateLabel{instruction.LabelToken})
+		}
+
+		labels[instruction.Label] = true
+	}
+
+	for i, instruction := range instructions {
+		if i == pow(radix, operandSize) {
+			errs = append(errs, ErrProgramSize{instruction.MnemonicToken, i})
+		}
+
+		def, ok := s.Lookup(instruction.Mnemonic)
+		if ok && def.Kind != OperandData && def.Opcode >= pow(radix, opcodeSize) {
+			errs = append(errs, ErrOpcodeRange{instruction.MnemonicToken, opcodeSize})
+		}
+
+		if ok && def.Kind != OperandData && def.Kind != OperandAddress && def.Operand >= pow(radix, operandSize) {
+			errs = append(errs, ErrFixedOperandRange{instruction.MnemonicToken, def.Operand, operandSize})
+		}
+
+		if instruction.OperandToken.Type == "" {
+			continue
+		}
+
+		if instruction.OperandToken.Type == IDENT {
+			if !labels[instruction.Operand] {
+				errs = append(errs, ErrUndefinedLabel{instruction.OperandToken})
+			}
+
+			continue
+		}
+
+		digits := operandSize
+		if s.isData(instruction) {
+			digits = opcodeSize + operandSize
+		}
+
+		if n, err := ParseInt(instruction.Operand); err != nil || n >= pow(radix, digits) {
+			errs = append(errs, ErrOperandRange{instruction.OperandToken, digits})
+		}
+	}
+
+	return errs
 }
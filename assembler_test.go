@@ -133,3 +133,86 @@ func TestAssemble(t *testing.T) {
 		}
 	}
 }
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		errors []string
+	}{
+		{
+			"valid",
+			`INP
+			 STA num1
+			 num1 DAT 0`,
+			[]string{},
+		},
+		{
+			"undefined-label",
+			`BRA nowhere`,
+			[]string{"undefined label: nowhere"},
+		},
+		{
+			"duplicate-label",
+			`a DAT 1
+			 a DAT 2`,
+			[]string{"label a is already defined"},
+		},
+		{
+			"operand-range",
+			`LDA 100
+			 DAT 1000`,
+			[]string{"operand 100 does not fit in 2 digits", "operand 1000 does not fit in 3 digits"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			instructions, err := lmc.NewParser(lmc.NewLexer(tc.input)).Parse()
+			assert.NoError(t, err, "not expecting error when executing parser")
+
+			errs := []string{}
+			for _, err := range lmc.Validate(instructions, 1, 2) {
+				errs = append(errs, err.Error())
+			}
+
+			assert.Equal(t, tc.errors, errs)
+		})
+	}
+}
+
+func TestValidateSizes(t *testing.T) {
+	instructions, err := lmc.NewParser(lmc.NewLexer("INA\nOUT\nHLT")).Parse()
+	assert.NoError(t, err)
+
+	assert.Empty(t, lmc.Validate(instructions, 1, 2))
+	assert.Equal(t, []error{lmc.ErrFixedOperandRange{Token: instructions[0].MnemonicToken, Operand: 11, Digits: 1}},
+		lmc.Validate(instructions, 2, 1), "expect fixed operands to need to fit")
+
+	assert.Equal(t, []error{lmc.ErrMailboxSize{Radix: 10, OpcodeSize: 0, OperandSize: 2}}, lmc.Validate(instructions, 0, 2))
+	assert.Equal(t, []error{lmc.ErrMailboxSize{Radix: 10, OpcodeSize: 3, OperandSize: 4}}, lmc.Validate(instructions, 3, 4))
+}
+
+func TestAssembleWithSourceMap(t *testing.T) {
+	input := `INP
+
+loop OUT
+	 // comment
+	 BRA loop`
+
+	instructions, err := lmc.NewParser(lmc.NewLexer(input)).Parse()
+	assert.NoError(t, err, "not expecting error when executing parser")
+
+	_, sourceMap := lmc.AssembleWithSourceMap(instructions, 1, 2)
+
+	for addr, line := range []int{0, 2, 4} {
+		got, ok := sourceMap.Line(addr)
+		assert.True(t, ok, "expecting mailbox %d to have a source line", addr)
+		assert.Equal(t, line, got, "expecting mailbox %d to be from line %d, got %d", addr, line, got)
+		assert.Equal(t, []int{addr}, sourceMap.Addresses(line))
+	}
+
+	_, ok := sourceMap.Line(3)
+	assert.False(t, ok, "not expecting mailbox 3 to have a source line")
+	assert.Equal(t, []int{}, sourceMap.Addresses(1))
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ollybritton/go-lmc/compiler"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// compileCmd represents the compile command
+var compileCmd = &cobra.Command{
+	Use:   "compile <program>",
+	Short: "Compile a high level program into LMC assembly",
+	Long: `Compile a program written in a small structured language into LMC assembly,
+which is printed or written to the file given by --output. For example:
+
+    n = input()
+    while n > 0 {
+        print(n * n)
+        n = n - 1
+    }
+
+The language has variables, integer arithmetic (+ - * / %), if/else, while loops,
+input() and print().`,
+	Args: cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		output, err := cmd.Flags().GetString("output")
+		checkFlagErr(err)
+
+		bytes, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			logrus.Fatalf("Error reading file: %s", err)
+		}
+
+		code, err := compiler.Compile(string(bytes))
+		if err != nil {
+			logrus.Fatalf("%s:%s", args[0], err)
+		}
+
+		if output == "" {
+			fmt.Print(code)
+			return
+		}
+
+		err = ioutil.WriteFile(output, []byte(code), 0644)
+		if err != nil {
+			logrus.Fatalf("Error writing file: %s", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(compileCmd)
+
+	compileCmd.Flags().StringP("output", "o", "", "file to write the assembly to, instead of printing it")
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/ollybritton/go-lmc/dap"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// dapCmd represents the dap command
+var dapCmd = &cobra.Command{
+	Use:   "dap",
+	Short: "Run a debug adapter over stdin and stdout",
+	Long: `Run a Debug Adapter Protocol server over stdin and stdout, so that editors can set
+breakpoints, step through programs, inspect the accumulator, program counter and
+mailboxes, and type INP values into the debug console.
+
+Launching with "microStep": true makes stepping stop after each phase of an
+instruction, fetch, decode, operand fetch, execute and write-back, with the
+MAR, MDR and CIR shown alongside the other registers.
+
+Launching with "replay" set to a session recorded by lmc run --record rather
+than "program" replays it under the debugger, giving the program the inputs
+that were recorded and stopping it if it does anything differently.`,
+	Args: cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		opcodeSize, err := cmd.Flags().GetInt("opcode-size")
+		checkFlagErr(err)
+		operandSize, err := cmd.Flags().GetInt("operand-size")
+		checkFlagErr(err)
+
+		// Anything logged to stdout would corrupt the protocol stream.
+		logrus.SetOutput(os.Stderr)
+
+		server := dap.NewServer(os.Stdin, os.Stdout)
+		server.OpcodeSize = opcodeSize
+		server.OperandSize = operandSize
+
+		if err := server.Serve(); err != nil {
+			logrus.Fatalf("Error running debug adapter: %s", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dapCmd)
+
+	dapCmd.Flags().IntP("opcode-size", "c", 1, "size of opcode, in digits")
+	dapCmd.Flags().IntP("operand-size", "r", 2, "size of operand, in digits")
+}
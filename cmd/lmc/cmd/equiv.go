@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/equiv"
+	"github.com/ollybritton/go-lmc/spec"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// equivCmd represents the equiv command
+var equivCmd = &cobra.Command{
+	Use:   "equiv <reference> <program>",
+	Short: "Check that two programs behave the same way",
+	Long: `Run two programs on the same inputs and compare what they read and output.
+If they ever differ, the first inputs they differ on are printed along with what
+each program did, side by side, and the command exits with status 1.
+
+By default every sequence of inputs in the range is tried, up to --max-inputs
+long. Sequences are only extended while a program asks for more input, so
+checking a program that reads one number only takes one run per number. Use
+--random to try random sequences instead when there are too many to try them all:
+
+    lmc equiv examples/square.lmc square-optimised.lmc --inputs 0..99 --max-inputs 3`,
+	Args: cobra.ExactArgs(2),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		opcodeSize, err := cmd.Flags().GetInt("opcode-size")
+		checkFlagErr(err)
+		operandSize, err := cmd.Flags().GetInt("operand-size")
+		checkFlagErr(err)
+		inputs, err := cmd.Flags().GetString("inputs")
+		checkFlagErr(err)
+		maxInputs, err := cmd.Flags().GetInt("max-inputs")
+		checkFlagErr(err)
+		maxCycles, err := cmd.Flags().GetInt("max-cycles")
+		checkFlagErr(err)
+		random, err := cmd.Flags().GetInt("random")
+		checkFlagErr(err)
+		seed, err := cmd.Flags().GetInt64("seed")
+		checkFlagErr(err)
+
+		min, max, err := equiv.ParseRange(inputs)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		programs := make([][]lmc.Instruction, 2)
+		for i, filename := range args {
+			bytes, err := ioutil.ReadFile(filename)
+			if err != nil {
+				logrus.Fatalf("Error reading file: %s", err)
+			}
+
+			programs[i], err = lmc.NewParser(lmc.NewLexer(string(bytes))).Parse()
+			if err != nil {
+				logrus.Fatalf("%s: %s", filename, err)
+			}
+
+			if errs := lmc.Validate(programs[i], opcodeSize, operandSize); len(errs) != 0 {
+				logrus.Fatalf("%s: %s", filename, errs[0])
+			}
+		}
+
+		d, tried, err := equiv.Compare(programs[0], programs[1], equiv.Options{
+			OpcodeSize:  opcodeSize,
+			OperandSize: operandSize,
+			Min:         min,
+			Max:         max,
+			MaxInputs:   maxInputs,
+			MaxCycles:   maxCycles,
+			Random:      random,
+			Seed:        seed,
+		})
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		if d == nil {
+			fmt.Printf("equivalent on %d input sequences\n", tried)
+			return
+		}
+
+		fmt.Printf("programs differ after %d input sequences\n", tried)
+		err = d.WriteSideBySide(os.Stdout, filepath.Base(args[0]), filepath.Base(args[1]))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		os.Exit(1)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(equivCmd)
+
+	equivCmd.Flags().IntP("opcode-size", "c", 1, "size of opcode, in digits")
+	equivCmd.Flags().IntP("operand-size", "r", 2, "size of operand, in digits")
+
+	equivCmd.Flags().StringP("inputs", "i", "0..99", "range of values to try for each input")
+	equivCmd.Flags().IntP("max-inputs", "n", 3, "longest sequence of inputs to try")
+	equivCmd.Flags().Int("max-cycles", spec.DefaultMaxCycles, "cycle budget for each run")
+	equivCmd.Flags().Int("random", 0, "try this many random input sequences instead of all of them")
+	equivCmd.Flags().Int64("seed", 1, "seed for random input sequences")
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/ollybritton/go-lmc/grade"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// gradeCmd represents the grade command
+var gradeCmd = &cobra.Command{
+	Use:   "grade <assignment.json> <submissions>",
+	Short: "Mark a directory of student submissions against an assignment",
+	Long: `Run every submission in a directory against the weighted test cases of an assignment
+and report each student's score.
+
+Submissions are either .lmc files directly inside the directory, named after the
+student, or subdirectories named after the student containing a .lmc file. The
+assignment can limit the number of cycles, mailboxes and time each program may use,
+and ban mnemonics or self-modifying code.
+
+Submissions are graded in parallel, as many at once as there are CPUs unless
+--parallel says otherwise, and -p 1 grades them one at a time. Reports are in
+the same order either way.
+
+Reports are written as CSV to stdout unless --csv or --json are given.`,
+	Args: cobra.ExactArgs(2),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		csvPath, err := cmd.Flags().GetString("csv")
+		checkFlagErr(err)
+		jsonPath, err := cmd.Flags().GetString("json")
+		checkFlagErr(err)
+		parallel, err := cmd.Flags().GetInt("parallel")
+		checkFlagErr(err)
+
+		assignment, err := grade.LoadAssignment(args[0])
+		if err != nil {
+			logrus.Fatalf("Error loading assignment: %s", err)
+		}
+
+		subs, err := grade.Submissions(args[1])
+		if err != nil {
+			logrus.Fatalf("Error finding submissions: %s", err)
+		}
+
+		reports := assignment.GradeAll(subs, parallel)
+
+		if csvPath == "" && jsonPath == "" {
+			if err := grade.WriteCSV(os.Stdout, reports); err != nil {
+				logrus.Fatalf("Error writing report: %s", err)
+			}
+
+			return
+		}
+
+		writeReport(csvPath, reports, grade.WriteCSV)
+		writeReport(jsonPath, reports, grade.WriteJSON)
+	},
+}
+
+// writeReport writes reports to a file using the writer given, doing nothing if path is empty.
+func writeReport(path string, reports []grade.Report, write func(w io.Writer, reports []grade.Report) error) {
+	if path == "" {
+		return
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		logrus.Fatalf("Error creating report: %s", err)
+	}
+	defer f.Close()
+
+	if err := write(f, reports); err != nil {
+		logrus.Fatalf("Error writing report: %s", err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(gradeCmd)
+
+	gradeCmd.Flags().String("csv", "", "write a CSV report to this file")
+	gradeCmd.Flags().String("json", "", "write a JSON report, with explanations for every failure, to this file")
+	gradeCmd.Flags().IntP("parallel", "p", runtime.NumCPU(), "number of submissions to grade at once")
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ollybritton/go-lmc"
+	"golang.org/x/term"
+)
+
+// Policies for what happens when a program asks for more input than it was given.
+const (
+	exhaustedError   = "error"   // Stop the program with an error.
+	exhaustedDefault = "default" // Give the program a default value.
+	exhaustedBlock   = "block"   // Wait for more input on stdin.
+)
+
+// inputSource gives a program its input, first from values given up front and then, if there were none or the
+// policy is to block, by reading whitespace separated values from stdin. INP takes a number and character input takes
+// the first character of a value, so values are kept as text until the program asks for them.
+type inputSource struct {
+	values   []string
+	stdin    *bufio.Scanner // nil if stdin isn't read.
+	prompt   bool           // Whether stdin is a terminal, in which case the user is prompted for each value.
+	policy   string
+	fallback int // The value given once input runs out if the policy is default.
+	outputs  int // Number of values output so far, for ErrInputExhausted.
+}
+
+// newInputSource returns a source of the values given followed by those in the file at path, each separated by commas
+// or whitespace. stdin is read if neither was given or the policy is to block, so values can be streamed into the
+// program.
+func newInputSource(values, path, policy string, fallback int) (*inputSource, error) {
+	switch policy {
+	case exhaustedError, exhaustedDefault, exhaustedBlock:
+	default:
+		return nil, fmt.Errorf("unknown exhausted input policy %q, expected error, default or block", policy)
+	}
+
+	s := &inputSource{values: splitInputs(values), policy: policy, fallback: fallback}
+
+	if path != "" {
+		bytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading input file: %s", err)
+		}
+
+		s.values = append(s.values, splitInputs(string(bytes))...)
+	}
+
+	if (values == "" && path == "") || policy == exhaustedBlock {
+		s.stdin = bufio.NewScanner(os.Stdin)
+		s.stdin.Split(bufio.ScanWords)
+		s.prompt = term.IsTerminal(int(os.Stdin.Fd()))
+	}
+
+	return s, nil
+}
+
+// splitInputs splits a list of values separated by commas or whitespace.
+func splitInputs(values string) []string {
+	return strings.FieldsFunc(values, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+}
+
+// Observe counts the values the program outputs.
+func (s *inputSource) Observe(msg lmc.Msg) {
+	if msg.Status == lmc.Output || msg.Status == lmc.OutputChar {
+		s.outputs++
+	}
+}
+
+// Input returns the next value for the program, as a number or, if char is true, a character. Once there are no
+// values left it follows the policy, and stdin having been closed counts as there being none left.
+func (s *inputSource) Input(char bool) (int, error) {
+	value, ok, err := s.next(char)
+	if err != nil {
+		return 0, err
+	}
+
+	if !ok {
+		if s.policy == exhaustedDefault {
+			return s.fallback, nil
+		}
+
+		return 0, lmc.ErrInputExhausted{Outputs: s.outputs}
+	}
+
+	if char {
+		return int([]rune(value)[0]), nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid input %q, expected a number", value)
+	}
+
+	return n, nil
+}
+
+// next returns the next value, or false if there are none left.
+func (s *inputSource) next(char bool) (string, bool, error) {
+	if len(s.values) != 0 {
+		value := s.values[0]
+		s.values = s.values[1:]
+		return value, true, nil
+	}
+
+	if s.stdin == nil {
+		return "", false, nil
+	}
+
+	if s.prompt {
+		if char {
+			fmt.Fprint(os.Stderr, "Input (char): ")
+		} else {
+			fmt.Fprint(os.Stderr, "Input (int): ")
+		}
+	}
+
+	if !s.stdin.Scan() {
+		if s.prompt {
+			fmt.Fprintln(os.Stderr)
+		}
+
+		return "", false, s.stdin.Err()
+	}
+
+	return s.stdin.Text(), true, nil
+}
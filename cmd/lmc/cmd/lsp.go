@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/ollybritton/go-lmc/lsp"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// lspCmd represents the lsp command
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a language server over stdin and stdout",
+	Long: `Run a Language Server Protocol server over stdin and stdout, so that editors can show
+diagnostics, go to label definitions, find references, rename labels, complete
+mnemonics and format .lmc files.`,
+	Args: cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		opcodeSize, err := cmd.Flags().GetInt("opcode-size")
+		checkFlagErr(err)
+		operandSize, err := cmd.Flags().GetInt("operand-size")
+		checkFlagErr(err)
+
+		// Anything logged to stdout would corrupt the protocol stream.
+		logrus.SetOutput(os.Stderr)
+
+		server := lsp.NewServer(os.Stdin, os.Stdout)
+		server.OpcodeSize = opcodeSize
+		server.OperandSize = operandSize
+
+		if err := server.Serve(); err != nil {
+			logrus.Fatalf("Error running language server: %s", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+
+	lspCmd.Flags().IntP("opcode-size", "c", 1, "size of opcode, in digits")
+	lspCmd.Flags().IntP("operand-size", "r", 2, "size of operand, in digits")
+}
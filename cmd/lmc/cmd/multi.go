@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/spec"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// multiCmd represents the multi command
+var multiCmd = &cobra.Command{
+	Use:   "multi <program>",
+	Short: "Run a program on several processors sharing the same mailboxes",
+	Long: `Run a program on several processors that share the same mailboxes, one
+instruction at a time, and print what each processor output along with the
+order they were stepped in. There is a processor for each address given by
+--entry, which is where it starts executing.
+
+The order the processors are stepped in is decided by --scheduler, which is
+round-robin, random (seeded with --seed) or scripted, where --script gives the
+order, such as one printed by an earlier run:
+
+    lmc multi counter.lmc --entry 0,0 --scheduler scripted --script 0,0,1,1
+
+Each processor's inputs are given with --input as processor:value, such as
+--input 0:5,1:7. With --explore, every interleaving of the processors is tried
+and each distinct outcome is printed along with a script that produces it. The
+command exits with status 1 if there is more than one, which means what the
+program does depends on how the processors are scheduled:
+
+    lmc multi examples/race.lmc --explore
+
+Runs that reach a state an earlier run has been through are cut short, so
+processors spinning on a lock don't need a cycle budget to stop. When exploring,
+runs are only limited if --max-cycles is given, since with a limit every way
+of reaching a state is explored up to it, to find the runs that hit it.
+
+The extended instruction set's TAS instruction loads a mailbox and sets it to
+one in a single step, which can be used to build a lock.`,
+	Args: cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		opcodeSize, err := cmd.Flags().GetInt("opcode-size")
+		checkFlagErr(err)
+		operandSize, err := cmd.Flags().GetInt("operand-size")
+		checkFlagErr(err)
+		setFile, err := cmd.Flags().GetString("instruction-set")
+		checkFlagErr(err)
+		entries, err := cmd.Flags().GetIntSlice("entry")
+		checkFlagErr(err)
+		scheduler, err := cmd.Flags().GetString("scheduler")
+		checkFlagErr(err)
+		seed, err := cmd.Flags().GetInt64("seed")
+		checkFlagErr(err)
+		script, err := cmd.Flags().GetIntSlice("script")
+		checkFlagErr(err)
+		inputSpecs, err := cmd.Flags().GetStringSlice("input")
+		checkFlagErr(err)
+		explore, err := cmd.Flags().GetBool("explore")
+		checkFlagErr(err)
+		maxCycles, err := cmd.Flags().GetInt("max-cycles")
+		checkFlagErr(err)
+		maxRuns, err := cmd.Flags().GetInt("max-runs")
+		checkFlagErr(err)
+
+		if explore && !cmd.Flags().Changed("max-cycles") {
+			maxCycles = 0
+		}
+
+		bytes, err := ioutil.ReadFile(filename)
+		if err != nil {
+			logrus.Fatalf("Error reading file: %s", err)
+		}
+
+		set := lmc.DefaultInstructionSet
+		if setFile != "" {
+			set, err = lmc.LoadInstructionSet(setFile)
+			if err != nil {
+				logrus.Fatalf("Error loading instruction set: %s", err)
+			}
+		}
+
+		parser := lmc.NewParser(lmc.NewLexer(string(bytes)))
+		parser.InstructionSet = set
+
+		instructions, err := parser.Parse()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		if errs := set.Validate(instructions, opcodeSize, operandSize); len(errs) != 0 {
+			logrus.Fatal(errs[0])
+		}
+
+		inputs, err := processorInputs(inputSpecs, len(entries))
+		if err != nil {
+			logrus.Fatalf("Error reading inputs: %s", err)
+		}
+
+		m := lmc.NewMultiprocessor(set.Assemble(instructions, opcodeSize, operandSize), entries, opcodeSize, operandSize)
+		for _, p := range m.Processors {
+			p.InstructionSet = set
+		}
+
+		if explore {
+			outcomes, err := m.Explore(inputs, maxCycles, maxRuns)
+			if err != nil {
+				logrus.Error(err)
+			}
+
+			for i, outcome := range outcomes {
+				fmt.Printf("outcome %d, found in %d runs:\n", i+1, outcome.Count)
+				printOutputs(outcome.Outputs)
+				if outcome.Err != nil {
+					fmt.Printf("  error: %s\n", outcome.Err)
+				}
+				fmt.Printf("  script: %s\n", joinInts(outcome.Schedule))
+			}
+
+			if len(outcomes) > 1 {
+				os.Exit(1)
+			}
+			return
+		}
+
+		switch scheduler {
+		case "round-robin":
+			m.Scheduler = &lmc.RoundRobin{}
+		case "random":
+			m.Scheduler = lmc.NewRandomScheduler(seed)
+		case "scripted":
+			m.Scheduler = &lmc.ScriptedScheduler{Order: script}
+		default:
+			logrus.Fatalf("Unknown scheduler %q, expected round-robin, random or scripted", scheduler)
+		}
+
+		result, err := m.RunWithInputs(inputs, maxCycles)
+
+		outputs := [][]int{}
+		for _, r := range result.Results {
+			outputs = append(outputs, r.Outputs)
+		}
+
+		printOutputs(outputs)
+		fmt.Printf("  script: %s\n", joinInts(result.Schedule))
+
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(multiCmd)
+
+	multiCmd.Flags().IntP("opcode-size", "c", 1, "size of opcode, in digits")
+	multiCmd.Flags().IntP("operand-size", "r", 2, "size of operand, in digits")
+	multiCmd.Flags().String("instruction-set", "", "YAML or JSON file defining the instruction set to use")
+
+	multiCmd.Flags().IntSlice("entry", []int{0, 0}, "address each processor starts at")
+	multiCmd.Flags().String("scheduler", "round-robin", "how processors are scheduled: round-robin, random or scripted")
+	multiCmd.Flags().Int64("seed", 1, "seed for the random scheduler")
+	multiCmd.Flags().IntSlice("script", nil, "order to step processors in with the scripted scheduler")
+	multiCmd.Flags().StringSlice("input", nil, "inputs for the processors, as processor:value")
+
+	multiCmd.Flags().Bool("explore", false, "try every interleaving and print each distinct outcome")
+	multiCmd.Flags().Int("max-cycles", spec.DefaultMaxCycles, "cycle budget for each run, if given when exploring")
+	multiCmd.Flags().Int("max-runs", 100000, "most runs to try when exploring")
+}
+
+// processorInputs reads inputs given as processor:value into the inputs for each processor.
+func processorInputs(specs []string, processors int) ([][]int, error) {
+	inputs := make([][]int, processors)
+
+	for _, input := range specs {
+		parts := strings.SplitN(input, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q should be processor:value", input)
+		}
+
+		p, err := strconv.Atoi(parts[0])
+		if err != nil || p < 0 || p >= processors {
+			return nil, fmt.Errorf("%q is not for one of the %d processors", input, processors)
+		}
+
+		val, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("%q has an invalid value: %s", input, err)
+		}
+
+		inputs[p] = append(inputs[p], val)
+	}
+
+	return inputs, nil
+}
+
+// printOutputs prints what each processor output.
+func printOutputs(outputs [][]int) {
+	for i, out := range outputs {
+		fmt.Printf("  processor %d: %s\n", i, joinInts(out))
+	}
+}
+
+// joinInts joins numbers with commas, in the form taken by flags like --script.
+func joinInts(nums []int) string {
+	strs := []string{}
+	for _, n := range nums {
+		strs = append(strs, strconv.Itoa(n))
+	}
+
+	return strings.Join(strs, ",")
+}
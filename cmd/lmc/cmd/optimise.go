@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// optimiseCmd represents the optimise command
+var optimiseCmd = &cobra.Command{
+	Use:   "optimise <program>",
+	Short: "Run the peephole optimiser over a program",
+	Long: `Run the peephole optimiser over a program and print the result, or write it to
+the file given by --output. The number of instructions before and after is
+reported on stderr.
+
+The optimiser removes loads straight after a store to the same mailbox, stores
+and DATs that are never used, branches to the next instruction and dead code,
+and shortens chains of branches. Programs that refer to mailboxes by number
+rather than by label, or that use instructions from outside the default
+instruction set, are left unchanged.`,
+	Args: cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		output, err := cmd.Flags().GetString("output")
+		checkFlagErr(err)
+
+		bytes, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			logrus.Fatalf("Error reading file: %s", err)
+		}
+
+		instructions, err := lmc.NewParser(lmc.NewLexer(string(bytes))).Parse()
+		if err != nil {
+			logrus.Fatalf("Error parsing program: %s", err)
+		}
+
+		optimised := lmc.Optimise(instructions)
+		fmt.Fprintf(os.Stderr, "instructions: %d -> %d\n", len(instructions), len(optimised))
+
+		if output == "" {
+			fmt.Print(lmc.Format(optimised))
+			return
+		}
+
+		err = ioutil.WriteFile(output, []byte(lmc.Format(optimised)), 0644)
+		if err != nil {
+			logrus.Fatalf("Error writing file: %s", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(optimiseCmd)
+
+	optimiseCmd.Flags().StringP("output", "o", "", "file to write the optimised program to, instead of printing it")
+}
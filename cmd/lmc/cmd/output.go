@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// printer writes what a program outputs to stdout as it happens: values from OUT one per line, and characters from
+// OTC as they are, so strings appear inline.
+type printer struct {
+	w       io.Writer
+	midLine bool // Whether a line of characters has been started and not yet finished.
+}
+
+// Observe prints a value or character output by the program.
+func (p *printer) Observe(msg lmc.Msg) {
+	switch msg.Status {
+	case lmc.Output:
+		p.Finish()
+		fmt.Fprintln(p.w, msg.Val)
+	case lmc.OutputChar:
+		n, _ := strconv.Atoi(msg.Val)
+		fmt.Fprint(p.w, string(rune(n)))
+		p.midLine = n != '\n'
+	}
+}
+
+// Finish ends a line of characters if one has been started, so that anything printed afterwards starts on its own
+// line.
+func (p *printer) Finish() {
+	if p.midLine {
+		fmt.Fprintln(p.w)
+		p.midLine = false
+	}
+}
+
+// result is what lmc run --json writes to stdout once the program stops.
+type result struct {
+	Outputs   []int    `json:"outputs"`        // Values output by OUT.
+	Text      string   `json:"text,omitempty"` // Characters output by OTC.
+	Cycles    int      `json:"cycles"`
+	Error     string   `json:"error,omitempty"`
+	Displays  []string `json:"displays,omitempty"`
+	Mailboxes []string `json:"mailboxes,omitempty"` // With --dump, the mailboxes up to the last one that isn't zero.
+}
+
+// Observe records a value or character output by the program.
+func (r *result) Observe(msg lmc.Msg) {
+	switch msg.Status {
+	case lmc.Output:
+		n, _ := strconv.Atoi(msg.Val)
+		r.Outputs = append(r.Outputs, n)
+	case lmc.OutputChar:
+		n, _ := strconv.Atoi(msg.Val)
+		r.Text += string(rune(n))
+	}
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/device"
+	"github.com/ollybritton/go-lmc/record"
+	"github.com/sirupsen/logrus"
+)
+
+// program is a program loaded into a machine set up with the settings given to a command, ready to be driven.
+type program struct {
+	machine   lmc.Machine
+	settings  record.Settings
+	sourceMap lmc.SourceMap
+	displays  []*device.Display // Displays mapped into the mailboxes, to be printed once the program halts.
+
+	// observers are passed every message from the computer before it's handled. More can be added until the program
+	// is driven.
+	observers []func(lmc.Msg)
+
+	char bool // Whether the program last asked for a character rather than a number.
+}
+
+// load assembles a program and loads it into a machine set up with the settings given.
+func load(source string, s record.Settings) (*program, error) {
+	switch lmc.Arithmetic(s.Arithmetic) {
+	case lmc.ArithmeticUnbounded, lmc.ArithmeticWrap, lmc.ArithmeticChecked:
+	default:
+		return nil, fmt.Errorf("unknown arithmetic %q, expected unbounded, wrap or checked", s.Arithmetic)
+	}
+
+	set := lmc.DefaultInstructionSet
+	if s.InstructionSet != "" {
+		var err error
+		set, err = lmc.LoadInstructionSet(s.InstructionSet)
+		if err != nil {
+			return nil, fmt.Errorf("error loading instruction set: %s", err)
+		}
+	}
+
+	if s.InterruptVector >= 0 {
+		set = set.WithInterrupts()
+	}
+
+	p := &program{settings: s}
+	opts := []lmc.Option{
+		lmc.WithInstructionSet(set),
+		lmc.WithArithmetic(lmc.Arithmetic(s.Arithmetic)),
+		lmc.WithMemory(s.Radix, s.OpcodeSize, s.OperandSize),
+		lmc.WithObserver(p.observe),
+	}
+
+	if s.MicroStep {
+		opts = append(opts, lmc.WithMicroStepping())
+	}
+
+	if s.InterruptVector >= 0 {
+		opts = append(opts, lmc.WithInterrupts(&lmc.Interrupts{
+			Vector:   s.InterruptVector,
+			SavePC:   s.InterruptPC,
+			SaveAcc:  s.InterruptAcc,
+			Schedule: s.InterruptAt,
+		}))
+	}
+
+	for _, spec := range s.Devices {
+		d, err := newDevice(spec, s.Seed, s.RandomMax, s.Keys, s.TimerInterval)
+		if err != nil {
+			return nil, fmt.Errorf("error creating device: %s", err)
+		}
+
+		opts = append(opts, lmc.WithDevice(d.addr, d.device))
+		if display, ok := d.device.(*device.Display); ok {
+			p.displays = append(p.displays, display)
+		}
+	}
+
+	p.machine = lmc.NewMachine(opts...)
+	if err := p.machine.Load(source); err != nil {
+		return nil, err
+	}
+
+	// The machine has already checked the program, so it only needs assembling again for its source map.
+	parser := lmc.NewParser(lmc.NewLexer(source))
+	parser.InstructionSet = set
+
+	instructions, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	_, p.sourceMap = set.AssembleRadix(instructions, s.Radix, s.OpcodeSize, s.OperandSize)
+
+	return p, nil
+}
+
+// observe passes a message from the computer to the observers, then logs it. When micro-stepping with Step set, it
+// waits for enter to be pressed after each phase of an instruction.
+func (p *program) observe(msg lmc.Msg) {
+	for _, observe := range p.observers {
+		observe(msg)
+	}
+
+	switch msg.Status {
+	case lmc.Done:
+		logrus.Debugln("DONE")
+	case lmc.NeedInput:
+		logrus.Debugln("NEED INPUT")
+		p.char = msg.Val == lmc.InputChar
+	case lmc.MicroStep:
+		if p.settings.Step {
+			logrus.Infof("%s %s", strings.ToUpper(msg.Val), p.registers())
+			fmt.Scanln()
+		} else {
+			logrus.Debugf("%s %s", msg.Val, p.registers())
+		}
+	case lmc.Log:
+		logrus.Debugln(msg.Val)
+	case lmc.Output, lmc.OutputChar:
+		logrus.Debugf("OUTPUT %s", msg.Val)
+	}
+}
+
+// registers describes the registers of the machine, as shown after each phase in micro-step mode, with the CIR written
+// in the machine's radix.
+func (p *program) registers() string {
+	state := p.machine.State()
+
+	cir := strconv.FormatInt(int64(state.CIR), p.settings.Radix)
+	if width := p.settings.OpcodeSize + p.settings.OperandSize; len(cir) < width {
+		cir = strings.Repeat("0", width-len(cir)) + cir
+	}
+
+	return fmt.Sprintf(
+		"PC=%d ACC=%d MAR=%d MDR=%d CIR=%s",
+		state.ProgramCounter, state.Accumulator, state.MAR, state.MDR, cir,
+	)
+}
+
+// drive runs the program, giving it input from the input function, until it stops or the context is done, returning
+// the error it stopped with. If Step is set it waits for enter to be pressed before each instruction. Observers can
+// stop the run by cancelling the context.
+func (p *program) drive(ctx context.Context, input func(char bool) (int, error)) error {
+	err := p.advance(ctx)
+
+	for {
+		if _, ok := err.(lmc.ErrInputExhausted); !ok {
+			return err
+		}
+
+		val, inputErr := input(p.char)
+		if inputErr != nil {
+			return inputErr
+		}
+
+		// The instruction that asked for input is finished before going on, so stepping doesn't wait for enter twice
+		// for the same instruction.
+		p.machine.SetInput(val)
+		if err = p.machine.Step(); err == nil {
+			err = p.advance(ctx)
+		}
+	}
+}
+
+// advance runs the program until it stops or needs input, one instruction at a time if Step is set.
+func (p *program) advance(ctx context.Context) error {
+	if !p.settings.Step {
+		return p.machine.Run(ctx)
+	}
+
+	for !p.machine.State().Halted {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		logrus.Infoln("NEED STEP")
+		fmt.Scanln()
+
+		if err := p.machine.Step(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/record"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:   "replay <session>",
+	Short: "Replay a run recorded with lmc run --record",
+	Long: `Replay a run recorded with lmc run --record, giving the program the inputs
+that were recorded and checking that it outputs the same values, takes the
+same interrupts and executes the same number of instructions as it did the
+first time:
+
+    lmc run --record session.json examples/add.lmc
+    lmc replay session.json
+
+The program is replayed from the source saved in the session, with the
+settings it was recorded with, and is stepped through if the recorded run
+was. With --step it is stepped through anyway. If the replay does anything
+differently, it is stopped and the first difference is reported.
+
+To replay a session under the debugger, launch lmc dap with "replay" set to
+the path of the session instead of "program".`,
+	Args: cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		shouldStep, err := cmd.Flags().GetBool("step")
+		checkFlagErr(err)
+		shouldLog, err := cmd.Flags().GetBool("log")
+		checkFlagErr(err)
+
+		if shouldLog {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+
+		session, err := record.Load(args[0])
+		if err != nil {
+			logrus.Fatalf("Error reading session: %s", err)
+		}
+
+		settings := session.Settings
+		settings.Step = settings.Step || shouldStep
+
+		p, err := load(session.Source, settings)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		replayer := record.NewReplayer(session)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Output is only printed once the replayer has checked it, so that a divergent value isn't printed as if it
+		// had been replayed.
+		out := &printer{w: os.Stdout}
+		p.observers = append(p.observers, func(msg lmc.Msg) {
+			replayer.Observe(msg)
+			if replayer.Err() != nil {
+				cancel()
+				return
+			}
+
+			out.Observe(msg)
+		})
+
+		err = p.drive(ctx, replayer.Input)
+		out.Finish()
+
+		if err := replayer.Finish(err); err != nil {
+			logrus.Fatal(err)
+		}
+
+		for _, display := range p.displays {
+			fmt.Print(display)
+		}
+
+		if session.Error != "" {
+			logrus.Infof("Replay of %s matched the recording, stopping with the error %s", session.Program,
+				session.Error)
+		} else {
+			logrus.Infof("Replay of %s matched the recording", session.Program)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().BoolP("step", "s", false, "whether to step through the input")
+	replayCmd.Flags().BoolP("log", "l", false, "whether to log each stage of the computation")
+}
@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 
 	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/record"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -40,41 +42,31 @@ var rootCmd = &cobra.Command{
 			logrus.Errorf("Error reading file: %s", err)
 		}
 
-		computer, err := lmc.NewComputerFromCode(string(bytes), opcodeSize, operandSize)
+		p, err := load(string(bytes), record.Settings{
+			OpcodeSize:      opcodeSize,
+			OperandSize:     operandSize,
+			Radix:           10,
+			Arithmetic:      string(lmc.ArithmeticUnbounded),
+			InterruptVector: -1,
+			Step:            shouldStep,
+		})
 		if err != nil {
 			logrus.Fatal(err)
 		}
 
-		go func() {
-			err = computer.Run()
-			if err != nil {
-				logrus.Errorf("Error running computer: %s", err)
-			}
-		}()
-
-		for {
-			msg := <-computer.Messages
-			switch msg.Status {
-			case lmc.Done:
-				logrus.Info("DONE")
-				return
-			case lmc.NeedInput:
-				logrus.Infoln("NEED INPUT")
-				var i int
-				fmt.Print("Input (int): ")
-				fmt.Scan(&i)
-				computer.Inbox <- i
-			case lmc.NeedStep:
-				if shouldStep {
-					logrus.Infoln("NEED STEP")
-					fmt.Scanln()
-				}
-				computer.Step <- struct{}{}
-			case lmc.Log:
-				logrus.Debugln(msg.Val)
-			case lmc.Output:
-				logrus.Infoln("OUTPUT", msg.Val)
-			}
+		input, err := newInputSource("", "", exhaustedError, 0)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		out := &printer{w: os.Stdout}
+		p.observers = append(p.observers, input.Observe, out.Observe)
+
+		err = p.drive(context.Background(), input.Input)
+		out.Finish()
+
+		if err != nil {
+			logrus.Fatalf("Error running computer: %s", err)
 		}
 	},
 }
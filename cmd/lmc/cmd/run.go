@@ -0,0 +1,390 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/device"
+	"github.com/ollybritton/go-lmc/profile"
+	"github.com/ollybritton/go-lmc/record"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run <program>",
+	Short: "Run a program",
+	Long: `Run a program, reading its input from stdin. This is the same as running
+lmc <program>, with extra options for giving the program input and seeing
+what it did.
+
+Values output by OUT are written to stdout one per line, and characters
+output by OTC as they are, with everything else, such as errors and
+prompts, written to stderr. With --json, what the program output is
+written to stdout as a JSON object once it stops instead, along with the
+number of cycles it ran for and the error it stopped with, if any.
+
+Input is read from stdin as values separated by whitespace, so it can be
+piped in, and is prompted for if stdin is a terminal. INP takes a number
+and character input takes the first character of a value. Values can be
+given up front with --input, separated by commas, or in a file with
+--input-file, in which case stdin isn't read:
+
+    lmc run --input 5,7 examples/add.lmc
+    echo 5 7 | lmc run --json examples/add.lmc
+
+If the program asks for more input than it was given, --on-exhausted
+decides what happens: with error, the default, the program stops with an
+error, with default it is given --default-input, and with block the rest
+of its input is read from stdin. The program also stops with an error if
+stdin is closed while it waits for input. lmc run exits with status 1 if
+the program stopped with an error.
+
+With --profile, a listing of the source is printed once the program halts,
+showing how many times each line was executed, how often each branch was
+taken and how often each mailbox was read and written. With --pprof, the
+profile is also written in the format read by go tool pprof:
+
+    lmc run --pprof bubble.pprof examples/bubble.lmc
+    go tool pprof -top bubble.pprof
+
+With --micro-step and --step, the computer stops after each phase of an
+instruction, fetch, decode, operand fetch, execute and write-back, and
+shows the program counter, accumulator, MAR, MDR and CIR:
+
+    lmc run --micro-step --step examples/add.lmc
+
+With --arithmetic wrap, the accumulator keeps only as many digits as fit in
+a mailbox, so 999 + 1 is 0, and with --arithmetic checked a program whose
+accumulator overflows stops with an error.
+
+With --instruction-set, the program is assembled and run using the
+mnemonics, opcodes and semantics defined in a YAML or JSON file rather
+than the standard ones.
+
+With --device, peripherals are mapped into mailboxes. Each is given as
+name@address, where the name is one of timer, random, display or keyboard:
+
+    lmc run --device display@90 --device keyboard@94 --keys hello prog.lmc
+
+With --radix, the computer works in a base other than 10, with the opcode
+and operand sizes counting digits in that base. Operands can be written in
+binary, octal or hexadecimal with 0b, 0o or 0x, and --dump prints the
+mailboxes in the computer's base once it halts. For a binary computer with a
+4 bit opcode and 8 bit addresses:
+
+    lmc run --radix 2 --opcode-size 4 --operand-size 8 --dump prog.lmc
+
+With --interrupt-vector, interrupts are enabled and jump to the given
+address, saving the program counter and accumulator to the mailboxes given
+by --interrupt-pc and --interrupt-acc, and the program can use RTI to return
+from the handler and DI and EI to mask and unmask interrupts. Interrupts are
+raised by the keyboard when keys are pressed, by the timer every
+--timer-interval instructions, and at each of the cycles given by
+--interrupt-at.
+
+With --record, every input given to the program, every value it outputs,
+every interrupt it takes and the cycle each happened in are saved to a
+session file along with the program and the options it was run with. lmc
+replay runs it again with the same inputs and fails if the program does
+anything differently:
+
+    lmc run --record session.json examples/add.lmc
+    lmc replay session.json
+
+The random number source is seeded with --seed and gives numbers below
+--random-max, the keyboard starts with the keys given by --keys in its
+buffer, and displays are printed once the program halts.`,
+	Args: cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		shouldLog, err := cmd.Flags().GetBool("log")
+		checkFlagErr(err)
+		shouldProfile, err := cmd.Flags().GetBool("profile")
+		checkFlagErr(err)
+		pprofFile, err := cmd.Flags().GetString("pprof")
+		checkFlagErr(err)
+		shouldDump, err := cmd.Flags().GetBool("dump")
+		checkFlagErr(err)
+		recordFile, err := cmd.Flags().GetString("record")
+		checkFlagErr(err)
+		inputs, err := cmd.Flags().GetString("input")
+		checkFlagErr(err)
+		inputFile, err := cmd.Flags().GetString("input-file")
+		checkFlagErr(err)
+		onExhausted, err := cmd.Flags().GetString("on-exhausted")
+		checkFlagErr(err)
+		defaultInput, err := cmd.Flags().GetInt("default-input")
+		checkFlagErr(err)
+		asJSON, err := cmd.Flags().GetBool("json")
+		checkFlagErr(err)
+
+		if shouldLog {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+
+		bytes, err := ioutil.ReadFile(filename)
+		if err != nil {
+			logrus.Fatalf("Error reading file: %s", err)
+		}
+
+		settings := runSettings(cmd)
+		p, err := load(string(bytes), settings)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		prof := profile.New(p.machine, p.sourceMap)
+
+		source, err := newInputSource(inputs, inputFile, onExhausted, defaultInput)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		input := source.Input
+		p.observers = append(p.observers, prof.Observe, source.Observe)
+
+		out := &printer{w: os.Stdout}
+		res := &result{Outputs: []int{}}
+		if asJSON {
+			p.observers = append(p.observers, res.Observe)
+		} else {
+			p.observers = append(p.observers, out.Observe)
+		}
+
+		var recorder *record.Recorder
+		if recordFile != "" {
+			recorder = record.NewRecorder(filename, string(bytes), settings)
+			p.observers = append(p.observers, recorder.Observe)
+			input = func(char bool) (int, error) {
+				val, err := source.Input(char)
+				if err == nil {
+					recorder.Input(val, char)
+				}
+
+				return val, err
+			}
+		}
+
+		runErr := p.drive(context.Background(), input)
+		out.Finish()
+
+		if runErr != nil {
+			logrus.Errorf("Error running computer: %s", runErr)
+		}
+
+		if recorder != nil {
+			if err := recorder.Finish(runErr).Save(recordFile); err != nil {
+				logrus.Fatalf("Error saving recording: %s", err)
+			}
+		}
+
+		state := p.machine.State()
+
+		if asJSON {
+			res.Cycles = state.Cycles
+			if runErr != nil {
+				res.Error = runErr.Error()
+			}
+
+			for _, display := range p.displays {
+				res.Displays = append(res.Displays, fmt.Sprint(display))
+			}
+
+			if shouldDump {
+				res.Mailboxes = usedMailboxes(state.Mailboxes)
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "    ")
+			if err := encoder.Encode(res); err != nil {
+				logrus.Fatalf("Error writing result: %s", err)
+			}
+		} else {
+			for _, display := range p.displays {
+				fmt.Print(display)
+			}
+
+			if shouldDump {
+				dump(state.Mailboxes, settings.Radix, settings.OperandSize)
+			}
+		}
+
+		if shouldProfile {
+			err = prof.WriteListing(os.Stderr, string(bytes))
+			if err != nil {
+				logrus.Fatalf("Error writing profile: %s", err)
+			}
+		}
+
+		if pprofFile != "" {
+			f, err := os.Create(pprofFile)
+			if err != nil {
+				logrus.Fatalf("Error creating profile: %s", err)
+			}
+
+			err = prof.WritePprof(f, filename)
+			f.Close()
+			if err != nil {
+				logrus.Fatalf("Error writing profile: %s", err)
+			}
+		}
+
+		if runErr != nil {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().IntP("opcode-size", "c", 1, "size of opcode, in digits")
+	runCmd.Flags().IntP("operand-size", "r", 2, "size of operand, in digits")
+	runCmd.Flags().BoolP("step", "s", false, "whether to step through the input")
+	runCmd.Flags().BoolP("log", "l", false, "whether to log each stage of the computation")
+	runCmd.Flags().Bool("micro-step", false, "stop after each phase of an instruction, showing MAR, MDR and CIR")
+	runCmd.Flags().String("arithmetic", "unbounded", "accumulator overflow: unbounded, wrap or checked")
+
+	runCmd.Flags().BoolP("profile", "p", false, "print an annotated listing of where the program spent its time")
+	runCmd.Flags().String("pprof", "", "file to write a profile to in the format read by go tool pprof")
+	runCmd.Flags().String("instruction-set", "", "YAML or JSON file defining the instruction set to use")
+	runCmd.Flags().Int("radix", 10, "base the computer works in, between 2 and 36")
+	runCmd.Flags().Bool("dump", false, "print the mailboxes in the computer's base once the program halts")
+
+	runCmd.Flags().StringSlice("device", nil, "map a device into the mailboxes, as name@address")
+	runCmd.Flags().Int64("seed", 1, "seed for the random number source")
+	runCmd.Flags().Int("random-max", 100, "numbers from the random number source are less than this")
+	runCmd.Flags().String("keys", "", "keys in the keyboard buffer when the program starts")
+	runCmd.Flags().Int("timer-interval", 0, "raise an interrupt every this many instructions from the timer, if non-zero")
+
+	runCmd.Flags().Int("interrupt-vector", -1, "address of the interrupt handler, enabling interrupts if not negative")
+	runCmd.Flags().Int("interrupt-pc", 98, "mailbox the program counter is saved to when interrupted")
+	runCmd.Flags().Int("interrupt-acc", 99, "mailbox the accumulator is saved to when interrupted")
+	runCmd.Flags().IntSlice("interrupt-at", nil, "cycles at which to raise interrupts")
+
+	runCmd.Flags().String("record", "", "file to record the run to, so that it can be replayed with lmc replay")
+
+	runCmd.Flags().String("input", "", "values to give the program, separated by commas, rather than reading stdin")
+	runCmd.Flags().String("input-file", "", "file of values to give the program, rather than reading stdin")
+	runCmd.Flags().String("on-exhausted", "error", "when input runs out: error, default or block to read stdin")
+	runCmd.Flags().Int("default-input", 0, "value given once input runs out with --on-exhausted default")
+	runCmd.Flags().Bool("json", false, "write what the program output to stdout as JSON once it stops")
+}
+
+// runSettings returns the settings given by the flags of lmc run.
+func runSettings(cmd *cobra.Command) record.Settings {
+	var (
+		s   record.Settings
+		err error
+	)
+
+	s.OpcodeSize, err = cmd.Flags().GetInt("opcode-size")
+	checkFlagErr(err)
+	s.OperandSize, err = cmd.Flags().GetInt("operand-size")
+	checkFlagErr(err)
+	s.Radix, err = cmd.Flags().GetInt("radix")
+	checkFlagErr(err)
+	s.Arithmetic, err = cmd.Flags().GetString("arithmetic")
+	checkFlagErr(err)
+	s.InstructionSet, err = cmd.Flags().GetString("instruction-set")
+	checkFlagErr(err)
+	s.Devices, err = cmd.Flags().GetStringSlice("device")
+	checkFlagErr(err)
+	s.Seed, err = cmd.Flags().GetInt64("seed")
+	checkFlagErr(err)
+	s.RandomMax, err = cmd.Flags().GetInt("random-max")
+	checkFlagErr(err)
+	s.Keys, err = cmd.Flags().GetString("keys")
+	checkFlagErr(err)
+	s.TimerInterval, err = cmd.Flags().GetInt("timer-interval")
+	checkFlagErr(err)
+	s.InterruptVector, err = cmd.Flags().GetInt("interrupt-vector")
+	checkFlagErr(err)
+	s.InterruptPC, err = cmd.Flags().GetInt("interrupt-pc")
+	checkFlagErr(err)
+	s.InterruptAcc, err = cmd.Flags().GetInt("interrupt-acc")
+	checkFlagErr(err)
+	s.InterruptAt, err = cmd.Flags().GetIntSlice("interrupt-at")
+	checkFlagErr(err)
+	s.Step, err = cmd.Flags().GetBool("step")
+	checkFlagErr(err)
+	s.MicroStep, err = cmd.Flags().GetBool("micro-step")
+	checkFlagErr(err)
+
+	return s
+}
+
+// mappedDevice is a device along with the mailbox it should be mapped to.
+type mappedDevice struct {
+	device lmc.Device
+	addr   int
+}
+
+// newDevice creates the device described by name@address.
+func newDevice(spec string, seed int64, randomMax int, keys string, timerInterval int) (mappedDevice, error) {
+	parts := strings.SplitN(spec, "@", 2)
+	if len(parts) != 2 {
+		return mappedDevice{}, fmt.Errorf("%q should be name@address", spec)
+	}
+
+	addr, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return mappedDevice{}, fmt.Errorf("%q has an invalid address: %s", spec, err)
+	}
+
+	switch parts[0] {
+	case "timer":
+		timer := device.NewTimer()
+		timer.Interval = timerInterval
+		return mappedDevice{timer, addr}, nil
+	case "random":
+		return mappedDevice{device.NewRandom(seed, randomMax), addr}, nil
+	case "display":
+		return mappedDevice{device.NewDisplay(), addr}, nil
+	case "keyboard":
+		keyboard := device.NewKeyboard()
+		keyboard.Press(keys)
+		return mappedDevice{keyboard, addr}, nil
+	}
+
+	return mappedDevice{}, fmt.Errorf("unknown device %q", parts[0])
+}
+
+// dump prints the contents of the mailboxes up to the last one that isn't zero, with addresses written in the radix
+// given.
+func dump(mailboxes []string, radix, operandSize int) {
+	used := usedMailboxes(mailboxes)
+	last := len(used) - 1
+
+	width := operandSize
+	if digits := len(strconv.FormatInt(int64(last), radix)); digits > width {
+		width = digits
+	}
+
+	for i, val := range used {
+		addr := strconv.FormatInt(int64(i), radix)
+		fmt.Printf("%s%s  %s\n", strings.Repeat("0", width-len(addr)), addr, val)
+	}
+}
+
+// usedMailboxes returns the contents of the mailboxes up to the last one that isn't zero.
+func usedMailboxes(mailboxes []string) []string {
+	last := -1
+	for i, val := range mailboxes {
+		if strings.Trim(val, "0") != "" {
+			last = i
+		}
+	}
+
+	return mailboxes[:last+1]
+}
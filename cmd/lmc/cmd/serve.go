@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/ollybritton/go-lmc/playground"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a web playground for writing and running programs",
+	Long: `Serve a web page with an editor, the mailboxes, the accumulator and program counter,
+and controls for running and stepping through programs. Everything the page needs is
+built into lmc, so it works without an internet connection.`,
+	Args: cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		opcodeSize, err := cmd.Flags().GetInt("opcode-size")
+		checkFlagErr(err)
+		operandSize, err := cmd.Flags().GetInt("operand-size")
+		checkFlagErr(err)
+		addr, err := cmd.Flags().GetString("addr")
+		checkFlagErr(err)
+
+		server := playground.NewServer()
+		server.OpcodeSize = opcodeSize
+		server.OperandSize = operandSize
+
+		logrus.Infof("Serving playground on http://%s", addr)
+		if err := http.ListenAndServe(addr, server); err != nil {
+			logrus.Fatalf("Error serving playground: %s", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().IntP("opcode-size", "c", 1, "size of opcode, in digits")
+	serveCmd.Flags().IntP("operand-size", "r", 2, "size of operand, in digits")
+	serveCmd.Flags().StringP("addr", "a", "localhost:8080", "address to serve the playground on")
+}
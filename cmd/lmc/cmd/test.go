@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/coverage"
+	"github.com/ollybritton/go-lmc/spec"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// testCmd represents the test command
+var testCmd = &cobra.Command{
+	Use:   "test [paths...]",
+	Short: "Run programs against their spec files",
+	Long: `Find every *_test.json spec file under the paths given (or the current directory),
+run the cases in each against the .lmc program next to it and report which pass.
+
+A spec file lists cases with the inputs to give the program, the outputs it should
+produce, optionally the values some mailboxes should hold when it halts, and a cycle
+budget. For example, square_test.json might contain:
+
+    {"cases": [{"name": "four", "inputs": [4], "outputs": [16]}]}
+
+Cases are run in parallel, as many at once as there are CPUs unless --parallel
+says otherwise. Results are always reported in the order of the spec files, and
+-p 1 runs the cases one at a time in that order too.
+
+With --cover, the instruction and branch coverage of each program across all of
+its cases is reported. A branch counts as covered once each of its sides has been
+taken, so 100% branch coverage means every BRZ and BRP went both ways. Use
+--cover-dir to write an annotated copy of each program, an HTML report and a JSON
+summary, and --min-branch-coverage to fail if the cases don't cover enough.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		junit, err := cmd.Flags().GetString("junit")
+		checkFlagErr(err)
+		parallel, err := cmd.Flags().GetInt("parallel")
+		checkFlagErr(err)
+		verbose, err := cmd.Flags().GetBool("verbose")
+		checkFlagErr(err)
+		cover, err := cmd.Flags().GetBool("cover")
+		checkFlagErr(err)
+		coverDir, err := cmd.Flags().GetString("cover-dir")
+		checkFlagErr(err)
+		minInstructions, err := cmd.Flags().GetFloat64("min-instruction-coverage")
+		checkFlagErr(err)
+		minBranches, err := cmd.Flags().GetFloat64("min-branch-coverage")
+		checkFlagErr(err)
+
+		cover = cover || coverDir != "" || minInstructions > 0 || minBranches > 0
+
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+
+		paths, err := spec.Discover(args...)
+		if err != nil {
+			logrus.Fatalf("Error finding spec files: %s", err)
+		}
+
+		suites := []*spec.Suite{}
+		for _, path := range paths {
+			suite, err := spec.Load(path)
+			if err != nil {
+				logrus.Fatalf("Error loading spec file: %s", err)
+			}
+
+			if cover {
+				suite.Coverage = lmc.NewCoverage()
+			}
+
+			suites = append(suites, suite)
+		}
+
+		results := spec.Run(suites, parallel)
+
+		failed := 0
+		for _, result := range results {
+			if result.Passed {
+				if verbose {
+					fmt.Printf("PASS  %s: %s (%d cycles)\n", result.Suite, result.Case, result.Cycles)
+				}
+
+				continue
+			}
+
+			failed++
+			fmt.Printf("FAIL  %s: %s (%d cycles)\n", result.Suite, result.Case, result.Cycles)
+			fmt.Printf("      %s\n", strings.Replace(result.Failure, "\n", "\n      ", -1))
+		}
+
+		fmt.Printf("%d passed, %d failed\n", len(results)-failed, failed)
+
+		if junit != "" {
+			f, err := os.Create(junit)
+			if err != nil {
+				logrus.Fatalf("Error creating JUnit report: %s", err)
+			}
+
+			err = spec.WriteJUnit(f, results)
+			f.Close()
+			if err != nil {
+				logrus.Fatalf("Error writing JUnit report: %s", err)
+			}
+		}
+
+		if cover {
+			covered, err := reportCoverage(suites, coverDir, minInstructions, minBranches)
+			if err != nil {
+				logrus.Fatalf("Error writing coverage report: %s", err)
+			}
+
+			if !covered {
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// reportCoverage prints the coverage of each suite's program and writes the reports to coverDir, if given, creating it
+// if it doesn't exist. It returns false if any program is less covered than the minimums given, as percentages.
+func reportCoverage(suites []*spec.Suite, coverDir string, minInstructions, minBranches float64) (bool, error) {
+	ok := true
+
+	if coverDir != "" {
+		if err := os.MkdirAll(coverDir, 0o755); err != nil {
+			return false, err
+		}
+	}
+
+	for _, suite := range suites {
+		program := filepath.Join(filepath.Dir(suite.Path), suite.Program)
+		report := coverage.New(program, suite.Source(), suite.Set(), suite.SourceMap(), suite.Coverage)
+		summary := report.Summary
+
+		fmt.Printf("cover %s: %.1f%% of instructions, %.1f%% of branches\n", program, summary.Instructions.Percent, summary.Branches.Percent)
+
+		if summary.Instructions.Percent < minInstructions || summary.Branches.Percent < minBranches {
+			fmt.Printf("      coverage is below the minimum; uncovered lines %v, branches not taken both ways on lines %v\n", summary.UncoveredLines, summary.PartialBranches)
+			ok = false
+		}
+
+		if coverDir == "" {
+			continue
+		}
+
+		base := filepath.Join(coverDir, strings.TrimSuffix(strings.Replace(filepath.ToSlash(program), "/", "_", -1), ".lmc"))
+		writers := map[string]func(io.Writer) error{
+			".cov":  report.WriteAnnotated,
+			".html": report.WriteHTML,
+			".json": report.WriteJSON,
+		}
+
+		for ext, write := range writers {
+			if err := writeFile(base+ext, write); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return ok, nil
+}
+
+// writeFile creates a file and writes to it with the function given.
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func init() {
+	rootCmd.AddCommand(testCmd)
+
+	testCmd.Flags().StringP("junit", "j", "", "write a JUnit XML report to this file")
+	testCmd.Flags().IntP("parallel", "p", runtime.NumCPU(), "number of cases to run at once")
+	testCmd.Flags().BoolP("verbose", "v", false, "list passing cases as well as failing ones")
+
+	testCmd.Flags().Bool("cover", false, "report instruction and branch coverage")
+	testCmd.Flags().String("cover-dir", "", "directory to write annotated, HTML and JSON coverage reports to")
+	testCmd.Flags().Float64("min-instruction-coverage", 0, "fail if less than this percentage of instructions are executed")
+	testCmd.Flags().Float64("min-branch-coverage", 0, "fail if less than this percentage of branch sides are taken")
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportCoverageNewDir(t *testing.T) {
+	suite, err := spec.Load(filepath.Join("..", "..", "..", "examples", "add_test.json"))
+	assert.NoError(t, err)
+
+	suite.Coverage = lmc.NewCoverage()
+	spec.Run([]*spec.Suite{suite}, 1)
+
+	dir := filepath.Join(t.TempDir(), "reports", "coverage")
+	ok, err := reportCoverage([]*spec.Suite{suite}, dir, 100, 0)
+	assert.NoError(t, err, "expect the directory to be created")
+	assert.True(t, ok)
+
+	for _, ext := range []string{".cov", ".html", ".json"} {
+		_, err := os.Stat(filepath.Join(dir, ".._.._.._examples_add"+ext))
+		assert.NoError(t, err, "expect the %s report to be written", ext)
+	}
+}
@@ -0,0 +1,71 @@
+package compiler
+
+// stmt is a statement in the source language.
+type stmt interface {
+	stmt()
+}
+
+// expr is an expression in the source language, which always evaluates to an integer.
+type expr interface {
+	expr()
+}
+
+// assign stores the value of an expression in a variable: name = value.
+type assign struct {
+	name  string
+	value expr
+}
+
+// printStmt outputs the value of an expression: print(value).
+type printStmt struct {
+	value expr
+}
+
+// ifStmt runs one of two blocks depending on a condition. The else block may be empty.
+type ifStmt struct {
+	cond      cond
+	then      []stmt
+	otherwise []stmt
+}
+
+// whileStmt runs a block for as long as a condition holds.
+type whileStmt struct {
+	cond cond
+	body []stmt
+}
+
+// cond compares two expressions with one of ==, !=, <, <=, > or >=. A condition written as a single expression is
+// true when the expression isn't zero, and is represented with op "!=" and a zero right hand side.
+type cond struct {
+	op          string
+	left, right expr
+}
+
+// number is an integer literal.
+type number struct {
+	value int
+}
+
+// variable is a reference to a variable's value.
+type variable struct {
+	name string
+}
+
+// inputExpr reads a value from the user: input().
+type inputExpr struct{}
+
+// binary applies an arithmetic operator, one of + - * / or %, to two expressions.
+type binary struct {
+	op          string
+	left, right expr
+}
+
+func (assign) stmt()    {}
+func (printStmt) stmt() {}
+func (ifStmt) stmt()    {}
+func (whileStmt) stmt() {}
+
+func (number) expr()    {}
+func (variable) expr()  {}
+func (inputExpr) expr() {}
+func (binary) expr()    {}
@@ -0,0 +1,393 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxValue is the largest number a mailbox can hold with the classic 3 digit mailboxes the compiler targets.
+const maxValue = 999
+
+// Names of the runtime routines' variables. Generated names never start with v, which is reserved for user
+// variables, so the two can't clash.
+const (
+	mulA, mulB, mulResult, mulReturn = "mula", "mulb", "mulr", "mulret"
+	divA, divB, divResult, divReturn = "diva", "divb", "divq", "divret"
+
+	branchOpcode = "kbra" // Holds a BRA instruction with a zero operand, used to build return addresses.
+)
+
+// line is a single line of generated assembly.
+type line struct {
+	labels   []string // Every label given to this line. The first is used and the others become aliases of it.
+	mnemonic string
+	operand  string
+}
+
+// generator turns statements into LMC assembly for an accumulator machine. Expressions are evaluated into the
+// accumulator, spilling intermediate results to temporary mailboxes.
+type generator struct {
+	code    []line
+	pending []string // Labels for the next line emitted.
+
+	vars   []string
+	consts map[int]bool
+
+	temps int // Temporaries currently in use.
+	used  int // Most temporaries in use at once.
+
+	labels  int // Number of labels generated so far.
+	returns []string
+
+	mul bool // Whether the multiplication routine is needed.
+	div bool // Whether the division routine is needed.
+}
+
+func newGenerator() *generator {
+	return &generator{consts: make(map[int]bool)}
+}
+
+func (g *generator) emit(mnemonic, operand string) {
+	g.code = append(g.code, line{g.pending, mnemonic, operand})
+	g.pending = nil
+}
+
+// label gives the next line emitted a label.
+func (g *generator) label(name string) {
+	g.pending = append(g.pending, name)
+}
+
+// newLabel returns a label that hasn't been used before.
+func (g *generator) newLabel() string {
+	g.labels++
+	return fmt.Sprintf("l%d", g.labels)
+}
+
+// variable returns the label for a user variable, declaring it if needed.
+func (g *generator) variable(name string) string {
+	label := "v" + name
+
+	for _, v := range g.vars {
+		if v == label {
+			return label
+		}
+	}
+
+	g.vars = append(g.vars, label)
+	return label
+}
+
+// constant returns the label of a mailbox holding a number.
+func (g *generator) constant(n int) string {
+	g.consts[n] = true
+	return fmt.Sprintf("k%d", n)
+}
+
+// temp reserves a temporary mailbox, which must be released once it's no longer needed.
+func (g *generator) temp() string {
+	g.temps++
+	if g.temps > g.used {
+		g.used = g.temps
+	}
+
+	return fmt.Sprintf("t%d", g.temps)
+}
+
+func (g *generator) release() {
+	g.temps--
+}
+
+func (g *generator) stmts(stmts []stmt) {
+	for _, s := range stmts {
+		g.stmt(s)
+	}
+}
+
+func (g *generator) stmt(s stmt) {
+	switch s := s.(type) {
+	case assign:
+		g.expr(s.value)
+		g.emit("STA", g.variable(s.name))
+
+	case printStmt:
+		g.expr(s.value)
+		g.emit("OUT", "")
+
+	case ifStmt:
+		otherwise, end := g.newLabel(), g.newLabel()
+
+		g.cond(s.cond, otherwise)
+		g.stmts(s.then)
+
+		if len(s.otherwise) == 0 {
+			g.label(otherwise)
+			g.label(end)
+			return
+		}
+
+		g.emit("BRA", end)
+		g.label(otherwise)
+		g.stmts(s.otherwise)
+		g.label(end)
+
+	case whileStmt:
+		start, end := g.newLabel(), g.newLabel()
+
+		g.label(start)
+		g.cond(s.cond, end)
+		g.stmts(s.body)
+		g.emit("BRA", start)
+		g.label(end)
+	}
+}
+
+// cond jumps to the label given if the condition is false, and carries on to the next instruction if it's true.
+// LMC can only test for zero and for positive numbers (including zero), so comparisons are made by subtracting one
+// side from the other.
+func (g *generator) cond(c cond, otherwise string) {
+	switch c.op {
+	case "==", "!=", ">=", "<":
+		g.expr(binary{"-", c.left, c.right})
+	case "<=", ">":
+		g.expr(binary{"-", c.right, c.left})
+	}
+
+	switch c.op {
+	case "!=":
+		g.emit("BRZ", otherwise)
+	case "<", ">":
+		g.emit("BRP", otherwise)
+	case "==", ">=", "<=":
+		branch := map[string]string{"==": "BRZ", ">=": "BRP", "<=": "BRP"}[c.op]
+		then := g.newLabel()
+
+		g.emit(branch, then)
+		g.emit("BRA", otherwise)
+		g.label(then)
+	}
+}
+
+// operand returns the label holding the value of a simple expression, which doesn't need any instructions to
+// evaluate. It returns false if the expression isn't simple.
+func (g *generator) operand(e expr) (string, bool) {
+	switch e := e.(type) {
+	case number:
+		return g.constant(e.value), true
+	case variable:
+		return g.variable(e.name), true
+	}
+
+	return "", false
+}
+
+// expr evaluates an expression into the accumulator.
+func (g *generator) expr(e expr) {
+	if label, ok := g.operand(e); ok {
+		g.emit("LDA", label)
+		return
+	}
+
+	switch e := e.(type) {
+	case inputExpr:
+		g.emit("INP", "")
+
+	case binary:
+		switch e.op {
+		case "+", "-":
+			mnemonic := map[string]string{"+": "ADD", "-": "SUB"}[e.op]
+
+			if right, ok := g.operand(e.right); ok {
+				g.expr(e.left)
+				g.emit(mnemonic, right)
+				return
+			}
+
+			right := g.temp()
+			g.expr(e.right)
+			g.emit("STA", right)
+			g.expr(e.left)
+			g.emit(mnemonic, right)
+			g.release()
+
+		case "*":
+			g.mul = true
+			g.call(e, mulA, mulB, mulReturn, "mul")
+
+		case "/":
+			g.div = true
+			g.call(e, divA, divB, divReturn, "div")
+
+		case "%":
+			g.div = true
+			g.call(e, divA, divB, divReturn, "div")
+
+			// The division routine leaves the remainder in its first argument.
+			g.emit("LDA", divA)
+		}
+	}
+}
+
+// call evaluates both sides of a binary expression into a routine's arguments and then jumps to it. LMC has no
+// instructions for calling subroutines, so the return address is made by adding the address to return to onto a BRA
+// instruction, which is stored as the last instruction of the routine.
+func (g *generator) call(e binary, a, b, ret, routine string) {
+	right := g.temp()
+	g.expr(e.right)
+	g.emit("STA", right)
+	g.expr(e.left)
+	g.emit("STA", a)
+	g.emit("LDA", right)
+	g.emit("STA", b)
+	g.release()
+
+	back := g.newLabel()
+	address := "r" + back
+	g.returns = append(g.returns, back)
+
+	g.emit("LDA", branchOpcode)
+	g.emit("ADD", address)
+	g.emit("STA", ret)
+	g.emit("BRA", routine)
+	g.label(back)
+}
+
+// routines emits the multiplication and division routines, if they are used. Both work on non-negative numbers by
+// repeated addition and subtraction, and leave their result in the accumulator.
+func (g *generator) routines() {
+	if g.mul {
+		g.label("mul")
+		g.emit("LDA", g.constant(0))
+		g.emit("STA", mulResult)
+		g.label("mulloop")
+		g.emit("LDA", mulB)
+		g.emit("BRZ", "muldone")
+		g.emit("SUB", g.constant(1))
+		g.emit("STA", mulB)
+		g.emit("LDA", mulResult)
+		g.emit("ADD", mulA)
+		g.emit("STA", mulResult)
+		g.emit("BRA", "mulloop")
+		g.label("muldone")
+		g.emit("LDA", mulResult)
+		g.label(mulReturn)
+		g.emit("DAT", "")
+	}
+
+	if g.div {
+		// Dividing by zero gives zero rather than looping forever.
+		g.label("div")
+		g.emit("LDA", g.constant(0))
+		g.emit("STA", divResult)
+		g.emit("LDA", divB)
+		g.emit("BRZ", "divdone")
+		g.label("divloop")
+		g.emit("LDA", divA)
+		g.emit("SUB", divB)
+		g.emit("BRP", "divstep")
+		g.emit("BRA", "divdone")
+		g.label("divstep")
+		g.emit("STA", divA)
+		g.emit("LDA", divResult)
+		g.emit("ADD", g.constant(1))
+		g.emit("STA", divResult)
+		g.emit("BRA", "divloop")
+		g.label("divdone")
+		g.emit("LDA", divResult)
+		g.label(divReturn)
+		g.emit("DAT", "")
+	}
+}
+
+// data emits a mailbox for every variable, constant and temporary.
+func (g *generator) data() {
+	for _, v := range g.vars {
+		g.label(v)
+		g.emit("DAT", "0")
+	}
+
+	for i := 1; i <= g.used; i++ {
+		g.label(fmt.Sprintf("t%d", i))
+		g.emit("DAT", "0")
+	}
+
+	if g.mul {
+		for _, name := range []string{mulA, mulB, mulResult} {
+			g.label(name)
+			g.emit("DAT", "0")
+		}
+	}
+
+	if g.div {
+		for _, name := range []string{divA, divB, divResult} {
+			g.label(name)
+			g.emit("DAT", "0")
+		}
+	}
+
+	if len(g.returns) > 0 {
+		g.label(branchOpcode)
+		g.emit("DAT", "600")
+
+		for _, back := range g.returns {
+			g.label("r" + back)
+			g.emit("DAT", back)
+		}
+	}
+
+	for n := 0; n <= maxValue; n++ {
+		if g.consts[n] {
+			g.label(fmt.Sprintf("k%d", n))
+			g.emit("DAT", fmt.Sprint(n))
+		}
+	}
+}
+
+// program generates a whole program: the statements, then HLT, then the routines and data they use.
+func (g *generator) program(stmts []stmt) string {
+	g.stmts(stmts)
+	g.emit("HLT", "")
+	g.routines()
+	g.data()
+
+	return g.String()
+}
+
+// String formats the generated code as assembly. Lines with more than one label keep the first, and operands
+// referring to the others are changed to refer to it instead.
+func (g *generator) String() string {
+	aliases := make(map[string]string)
+	width := 0
+
+	for _, l := range g.code {
+		for _, label := range l.labels {
+			aliases[label] = l.labels[0]
+		}
+
+		if len(l.labels) > 0 && len(l.labels[0]) > width {
+			width = len(l.labels[0])
+		}
+	}
+
+	var b strings.Builder
+	for _, l := range g.code {
+		label := ""
+		if len(l.labels) > 0 {
+			label = l.labels[0]
+		}
+
+		b.WriteString(label + strings.Repeat(" ", width-len(label)+1) + l.mnemonic)
+
+		if l.operand != "" {
+			operand := l.operand
+			if alias, ok := aliases[operand]; ok {
+				operand = alias
+			}
+
+			b.WriteString(" " + operand)
+		}
+
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,55 @@
+// Package compiler compiles a small structured language into LMC assembly that can be loaded with the lmc package's
+// Parser. It is intended to show how the constructs of a high level language are lowered onto the handful of
+// instructions the Little Man Computer has.
+//
+// A program is a list of statements, one per line:
+//
+//	n = input()
+//	total = 0
+//	while n > 0 {
+//	    total = total + n * n
+//	    n = n - 1
+//	}
+//	if total >= 100 {
+//	    print(total / 100)
+//	} else {
+//	    print(total % 100)
+//	}
+//
+// Variables hold integers and don't need to be declared. Expressions can use + - * / and % along with parentheses,
+// and conditions compare two expressions with == != < <= > or >=. Multiplication and division are carried out by
+// runtime routines built from ADD and SUB loops, which are only included in the output if they are used.
+//
+// The output targets the classic LMC with a 1 digit opcode and 2 digit operand, whose mailboxes hold 0 to 999, and
+// the runtime routines only work with non-negative numbers.
+package compiler
+
+import "fmt"
+
+// Error is an error in the source program.
+type Error struct {
+	Line int // One-indexed.
+	Col  int // One-indexed.
+	Msg  string
+}
+
+// Error returns the error string for Error.
+func (e Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// Compile compiles a program into LMC assembly.
+func Compile(src string) (string, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return "", err
+	}
+
+	p := &parser{toks: toks}
+	stmts, err := p.program()
+	if err != nil {
+		return "", err
+	}
+
+	return newGenerator().program(stmts), nil
+}
@@ -0,0 +1,91 @@
+package compiler
+
+import (
+	"testing"
+
+	lmc "github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+func run(t *testing.T, src string, inputs []int) []int {
+	t.Helper()
+
+	code, err := Compile(src)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+
+	computer, err := lmc.NewComputerFromCode(code, 1, 2)
+	if !assert.NoError(t, err, code) {
+		return nil
+	}
+
+	result, err := computer.RunWithInputs(inputs, 100000)
+	assert.NoError(t, err, code)
+
+	return result.Outputs
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		inputs  []int
+		outputs []int
+	}{
+		{"print", "print(42)", nil, []int{42}},
+		{"arithmetic", "print(1 + 2 * 3 - (4 - 2))", nil, []int{5}},
+		{"input", "a = input()\nb = input()\nprint(a + b)", []int{3, 4}, []int{7}},
+		{"nested temporaries", "x = 5\nprint((x + 1) + (x + 2) + (x + (x + 3)))", nil, []int{26}},
+		{"multiply", "print(input() * input())", []int{12, 11}, []int{132}},
+		{"divide", "a = input()\nb = input()\nprint(a / b)\nprint(a % b)", []int{47, 5}, []int{9, 2}},
+		{"divide by zero", "print(7 / 0)", nil, []int{0}},
+		{"nested calls", "print(2 * 3 * (10 / 3) + 20 % 6)", nil, []int{20}},
+		{"if", "x = input()\nif x > 5 { print(1) } else { print(0) }", []int{6}, []int{1}},
+		{"else", "x = input()\nif x > 5 { print(1) } else { print(0) }", []int{5}, []int{0}},
+		{"else if", "x = input()\nif x < 5 {\n print(1)\n} else if x == 5 {\n print(2)\n} else {\n print(3)\n}", []int{5}, []int{2}},
+		{"bare condition", "x = input()\nif x { print(1) }\nprint(2)", []int{0}, []int{2}},
+		{"while", "n = input()\nwhile n >= 1 {\n print(n)\n n = n - 1\n}", []int{3}, []int{3, 2, 1}},
+		{"comparisons", `a = 3; b = 3
+if a == b { print(1) }
+if a != b { print(2) }
+if a <= b { print(3) }
+if a >= b { print(4) }
+if a < b { print(5) }
+if a > b { print(6) }`, nil, []int{1, 3, 4}},
+		{"squares", `// Sum the squares of 1 to n.
+n = input()
+total = 0
+while n > 0 {
+    total = total + n * n
+    n = n - 1
+}
+print(total)`, []int{5}, []int{55}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.outputs, run(t, tt.src, tt.inputs))
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"x = ", "1:5: expected an expression, got end of input"},
+		{"print(1", "1:8: expected \")\", got end of input"},
+		{"x = 1000", "1:5: number 1000 is too large, the largest is 999"},
+		{"x = 1 $", "1:7: unexpected character '$'"},
+		{"if x { print(1)", "1:16: expected \"}\", got end of input"},
+		{"x = 1 2", "1:7: expected end of statement, got \"2\""},
+		{"while = 3", "1:7: expected an expression, got \"=\""},
+	}
+
+	for _, tt := range tests {
+		_, err := Compile(tt.src)
+		assert.EqualError(t, err, tt.err, tt.src)
+	}
+}
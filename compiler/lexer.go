@@ -0,0 +1,125 @@
+package compiler
+
+import "fmt"
+
+// tokenType is the type of a token in the source language.
+type tokenType string
+
+const (
+	tokEOF     tokenType = "EOF"
+	tokNewline tokenType = "NEWLINE"
+	tokIdent   tokenType = "IDENT"
+	tokNumber  tokenType = "NUMBER"
+	tokKeyword tokenType = "KEYWORD"
+	tokSymbol  tokenType = "SYMBOL"
+)
+
+// keywords are identifiers with special meaning, which can't be used as variable names.
+var keywords = map[string]bool{
+	"if":    true,
+	"else":  true,
+	"while": true,
+	"print": true,
+	"input": true,
+}
+
+// token is a chunk of source text.
+type token struct {
+	typ  tokenType
+	lit  string
+	line int // One-indexed.
+	col  int // One-indexed.
+}
+
+// String returns a description of the token for use in error messages.
+func (t token) String() string {
+	switch t.typ {
+	case tokEOF:
+		return "end of input"
+	case tokNewline:
+		return "newline"
+	}
+
+	return fmt.Sprintf("%q", t.lit)
+}
+
+// lex splits source into tokens. Comments start with // and run to the end of the line.
+func lex(src string) ([]token, error) {
+	toks := []token{}
+	line, col := 1, 1
+
+	for i := 0; i < len(src); {
+		ch := src[i]
+		start := token{line: line, col: col}
+
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			i++
+			col++
+			continue
+
+		case ch == '\n':
+			start.typ, start.lit = tokNewline, "\n"
+			toks = append(toks, start)
+			i++
+			line, col = line+1, 1
+			continue
+
+		case ch == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+
+		case isDigit(ch):
+			j := i
+			for j < len(src) && isDigit(src[j]) {
+				j++
+			}
+			start.typ, start.lit = tokNumber, src[i:j]
+
+		case isLetter(ch):
+			j := i
+			for j < len(src) && (isLetter(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			start.typ, start.lit = tokIdent, src[i:j]
+			if keywords[start.lit] {
+				start.typ = tokKeyword
+			}
+
+		default:
+			start.typ = tokSymbol
+
+			if i+1 < len(src) {
+				switch two := src[i : i+2]; two {
+				case "==", "!=", "<=", ">=":
+					start.lit = two
+				}
+			}
+
+			if start.lit == "" {
+				switch ch {
+				case '+', '-', '*', '/', '%', '(', ')', '{', '}', '=', '<', '>', ';':
+					start.lit = string(ch)
+				default:
+					return nil, Error{line, col, fmt.Sprintf("unexpected character %q", ch)}
+				}
+			}
+		}
+
+		toks = append(toks, start)
+		i += len(start.lit)
+		col += len(start.lit)
+	}
+
+	return append(toks, token{typ: tokEOF, line: line, col: col}), nil
+}
+
+func isLetter(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
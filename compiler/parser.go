@@ -0,0 +1,312 @@
+package compiler
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// parser builds statements from a list of tokens using recursive descent.
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) cur() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.toks[p.pos]
+	if tok.typ != tokEOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+// is returns true if the current token has the literal given and is a symbol or keyword.
+func (p *parser) is(lit string) bool {
+	tok := p.cur()
+	return (tok.typ == tokSymbol || tok.typ == tokKeyword) && tok.lit == lit
+}
+
+// expect consumes a symbol or keyword, returning an error if it isn't the current token.
+func (p *parser) expect(lit string) error {
+	if !p.is(lit) {
+		return p.errorf("expected %q, got %s", lit, p.cur())
+	}
+
+	p.next()
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	tok := p.cur()
+	return Error{tok.line, tok.col, fmt.Sprintf(format, args...)}
+}
+
+// skipSeparators skips any newlines and semicolons between statements.
+func (p *parser) skipSeparators() {
+	for p.cur().typ == tokNewline || p.is(";") {
+		p.next()
+	}
+}
+
+// program parses statements until the end of the input.
+func (p *parser) program() ([]stmt, error) {
+	stmts, err := p.stmts()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.cur().typ != tokEOF {
+		return nil, p.errorf("unexpected %s", p.cur())
+	}
+
+	return stmts, nil
+}
+
+// stmts parses statements until the end of a block or the input.
+func (p *parser) stmts() ([]stmt, error) {
+	stmts := []stmt{}
+
+	for {
+		p.skipSeparators()
+		if p.cur().typ == tokEOF || p.is("}") {
+			return stmts, nil
+		}
+
+		s, err := p.stmt()
+		if err != nil {
+			return nil, err
+		}
+
+		stmts = append(stmts, s)
+
+		if p.cur().typ != tokNewline && !p.is(";") && !p.is("}") && p.cur().typ != tokEOF {
+			return nil, p.errorf("expected end of statement, got %s", p.cur())
+		}
+	}
+}
+
+// block parses statements between braces.
+func (p *parser) block() ([]stmt, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	stmts, err := p.stmts()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+
+	return stmts, nil
+}
+
+func (p *parser) stmt() (stmt, error) {
+	switch {
+	case p.is("print"):
+		p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+
+		value, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+
+		return printStmt{value}, p.expect(")")
+
+	case p.is("if"):
+		return p.ifStmt()
+
+	case p.is("while"):
+		p.next()
+		c, err := p.cond()
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+
+		return whileStmt{c, body}, nil
+
+	case p.cur().typ == tokIdent:
+		name := p.next().lit
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+
+		value, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+
+		return assign{name, value}, nil
+	}
+
+	return nil, p.errorf("expected a statement, got %s", p.cur())
+}
+
+func (p *parser) ifStmt() (stmt, error) {
+	p.next()
+
+	c, err := p.cond()
+	if err != nil {
+		return nil, err
+	}
+
+	then, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+
+	s := ifStmt{c, then, nil}
+	if !p.is("else") {
+		return s, nil
+	}
+
+	p.next()
+	if p.is("if") {
+		elseIf, err := p.ifStmt()
+		if err != nil {
+			return nil, err
+		}
+
+		s.otherwise = []stmt{elseIf}
+		return s, nil
+	}
+
+	s.otherwise, err = p.block()
+	return s, err
+}
+
+func (p *parser) cond() (cond, error) {
+	left, err := p.expr()
+	if err != nil {
+		return cond{}, err
+	}
+
+	tok := p.cur()
+	switch tok.lit {
+	case "==", "!=", "<", "<=", ">", ">=":
+		if tok.typ != tokSymbol {
+			break
+		}
+
+		p.next()
+		right, err := p.expr()
+		if err != nil {
+			return cond{}, err
+		}
+
+		return cond{tok.lit, left, right}, nil
+	}
+
+	return cond{"!=", left, number{0}}, nil
+}
+
+// expr parses addition and subtraction, which bind more loosely than the other operators.
+func (p *parser) expr() (expr, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.is("+") || p.is("-") {
+		op := p.next().lit
+
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		left = binary{op, left, right}
+	}
+
+	return left, nil
+}
+
+// term parses multiplication, division and remainder.
+func (p *parser) term() (expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.is("*") || p.is("/") || p.is("%") {
+		op := p.next().lit
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = binary{op, left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) unary() (expr, error) {
+	if p.is("-") {
+		p.next()
+
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return binary{"-", number{0}, operand}, nil
+	}
+
+	return p.primary()
+}
+
+func (p *parser) primary() (expr, error) {
+	tok := p.cur()
+
+	switch {
+	case tok.typ == tokNumber:
+		p.next()
+
+		n, err := strconv.Atoi(tok.lit)
+		if err != nil || n > maxValue {
+			return nil, Error{tok.line, tok.col, fmt.Sprintf("number %s is too large, the largest is %d", tok.lit, maxValue)}
+		}
+
+		return number{n}, nil
+
+	case tok.typ == tokIdent:
+		p.next()
+		return variable{tok.lit}, nil
+
+	case p.is("input"):
+		p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+
+		return inputExpr{}, p.expect(")")
+
+	case p.is("("):
+		p.next()
+
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+
+		return e, p.expect(")")
+	}
+
+	return nil, p.errorf("expected an expression, got %s", tok)
+}
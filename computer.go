@@ -2,14 +2,19 @@ package lmc
 
 import (
 	"fmt"
-	"math"
 	"strconv"
 	"strings"
+	"sync"
 )
 
-// Mailboxes represents a memory for the Little Man Computer.
+// Mailboxes represents a memory for the Little Man Computer. Each mailbox holds a number written with a fixed number
+// of digits in the radix of the mailboxes, which is usually 10.
 type Mailboxes struct {
-	mem []string
+	mem   []string
+	width int // Number of digits in a mailbox.
+	radix int // Radix the digits are in.
+
+	devices []mapping // Devices mapped into the mailboxes, in order of address.
 }
 
 // Get attempts to retrieve what is in mailbox N, indexed from 0.
@@ -22,6 +27,10 @@ func (m *Mailboxes) Get(n int) (string, error) {
 		return "", ErrInvalidMemory{n}
 	}
 
+	if device, offset, ok := m.Device(n); ok {
+		return m.format(device.Read(offset)), nil
+	}
+
 	return m.mem[n], nil
 }
 
@@ -35,16 +44,55 @@ func (m *Mailboxes) Set(n int, val string) error {
 		return ErrInvalidMemory{n}
 	}
 
+	if device, offset, ok := m.Device(n); ok {
+		num, err := m.parse(val)
+		if err != nil {
+			return err
+		}
+
+		device.Write(offset, num)
+		return nil
+	}
+
 	m.mem[n] = val
 	return nil
 }
 
+// Len returns the number of mailboxes.
+func (m *Mailboxes) Len() int {
+	return len(m.mem)
+}
+
+// Radix returns the radix the numbers in the mailboxes are written in.
+func (m *Mailboxes) Radix() int {
+	return m.radix
+}
+
+// format writes a number with the digits it would have in a mailbox.
+func (m *Mailboxes) format(n int) string {
+	return formatInt(n, m.radix, m.width)
+}
+
+// parse returns the number written in a mailbox.
+func (m *Mailboxes) parse(val string) (int, error) {
+	n, err := strconv.ParseInt(val, m.radix, 64)
+	return int(n), err
+}
+
 // NewMailboxes returns a new Mailboxes instance with the size specified.
 func NewMailboxes(inSize, opSize int) *Mailboxes {
-	size := int(math.Pow(10, float64(inSize+opSize)))
+	return NewRadixMailboxes(10, inSize, opSize)
+}
+
+// NewRadixMailboxes returns a new Mailboxes instance whose mailboxes hold numbers in the radix given, such as 2 for
+// a binary computer, with the number of digits specified. There is a mailbox for every number with that many digits.
+func NewRadixMailboxes(radix, inSize, opSize int) *Mailboxes {
+	size := pow(radix, inSize+opSize)
 
 	mb := &Mailboxes{
-		mem: make([]string, size),
+		mem:   make([]string, size),
+		width: inSize + opSize,
+		radix: radix,
 	}
 
 	def := strings.Repeat("0", inSize+opSize)
@@ -72,12 +120,48 @@ type Computer struct {
 	ProgramCounter int
 	Accumulator    int
 
+	// StackPointer is the address of the value on top of the stack used by PUSH, POP, CALL and RET in the extended
+	// instruction set. The stack is empty when it's equal to the number of mailboxes.
+	StackPointer int
+
 	InstructionSize int
 	OperandSize     int
 
 	Messages chan Msg
 	Step     chan struct{}
 	Inbox    chan int
+
+	// Cycles is the number of instructions that have been executed.
+	Cycles int
+
+	// Interrupts, if set, lets the computer be interrupted. It's used by RTI, EI and DI, which are only in instruction
+	// sets returned by InstructionSet.WithInterrupts.
+	Interrupts *Interrupts
+
+	// InstructionSet decides what each instruction does. If it's nil, DefaultInstructionSet is used.
+	InstructionSet *InstructionSet
+
+	// Arithmetic decides what happens when the accumulator doesn't fit in a mailbox. If it's empty,
+	// ArithmeticUnbounded is used.
+	Arithmetic Arithmetic
+
+	// Coverage, if set, records which instructions Run executes and which way its branches go.
+	Coverage *Coverage
+
+	// MAR, MDR and CIR are the memory address register, memory data register and current instruction register. The
+	// MAR holds the address of the last mailbox read or written, the MDR the value that was read or written, and the
+	// CIR the instruction being executed.
+	MAR int
+	MDR int
+	CIR int
+
+	// MicroStepping, if true, makes Run stop after each phase of an instruction as well as before it.
+	MicroStepping bool
+
+	image     []string // Contents of the mailboxes when the program was loaded, restored by Reset.
+	executing bool     // Whether the execute phase of the current instruction hasn't been finished yet.
+	halt      chan struct{}
+	haltOnce  sync.Once
 }
 
 // Status represents a status of the computer.
@@ -90,8 +174,28 @@ const (
 	Log       Status = "Log"
 	Done      Status = "Done"
 	Output    Status = "Output"
+
+	// MemoryRead and MemoryWrite are sent after an instruction reads or writes a mailbox, with the address as the
+	// value. Fetching an instruction doesn't count as a read.
+	MemoryRead  Status = "MemoryRead"
+	MemoryWrite Status = "MemoryWrite"
+
+	// Interrupt is sent when an interrupt is taken, with the address of the instruction that was interrupted as the
+	// value. It comes before the NeedStep for the first instruction of the handler.
+	Interrupt Status = "Interrupt"
+
+	// OutputChar is sent by OTC, with the character code as the value. NeedInput is sent by INA with the value
+	// InputChar, and expects the code of a character on the Inbox.
+	OutputChar Status = "OutputChar"
+
+	// MicroStep is sent in micro-step mode after each phase of an instruction, with the Phase as the value. The
+	// computer then waits for a value on the Step channel before carrying on.
+	MicroStep Status = "MicroStep"
 )
 
+// InputChar is the value of a NeedInput message when the computer wants a character rather than a number.
+const InputChar = "char"
+
 // Msg is a message sent to the user of a Little Man Computer.
 type Msg struct {
 	Status Status // What kind of message this is.
@@ -103,11 +207,14 @@ type Msg struct {
 func NewComputerFromMailboxes(mailboxes *Mailboxes, inSize, opSize int) *Computer {
 	return &Computer{
 		Mailboxes:       mailboxes,
+		StackPointer:    mailboxes.Len(),
 		InstructionSize: inSize,
 		OperandSize:     opSize,
 		Messages:        make(chan Msg),
 		Step:            make(chan struct{}),
 		Inbox:           make(chan int),
+		image:           append([]string{}, mailboxes.mem...),
+		halt:            make(chan struct{}),
 	}
 }
 
@@ -125,145 +232,492 @@ func NewComputerFromCode(code string, inSize, opSize int) (*Computer, error) {
 	return NewComputerFromMailboxes(mailboxes, inSize, opSize), nil
 }
 
+// Reset puts the program back into the mailboxes as it was when the computer was created or last reloaded, clears
+// the registers, the cycle count, any input that was sent but not received and any interrupt that hasn't been taken,
+// puts interrupt masking back as it was configured and resets the mapped devices that are Resetters, so that the
+// program can be run again from the start without creating a new computer. The channels are kept, and a computer
+// that was halted can be run again once it's been reset. It must not be called while Run is running.
+func (c *Computer) Reset() {
+	copy(c.Mailboxes.mem, c.image)
+	c.Mailboxes.reset()
+
+	c.ProgramCounter = 0
+	c.Accumulator = 0
+	c.StackPointer = c.Mailboxes.Len()
+	c.MAR, c.MDR, c.CIR = 0, 0, 0
+	c.Cycles = 0
+	c.executing = false
+
+	if c.Interrupts != nil {
+		c.Interrupts.reset()
+	}
+
+	for pending := true; pending; {
+		select {
+		case <-c.Inbox:
+		default:
+			pending = false
+		}
+	}
+
+	c.halt = make(chan struct{})
+	c.haltOnce = sync.Once{}
+}
+
+// Reload replaces the mailboxes with ones holding another program, then resets the computer. The mailboxes must be
+// for instructions of the same size as the computer's.
+func (c *Computer) Reload(mailboxes *Mailboxes) {
+	c.Mailboxes = mailboxes
+	c.image = append(c.image[:0], mailboxes.mem...)
+	c.Reset()
+}
+
+// Halt stops a running computer. Run returns ErrHalted the next time it would wait for a step or for input, and
+// stops sending messages. It is safe to call Halt more than once.
+func (c *Computer) Halt() {
+	c.haltOnce.Do(func() {
+		close(c.halt)
+	})
+}
+
+// send sends a message to the user of the computer, unless it has been halted.
+func (c *Computer) send(msg Msg) {
+	select {
+	case c.Messages <- msg:
+	case <-c.halt:
+	}
+}
+
+// wait waits for the user of the computer to allow the next instruction to be executed.
+func (c *Computer) wait() error {
+	select {
+	case <-c.Step:
+		return nil
+	case <-c.halt:
+		return ErrHalted{}
+	}
+}
+
+// receive waits for the user of the computer to provide an input.
+func (c *Computer) receive() (int, error) {
+	select {
+	case val := <-c.Inbox:
+		return val, nil
+	case <-c.halt:
+		return 0, ErrHalted{}
+	}
+}
+
+// decode splits the contents of a mailbox into an instruction code and an operand. The value is treated as a number
+// rather than split by position, so values that are shorter or longer than a mailbox still decode, though values that
+// don't fit will have an instruction code that doesn't exist.
+func (c *Computer) decode(val string) (int, int, error) {
+	n, err := c.Mailboxes.parse(val)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	scale := pow(c.Mailboxes.radix, c.OperandSize)
+	return n / scale, n % scale, nil
+}
+
+// Decode returns the definition of the instruction in a mailbox, and its operand. It returns false if the mailbox
+// doesn't exist or doesn't hold an instruction from the computer's instruction set.
+func (c *Computer) Decode(addr int) (InstructionDef, int, bool) {
+	val, err := c.Mailboxes.Peek(addr)
+	if err != nil {
+		return InstructionDef{}, 0, false
+	}
+
+	instruction, operand, err := c.decode(val)
+	if err != nil {
+		return InstructionDef{}, 0, false
+	}
+
+	def, ok := instructionSet(c.InstructionSet).Decode(instruction, operand)
+	return def, operand, ok
+}
+
+// read reads the number in a mailbox for an instruction, and tells the user of the computer that it was read. This is
+// the operand fetch phase.
+func (c *Computer) read(addr int) (int, error) {
+	c.MAR = addr
+	val, err := c.Mailboxes.Get(addr)
+	if err != nil {
+		return 0, err
+	}
+
+	c.send(Msg{MemoryRead, fmt.Sprint(addr)})
+
+	c.MDR, err = c.Mailboxes.parse(val)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.MDR, c.phase(PhaseOperandFetch)
+}
+
+// readIndirect reads the number in the mailbox whose address is in another mailbox.
+func (c *Computer) readIndirect(addr int) (int, error) {
+	pointer, err := c.read(addr)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.read(pointer)
+}
+
+// write stores a number in a mailbox for an instruction, and tells the user of the computer that it was written. This
+// finishes the execute phase, if it hasn't been already, and is the write-back phase.
+func (c *Computer) write(addr, val int) error {
+	if err := c.execute(); err != nil {
+		return err
+	}
+
+	c.MAR, c.MDR = addr, val
+	err := c.Mailboxes.Set(addr, c.Mailboxes.format(val))
+	if err != nil {
+		return err
+	}
+
+	c.send(Msg{MemoryWrite, fmt.Sprint(addr)})
+	return c.phase(PhaseWriteBack)
+}
+
+// push pushes a number onto the stack.
+func (c *Computer) push(val int) error {
+	if c.StackPointer <= 0 {
+		return ErrStackOverflow{c.ProgramCounter}
+	}
+
+	c.StackPointer--
+	return c.write(c.StackPointer, val)
+}
+
+// pop pops the number on top of the stack.
+func (c *Computer) pop() (int, error) {
+	if c.StackPointer >= c.Mailboxes.Len() {
+		return 0, ErrStackUnderflow{c.ProgramCounter}
+	}
+
+	val, err := c.read(c.StackPointer)
+	if err != nil {
+		return 0, err
+	}
+
+	c.StackPointer++
+	return val, nil
+}
+
 // Run starts the computer, it will continue until it hits a HLT instruction.
+// Before each instruction it sends a NeedStep message, with the address of the instruction as the value, and waits
+// for a value on the Step channel. If MicroStepping is set, it also sends a MicroStep message after each phase of the
+// instruction and waits for another value on the Step channel.
 func (c *Computer) Run() error {
-	c.Messages <- Msg{Log, "Little Man warming up..."}
+	c.send(Msg{Log, "Little Man warming up..."})
 
-	for i := 0; true; i++ {
-		if i != 0 {
-			c.Messages <- Msg{NeedStep, ""}
-			<-c.Step
+	for {
+		if err := c.execute(); err != nil {
+			return err
 		}
 
-		c.Messages <- Msg{Log, fmt.Sprintf("Getting instruction/operand at address %d", c.ProgramCounter)}
-		memNum, err := c.Mailboxes.Get(c.ProgramCounter)
-		if err != nil {
+		if c.Interrupts != nil {
+			if err := c.interrupt(); err != nil {
+				c.send(Msg{Done, ""})
+				return err
+			}
+		}
+
+		c.send(Msg{NeedStep, fmt.Sprint(c.ProgramCounter)})
+		if err := c.wait(); err != nil {
 			return err
 		}
 
-		memStr := fmt.Sprint(memNum)
-		memStr = strings.Repeat("0", len(memStr)-(c.InstructionSize+c.OperandSize)) + memStr
+		c.Cycles++
+		c.Mailboxes.tick()
 
-		instructionStr := memStr[0:c.InstructionSize]
-		operandStr := memStr[c.InstructionSize:]
+		c.send(Msg{Log, fmt.Sprintf("Getting instruction/operand at address %d", c.ProgramCounter)})
 
-		c.Messages <- Msg{Log, fmt.Sprintf("Instruction code: %s, Operand: %s", instructionStr, operandStr)}
+		c.MAR = c.ProgramCounter
+		memNum, err := c.Mailboxes.Get(c.MAR)
+		if err != nil {
+			c.send(Msg{Done, ""})
+			return err
+		}
 
-		instruction, err := strconv.Atoi(instructionStr)
+		c.MDR, err = c.Mailboxes.parse(memNum)
 		if err != nil {
-			c.Messages <- Msg{Done, ""}
+			c.send(Msg{Done, ""})
 			return err
 		}
 
-		operand, err := strconv.Atoi(operandStr)
+		c.CIR = c.MDR
+		if err := c.phase(PhaseFetch); err != nil {
+			return err
+		}
+
+		instruction, operand, err := c.decode(memNum)
 		if err != nil {
-			c.Messages <- Msg{Done, ""}
+			c.send(Msg{Done, ""})
+			return err
+		}
+
+		c.send(Msg{Log, fmt.Sprintf("Instruction code: %d, Operand: %d", instruction, operand)})
+		if err := c.phase(PhaseDecode); err != nil {
 			return err
 		}
 
-		switch instruction {
-		case 1: // ADD
-			c.Messages <- Msg{Log, fmt.Sprintf("ADD; adding what is at address %d to accumulator", operand)}
-			val, err := c.Mailboxes.Get(operand)
+		c.executing = true
+
+		if c.Coverage != nil {
+			c.Coverage.recordExecution(c.ProgramCounter)
+		}
+
+		def, ok := instructionSet(c.InstructionSet).Decode(instruction, operand)
+		if !ok {
+			def.Semantics = SemanticsNone
+		}
+
+		switch def.Semantics {
+		case SemanticsAdd:
+			c.send(Msg{Log, fmt.Sprintf("%s; adding what is at address %d to accumulator", def.Mnemonic, operand)})
+			num, err := c.read(operand)
 			if err != nil {
-				c.Messages <- Msg{Done, ""}
+				c.send(Msg{Done, ""})
 				return err
 			}
 
-			num, err := strconv.Atoi(val)
+			c.Accumulator += num
+			c.send(Msg{Log, fmt.Sprintf("%s; added %d to accumulator, new value %d", def.Mnemonic, num, c.Accumulator)})
+
+		case SemanticsSubtract:
+			c.send(Msg{Log, fmt.Sprintf("%s; subtracting what is at address %d from accumulator", def.Mnemonic, operand)})
+			num, err := c.read(operand)
 			if err != nil {
-				c.Messages <- Msg{Done, ""}
+				c.send(Msg{Done, ""})
 				return err
 			}
 
-			c.Accumulator += num
-			c.Messages <- Msg{Log, fmt.Sprintf("ADD; added %d to accumulator, new value %d", num, c.Accumulator)}
+			c.Accumulator -= num
+			c.send(Msg{Log, fmt.Sprintf("%s; subtracted %d from accumulator, new value %d", def.Mnemonic, num, c.Accumulator)})
 
-		case 2: // SUB
-			c.Messages <- Msg{Log, fmt.Sprintf("SUB; subtracting what is at address %d from accumulator", operand)}
-			val, err := c.Mailboxes.Get(operand)
+		case SemanticsStore:
+			c.send(Msg{Log, fmt.Sprintf("%s; storing accumulator %d at address %d", def.Mnemonic, c.Accumulator, operand)})
+			if err := c.write(operand, c.Accumulator); err != nil {
+				c.send(Msg{Done, ""})
+				return err
+			}
+
+		case SemanticsLoad:
+			c.send(Msg{Log, fmt.Sprintf("%s; loading what is at address %d into accumulator", def.Mnemonic, operand)})
+			num, err := c.read(operand)
 			if err != nil {
-				c.Messages <- Msg{Done, ""}
+				c.send(Msg{Done, ""})
 				return err
 			}
 
-			num, err := strconv.Atoi(val)
+			c.Accumulator = num
+			c.send(Msg{Log, fmt.Sprintf("%s; set accumulator to %d", def.Mnemonic, c.Accumulator)})
+
+		case SemanticsBranch:
+			c.send(Msg{Log, fmt.Sprintf("%s; setting program counter to %d", def.Mnemonic, operand)})
+			c.ProgramCounter = operand
+			continue
+
+		case SemanticsBranchZero:
+			c.send(Msg{Log, fmt.Sprintf("%s; setting program counter to %d if accumulator is zero", def.Mnemonic, operand)})
+			if c.Coverage != nil {
+				c.Coverage.recordBranch(c.ProgramCounter, c.Accumulator == 0)
+			}
+
+			if c.Accumulator == 0 {
+				c.ProgramCounter = operand
+				c.send(Msg{Log, fmt.Sprintf("%s; accumlator IS zero, program counter now %d", def.Mnemonic, operand)})
+				continue
+			}
+
+		case SemanticsBranchPositive:
+			c.send(Msg{Log, fmt.Sprintf("%s; setting program counter to %d if accumulator is positive", def.Mnemonic, operand)})
+			if c.Coverage != nil {
+				c.Coverage.recordBranch(c.ProgramCounter, c.Accumulator >= 0)
+			}
+
+			if c.Accumulator >= 0 {
+				c.ProgramCounter = operand
+				c.send(Msg{Log, fmt.Sprintf(
+					"%s; accumlator IS positive (%d), program counter now %d", def.Mnemonic, c.Accumulator, operand,
+				)})
+				continue
+			}
+
+		case SemanticsInput:
+			c.send(Msg{Log, fmt.Sprintf("%s; Need input from user", def.Mnemonic)})
+			c.send(Msg{NeedInput, ""})
+
+			val, err := c.receive()
 			if err != nil {
-				c.Messages <- Msg{Done, ""}
 				return err
 			}
 
-			c.Accumulator -= num
-			c.Messages <- Msg{Log, fmt.Sprintf("SUB; subtracted %d from accumulator, new value %d", num, c.Accumulator)}
+			c.send(Msg{Log, fmt.Sprintf("%s; Recieved input %d from user, set accumlator", def.Mnemonic, val)})
+			c.Accumulator = val
+
+		case SemanticsInputChar:
+			c.send(Msg{Log, fmt.Sprintf("%s; Need character input from user", def.Mnemonic)})
+			c.send(Msg{NeedInput, InputChar})
 
-		case 3: // STA
-			c.Messages <- Msg{Log, fmt.Sprintf("STA; storing accumulator %d at address %d", c.Accumulator, operand)}
-			err := c.Mailboxes.Set(operand, leftPadInt(c.Accumulator, c.InstructionSize+c.OperandSize))
+			val, err := c.receive()
 			if err != nil {
-				c.Messages <- Msg{Done, ""}
 				return err
 			}
 
-		case 5: // LDA
-			c.Messages <- Msg{Log, fmt.Sprintf("LDA; loading what is at address %d into accumulator", operand)}
-			val, err := c.Mailboxes.Get(operand)
+			c.send(Msg{Log, fmt.Sprintf("%s; Recieved character %d from user, set accumlator", def.Mnemonic, val)})
+			c.Accumulator = val
+
+		case SemanticsOutputChar:
+			c.send(Msg{Log, fmt.Sprintf("%s; Outputting accumulator contents as a character", def.Mnemonic)})
+			c.send(Msg{OutputChar, fmt.Sprint(c.Accumulator)})
+
+		case SemanticsOutput:
+			c.send(Msg{Log, fmt.Sprintf("%s; Outputting accumulator contents", def.Mnemonic)})
+			c.send(Msg{Output, fmt.Sprint(c.Accumulator)})
+
+		case SemanticsLoadIndirect:
+			c.send(Msg{Log, fmt.Sprintf("%s; loading what is at the address at %d into accumulator", def.Mnemonic, operand)})
+			num, err := c.readIndirect(operand)
 			if err != nil {
-				c.Messages <- Msg{Done, ""}
+				c.send(Msg{Done, ""})
 				return err
 			}
 
-			num, err := strconv.Atoi(val)
+			c.Accumulator = num
+			c.send(Msg{Log, fmt.Sprintf("%s; set accumulator to %d", def.Mnemonic, c.Accumulator)})
+
+		case SemanticsStoreIndirect:
+			c.send(Msg{Log, fmt.Sprintf(
+				"%s; storing accumulator %d at the address at %d", def.Mnemonic, c.Accumulator, operand,
+			)})
+			addr, err := c.read(operand)
+			if err == nil {
+				err = c.write(addr, c.Accumulator)
+			}
+
 			if err != nil {
-				c.Messages <- Msg{Done, ""}
+				c.send(Msg{Done, ""})
+				return err
+			}
+
+		case SemanticsAnd, SemanticsOr, SemanticsMultiply, SemanticsDivide:
+			c.send(Msg{Log, fmt.Sprintf("%s; combining what is at address %d with accumulator", def.Mnemonic, operand)})
+			num, err := c.read(operand)
+			if err == nil && def.Semantics == SemanticsDivide && num == 0 {
+				err = ErrDivisionByZero{c.ProgramCounter}
+			}
+
+			if err != nil {
+				c.send(Msg{Done, ""})
+				return err
+			}
+
+			switch def.Semantics {
+			case SemanticsAnd:
+				c.Accumulator &= num
+			case SemanticsOr:
+				c.Accumulator |= num
+			case SemanticsMultiply:
+				c.Accumulator *= num
+			case SemanticsDivide:
+				c.Accumulator /= num
+			}
+
+			c.send(Msg{Log, fmt.Sprintf("%s; combined %d with accumulator, new value %d", def.Mnemonic, num, c.Accumulator)})
+
+		case SemanticsPush:
+			c.send(Msg{Log, fmt.Sprintf("%s; pushing accumulator %d onto the stack", def.Mnemonic, c.Accumulator)})
+			if err := c.push(c.Accumulator); err != nil {
+				c.send(Msg{Done, ""})
+				return err
+			}
+
+		case SemanticsPop:
+			c.send(Msg{Log, fmt.Sprintf("%s; popping the top of the stack into accumulator", def.Mnemonic)})
+			num, err := c.pop()
+			if err != nil {
+				c.send(Msg{Done, ""})
 				return err
 			}
 
 			c.Accumulator = num
-			c.Messages <- Msg{Log, fmt.Sprintf("LDA; set accumulator to %d", c.Accumulator)}
+			c.send(Msg{Log, fmt.Sprintf("%s; set accumulator to %d", def.Mnemonic, c.Accumulator)})
+
+		case SemanticsCall:
+			c.send(Msg{Log, fmt.Sprintf("%s; calling subroutine at %d", def.Mnemonic, operand)})
+			if err := c.push(c.ProgramCounter + 1); err != nil {
+				c.send(Msg{Done, ""})
+				return err
+			}
 
-		case 6: // BRA
-			c.Messages <- Msg{Log, fmt.Sprintf("BRA; setting program counter to %d", operand)}
 			c.ProgramCounter = operand
 			continue
 
-		case 7: // BRZ
-			c.Messages <- Msg{Log, fmt.Sprintf("BRZ; setting program counter to %d if accumulator is zero", operand)}
-			if c.Accumulator == 0 {
-				c.ProgramCounter = operand
-				c.Messages <- Msg{Log, fmt.Sprintf("BRZ; accumlator IS zero, program counter now %d", operand)}
-				continue
+		case SemanticsReturn:
+			addr, err := c.pop()
+			if err != nil {
+				c.send(Msg{Done, ""})
+				return err
 			}
 
-		case 8: // BRP
-			c.Messages <- Msg{Log, fmt.Sprintf("BRP; setting program counter to %d if accumulator is positive", operand)}
-			if c.Accumulator >= 0 {
-				c.ProgramCounter = operand
-				c.Messages <- Msg{Log, fmt.Sprintf("BRZ; accumlator IS positive (%d), program counter now %d", c.Accumulator, operand)}
-				continue
+			c.send(Msg{Log, fmt.Sprintf("%s; returning to %d", def.Mnemonic, addr)})
+			c.ProgramCounter = addr
+			continue
+
+		case SemanticsTestAndSet:
+			c.send(Msg{Log, fmt.Sprintf("%s; loading what is at address %d and setting it to 1", def.Mnemonic, operand)})
+			num, err := c.read(operand)
+			if err != nil {
+				c.send(Msg{Done, ""})
+				return err
 			}
 
-		case 9: // INP/OUT
-			if operand == 1 {
-				c.Messages <- Msg{Log, "INP; Need input from user"}
-				c.Messages <- Msg{NeedInput, ""}
-
-				val := <-c.Inbox
-				c.Messages <- Msg{Log, fmt.Sprintf("INP; Recieved input %d from user, set accumlator", val)}
-				c.Accumulator = val
-			} else if operand == 2 {
-				c.Messages <- Msg{Log, "OUT; Outputting accumulator contents"}
-				c.Messages <- Msg{Output, fmt.Sprint(c.Accumulator)}
+			if err := c.write(operand, 1); err != nil {
+				c.send(Msg{Done, ""})
+				return err
+			}
+
+			c.Accumulator = num
+			c.send(Msg{Log, fmt.Sprintf("%s; set accumulator to %d", def.Mnemonic, c.Accumulator)})
+
+		case SemanticsReturnInterrupt:
+			if err := c.returnFromInterrupt(); err != nil {
+				c.send(Msg{Done, ""})
+				return err
 			}
-		}
 
-		if operand == 0 && instruction == 0 {
-			c.Messages <- Msg{Log, "HLT; We're done here!"}
-			c.Messages <- Msg{Done, ""}
+			c.send(Msg{Log, fmt.Sprintf("%s; returning from interrupt to %d", def.Mnemonic, c.ProgramCounter)})
+			continue
+
+		case SemanticsEnableInterrupts, SemanticsDisableInterrupts:
+			masked := def.Semantics == SemanticsDisableInterrupts
+			c.send(Msg{Log, fmt.Sprintf("%s; setting interrupts masked to %t", def.Mnemonic, masked)})
+			if c.Interrupts != nil {
+				c.Interrupts.mask(masked)
+			}
+
+		case SemanticsHalt:
+			if err := c.execute(); err != nil {
+				return err
+			}
+
+			c.send(Msg{Log, fmt.Sprintf("%s; We're done here!", def.Mnemonic)})
+			c.send(Msg{Done, ""})
 			return nil
 		}
 
+		if err := c.arithmetic(); err != nil {
+			c.send(Msg{Done, ""})
+			return err
+		}
+
 		c.ProgramCounter++
 	}
-
-	return nil
 }
@@ -1,6 +1,7 @@
 package lmc_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/ollybritton/go-lmc"
@@ -36,3 +37,234 @@ func TestComputerFromMailboxes(t *testing.T) {
 		assert.Equal(t, tc.accumulator, computer.Accumulator, "expect accumulator to be correct")
 	}
 }
+
+func TestComputerHalt(t *testing.T) {
+	mbs := lmc.NewInitialisedMailboxes(1, 2, []string{"600"})
+	computer := lmc.NewComputerFromMailboxes(mbs, 1, 2)
+
+	errs := make(chan error)
+	go func() {
+		errs <- computer.Run()
+	}()
+
+	for steps := 0; steps < 10; {
+		msg := <-computer.Messages
+		if msg.Status == lmc.NeedStep {
+			computer.Step <- struct{}{}
+			steps++
+		}
+	}
+
+	computer.Halt()
+	assert.Equal(t, lmc.ErrHalted{}, <-errs, "expecting computer to have been halted")
+	assert.Equal(t, 0, computer.ProgramCounter, "expect program counter to be correct")
+}
+
+func TestComputerExtendedInstructionSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		inputs  []int
+		outputs []int
+		err     error
+	}{
+		{"multiply", "INP\nMUL seven\nOUT\nHLT\nseven DAT 7", []int{6}, []int{42}, nil},
+		{"divide", "INP\nDIV seven\nOUT\nHLT\nseven DAT 7", []int{50}, []int{7}, nil},
+		{"divide-by-zero", "INP\nDIV zero\nOUT\nHLT\nzero DAT 0", []int{50}, []int{}, lmc.ErrDivisionByZero{1}},
+		{"and-or", "INP\nAND mask\nOUT\nOR mask\nOUT\nHLT\nmask DAT 12", []int{10}, []int{8, 12}, nil},
+		{"indirect", "LDI p\nOUT\nINP\nSTI p\nLDA x\nOUT\nHLT\np DAT x\nx DAT 5", []int{9}, []int{5, 9}, nil},
+		{
+			"stack",
+			"INP\nPUSH\nINP\nPUSH\nPOP\nOUT\nPOP\nOUT\nHLT",
+			[]int{1, 2}, []int{2, 1}, nil,
+		},
+		{
+			"call",
+			"INP\nCALL double\nCALL double\nOUT\nHLT\ndouble PUSH\nSTA x\nPOP\nADD x\nRET\nx DAT 0",
+			[]int{3}, []int{12}, nil,
+		},
+		{"underflow", "POP\nHLT", nil, []int{}, lmc.ErrStackUnderflow{0}},
+		{"return-underflow", "RET\nHLT", nil, []int{}, lmc.ErrStackUnderflow{0}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := lmc.NewParser(lmc.NewLexer(tc.code))
+			parser.InstructionSet = lmc.ExtendedInstructionSet
+
+			instructions, err := parser.Parse()
+			assert.NoError(t, err, "not expecting error parsing program")
+			assert.Empty(t, lmc.ExtendedInstructionSet.Validate(instructions, 2, 2), "expect program to be valid")
+
+			mailboxes := lmc.ExtendedInstructionSet.Assemble(instructions, 2, 2)
+			computer := lmc.NewComputerFromMailboxes(mailboxes, 2, 2)
+			computer.InstructionSet = lmc.ExtendedInstructionSet
+
+			result, err := computer.RunWithInputs(tc.inputs, 1000)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.outputs, result.Outputs, "expect outputs to be correct")
+		})
+	}
+}
+
+func TestExtendedInstructionSetOpcodeSize(t *testing.T) {
+	parser := lmc.NewParser(lmc.NewLexer("MUL x\nx DAT 2"))
+	parser.InstructionSet = lmc.ExtendedInstructionSet
+
+	instructions, err := parser.Parse()
+	assert.NoError(t, err)
+
+	errs := lmc.ExtendedInstructionSet.Validate(instructions, 1, 2)
+	assert.Equal(t, []error{lmc.ErrOpcodeRange{Token: instructions[0].MnemonicToken, Digits: 1}}, errs)
+}
+
+func TestComputerGeometries(t *testing.T) {
+	code := `
+		INP
+		STA n
+loop	LDA n
+		OUT
+		SUB one
+		STA n
+		BRP loop
+		HLT
+n		DAT 0
+one		DAT 1`
+
+	tests := []struct {
+		opcodeSize, operandSize int
+		first                   string // The assembled STA n.
+	}{
+		{1, 1, "38"},
+		{1, 2, "308"},
+		{2, 2, "0308"},
+		{1, 3, "3008"},
+		{2, 3, "03008"},
+		{3, 2, "00308"},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%d-%d", tc.opcodeSize, tc.operandSize), func(t *testing.T) {
+			instructions, err := lmc.NewParser(lmc.NewLexer(code)).Parse()
+			assert.NoError(t, err)
+			assert.Empty(t, lmc.Validate(instructions, tc.opcodeSize, tc.operandSize))
+
+			mailboxes := lmc.Assemble(instructions, tc.opcodeSize, tc.operandSize)
+			sta, _ := mailboxes.Get(1)
+			assert.Equal(t, tc.first, sta, "expect labels to be assembled at the operand size")
+
+			disassembled := lmc.Disassemble(mailboxes, tc.opcodeSize, tc.operandSize)
+			reassembled := lmc.Assemble(disassembled, tc.opcodeSize, tc.operandSize)
+			for i := range instructions {
+				want, _ := mailboxes.Get(i)
+				got, _ := reassembled.Get(i)
+				assert.Equal(t, want, got, "expect mailbox %d to survive disassembly", i)
+			}
+
+			computer := lmc.NewComputerFromMailboxes(mailboxes, tc.opcodeSize, tc.operandSize)
+			result, err := computer.RunWithInputs([]int{3}, 100)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{3, 2, 1, 0}, result.Outputs)
+		})
+	}
+}
+
+func TestComputerRadix(t *testing.T) {
+	code := `
+		INP
+		AND mask
+		OUT
+		HLT
+mask	DAT 0b1111`
+
+	tests := []struct {
+		name                           string
+		radix, opcodeSize, operandSize int
+		and, mask                      string
+	}{
+		{"binary", 2, 5, 8, "0110000000100", "0000000001111"},
+		{"octal", 8, 2, 3, "14004", "00017"},
+		{"hexadecimal", 16, 2, 2, "0c04", "000f"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := lmc.NewParser(lmc.NewLexer(code))
+			parser.InstructionSet = lmc.ExtendedInstructionSet
+
+			instructions, err := parser.Parse()
+			assert.NoError(t, err)
+
+			set := lmc.ExtendedInstructionSet
+			assert.Empty(t, set.ValidateRadix(instructions, tc.radix, tc.opcodeSize, tc.operandSize))
+
+			mailboxes, _ := set.AssembleRadix(instructions, tc.radix, tc.opcodeSize, tc.operandSize)
+			assert.Equal(t, tc.radix, mailboxes.Radix())
+
+			and, _ := mailboxes.Get(1)
+			assert.Equal(t, tc.and, and, "expect instructions to be written in the radix")
+			mask, _ := mailboxes.Get(4)
+			assert.Equal(t, tc.mask, mask, "expect data to be written in the radix")
+
+			assert.Equal(t, "AND 4", lmc.Format(set.Disassemble(mailboxes, tc.opcodeSize, tc.operandSize)[1:2])[8:13],
+				"expect disassembly to be in decimal")
+
+			computer := lmc.NewComputerFromMailboxes(mailboxes, tc.opcodeSize, tc.operandSize)
+			computer.InstructionSet = set
+
+			result, err := computer.RunWithInputs([]int{0x5A}, 100)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{0xA}, result.Outputs)
+		})
+	}
+
+	instructions, err := lmc.NewParser(lmc.NewLexer("DAT 0x1000\nLDA 0b100000000")).Parse()
+	assert.NoError(t, err)
+
+	errs := lmc.DefaultInstructionSet.ValidateRadix(instructions, 16, 1, 2)
+	assert.Equal(t, []error{
+		lmc.ErrOperandRange{Token: instructions[0].OperandToken, Digits: 3},
+		lmc.ErrOperandRange{Token: instructions[1].OperandToken, Digits: 2},
+	}, errs, "expect operands to have to fit in the radix")
+
+	assert.Equal(t, []error{lmc.ErrMailboxSize{Radix: 2, OpcodeSize: 10, OperandSize: 10}},
+		lmc.DefaultInstructionSet.ValidateRadix(instructions, 2, 10, 10))
+}
+
+func TestComputerReset(t *testing.T) {
+	computer, err := lmc.NewComputerFromCode("INP\nSTA 9\nOUT\nHLT", 1, 2)
+	assert.NoError(t, err)
+
+	result, err := computer.RunWithInputs([]int{5}, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5}, result.Outputs)
+
+	computer.Reset()
+	stored, _ := computer.Mailboxes.Get(9)
+	assert.Equal(t, "000", stored, "expect the program to be put back")
+	assert.Equal(t, 0, computer.ProgramCounter)
+	assert.Equal(t, 0, computer.Accumulator)
+	assert.Equal(t, 0, computer.Cycles)
+
+	_, err = computer.RunWithInputs(nil, 100)
+	assert.Equal(t, lmc.ErrInputExhausted{}, err)
+
+	computer.Reset()
+	result, err = computer.RunWithInputs([]int{7}, 100)
+	assert.NoError(t, err, "expect a halted computer to run again once reset")
+	assert.Equal(t, []int{7}, result.Outputs)
+	assert.Equal(t, 4, computer.Cycles)
+
+	other, err := lmc.NewComputerFromCode("LDA 3\nOUT\nHLT\nDAT 42", 1, 2)
+	assert.NoError(t, err)
+
+	computer.Reload(other.Mailboxes)
+	result, err = computer.RunWithInputs(nil, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{42}, result.Outputs)
+
+	computer.Reset()
+	result, err = computer.RunWithInputs(nil, 100)
+	assert.NoError(t, err, "expect reset to put back the program that was reloaded")
+	assert.Equal(t, []int{42}, result.Outputs)
+}
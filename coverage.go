@@ -0,0 +1,58 @@
+package lmc
+
+import "sync"
+
+// Coverage records which instructions a program executed and which way its conditional branches went. Set the
+// Coverage field of a Computer to collect it while the computer runs. The same Coverage can be shared between
+// computers, including ones running at the same time, to collect coverage across a set of runs.
+type Coverage struct {
+	mu       sync.Mutex
+	executed map[int]int
+	taken    map[int]int
+	notTaken map[int]int
+}
+
+// NewCoverage returns an empty Coverage.
+func NewCoverage() *Coverage {
+	return &Coverage{
+		executed: make(map[int]int),
+		taken:    make(map[int]int),
+		notTaken: make(map[int]int),
+	}
+}
+
+// Executions returns the number of times the instruction in a mailbox was executed.
+func (c *Coverage) Executions(addr int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.executed[addr]
+}
+
+// Branch returns the number of times the BRZ or BRP instruction in a mailbox was taken and not taken.
+func (c *Coverage) Branch(addr int) (taken, notTaken int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.taken[addr], c.notTaken[addr]
+}
+
+// recordExecution records that the instruction in a mailbox was executed.
+func (c *Coverage) recordExecution(addr int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.executed[addr]++
+}
+
+// recordBranch records which way a conditional branch went.
+func (c *Coverage) recordBranch(addr int, taken bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if taken {
+		c.taken[addr]++
+	} else {
+		c.notTaken[addr]++
+	}
+}
@@ -0,0 +1,46 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// uncoveredMarker marks instructions that were never executed and branches that weren't taken both ways, as gcov
+// does.
+const uncoveredMarker = "#####"
+
+// WriteAnnotated writes the source of the program in the style of gcov, with each line prefixed by the number of
+// times it was executed. Lines that aren't instructions are marked with - and instructions that were never executed
+// with #####. Each branch is followed by a line saying how many times it was taken and not taken.
+func (r *Report) WriteAnnotated(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	s := r.Summary
+
+	fmt.Fprintf(bw, "%9s:%5d:Source:%s\n", "-", 0, r.Program)
+	fmt.Fprintf(bw, "%9s:%5d:Instructions:%.1f%% (%d/%d)\n", "-", 0, s.Instructions.Percent, s.Instructions.Covered, s.Instructions.Total)
+	fmt.Fprintf(bw, "%9s:%5d:Branches:%.1f%% (%d/%d)\n", "-", 0, s.Branches.Percent, s.Branches.Covered, s.Branches.Total)
+
+	for _, line := range r.Lines {
+		count := "-"
+		switch {
+		case line.Instruction && line.Executions == 0:
+			count = uncoveredMarker
+		case line.Instruction:
+			count = fmt.Sprint(line.Executions)
+		}
+
+		fmt.Fprintf(bw, "%9s:%5d:%s\n", count, line.Number, line.Text)
+
+		if line.Branch {
+			marker := ""
+			if line.Taken == 0 || line.NotTaken == 0 {
+				marker = uncoveredMarker
+			}
+
+			fmt.Fprintf(bw, "%9s:%5s: branch taken %d, not taken %d\n", marker, "", line.Taken, line.NotTaken)
+		}
+	}
+
+	return bw.Flush()
+}
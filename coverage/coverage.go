@@ -0,0 +1,144 @@
+// Package coverage reports the instruction and branch coverage collected by an lmc.Coverage, as an annotated copy of
+// the source, an HTML page or a JSON summary.
+package coverage
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// Report is the coverage of a single program.
+type Report struct {
+	Program string
+	Lines   []Line
+	Summary Summary
+}
+
+// Line is the coverage of a single line of source.
+type Line struct {
+	Number int // One-indexed.
+	Text   string
+
+	Instruction bool // Whether the line assembled into an instruction rather than data.
+	Executions  int
+
+	Branch   bool // Whether the line is a conditional branch, such as BRZ or BRP.
+	Taken    int
+	NotTaken int
+}
+
+// Covered returns true if the line is an instruction that was executed, and both ways were taken if it's a branch.
+func (l Line) Covered() bool {
+	return l.Executions > 0 && (!l.Branch || (l.Taken > 0 && l.NotTaken > 0))
+}
+
+// Summary is how much of a program was covered, in a form suitable for checking automatically.
+type Summary struct {
+	Program      string `json:"program"`
+	Instructions Count  `json:"instructions"`
+
+	// Branches counts each side of every conditional branch separately, so a branch that was only ever taken is half
+	// covered.
+	Branches Count `json:"branches"`
+
+	UncoveredLines  []int `json:"uncoveredLines"`  // Lines with instructions that were never executed.
+	PartialBranches []int `json:"partialBranches"` // Lines with branches that weren't taken both ways.
+}
+
+// Count is how many of something were covered.
+type Count struct {
+	Covered int     `json:"covered"`
+	Total   int     `json:"total"`
+	Percent float64 `json:"percent"`
+}
+
+func newCount(covered, total int) Count {
+	percent := 100.0
+	if total > 0 {
+		percent = 100 * float64(covered) / float64(total)
+	}
+
+	return Count{covered, total, percent}
+}
+
+// New builds a coverage report for a program from its source, the instruction set and source map it was assembled with
+// and the coverage collected while running it. Instructions are told apart from data, and branches from other
+// instructions, by their definitions in the set, so that sets with other mnemonics are reported on properly.
+func New(program, source string, set *lmc.InstructionSet, sourceMap lmc.SourceMap, coverage *lmc.Coverage) *Report {
+	if set == nil {
+		set = lmc.DefaultInstructionSet
+	}
+
+	r := &Report{Program: program}
+
+	var instructions, executed, branches, taken int
+	uncovered, partial := []int{}, []int{}
+
+	for i, text := range strings.Split(strings.TrimSuffix(source, "\n"), "\n") {
+		line := Line{Number: i + 1, Text: text}
+
+		for _, addr := range sourceMap.Addresses(i) {
+			def, _ := set.Lookup(sourceMap[addr].Mnemonic)
+			if def.Kind == lmc.OperandData {
+				line.Executions += coverage.Executions(addr)
+				continue
+			}
+
+			switch def.Semantics {
+			case lmc.SemanticsBranchZero, lmc.SemanticsBranchPositive:
+				t, n := coverage.Branch(addr)
+				line.Branch = true
+				line.Taken += t
+				line.NotTaken += n
+			}
+
+			line.Instruction = true
+			line.Executions += coverage.Executions(addr)
+		}
+
+		if line.Instruction {
+			instructions++
+			if line.Executions > 0 {
+				executed++
+			} else {
+				uncovered = append(uncovered, line.Number)
+			}
+		}
+
+		if line.Branch {
+			branches += 2
+			if line.Taken > 0 {
+				taken++
+			}
+			if line.NotTaken > 0 {
+				taken++
+			}
+			if line.Taken == 0 || line.NotTaken == 0 {
+				partial = append(partial, line.Number)
+			}
+		}
+
+		r.Lines = append(r.Lines, line)
+	}
+
+	r.Summary = Summary{
+		Program:         program,
+		Instructions:    newCount(executed, instructions),
+		Branches:        newCount(taken, branches),
+		UncoveredLines:  uncovered,
+		PartialBranches: partial,
+	}
+
+	return r
+}
+
+// WriteJSON writes the summary of the report as JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r.Summary)
+}
@@ -0,0 +1,147 @@
+package coverage
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+const countdown = `        INP
+loop    BRZ done
+        OUT
+        SUB one
+        BRA loop
+done    HLT
+        OUT
+one     DAT 1
+`
+
+func report(t *testing.T, inputs ...int) *Report {
+	t.Helper()
+
+	return reportSet(t, lmc.DefaultInstructionSet, countdown, inputs...)
+}
+
+// reportSet runs a program written using an instruction set once for each input, and reports its coverage.
+func reportSet(t *testing.T, set *lmc.InstructionSet, code string, inputs ...int) *Report {
+	t.Helper()
+
+	parser := lmc.NewParser(lmc.NewLexer(code))
+	parser.InstructionSet = set
+	instructions, err := parser.Parse()
+	assert.NoError(t, err)
+
+	cov := lmc.NewCoverage()
+	mailboxes, sourceMap := set.AssembleWithSourceMap(instructions, 1, 2)
+
+	for _, input := range inputs {
+		computer := lmc.NewComputerFromMailboxes(mailboxes, 1, 2)
+		computer.InstructionSet = set
+		computer.Coverage = cov
+
+		_, err := computer.RunWithInputs([]int{input}, 1000)
+		assert.NoError(t, err)
+	}
+
+	return New("countdown.lmc", code, set, sourceMap, cov)
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		inputs []int
+		want   Summary
+	}{
+		{
+			[]int{0},
+			Summary{
+				Program:         "countdown.lmc",
+				Instructions:    Count{3, 7, 100 * 3.0 / 7},
+				Branches:        Count{1, 2, 50},
+				UncoveredLines:  []int{3, 4, 5, 7},
+				PartialBranches: []int{2},
+			},
+		},
+		{
+			[]int{0, 2},
+			Summary{
+				Program:         "countdown.lmc",
+				Instructions:    Count{6, 7, 100 * 6.0 / 7},
+				Branches:        Count{2, 2, 100},
+				UncoveredLines:  []int{7},
+				PartialBranches: []int{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, report(t, tt.inputs...).Summary)
+	}
+}
+
+func TestSummaryInstructionSet(t *testing.T) {
+	renamed := map[string]string{"BRZ": "JZ", "BRA": "JMP", "DAT": "WORD"}
+	set := &lmc.InstructionSet{Name: "renamed"}
+	for _, def := range lmc.DefaultInstructionSet.Instructions {
+		if mnemonic, ok := renamed[def.Mnemonic]; ok {
+			def.Mnemonic = mnemonic
+		}
+		set.Instructions = append(set.Instructions, def)
+	}
+
+	code := "        INP\nloop    JZ done\n        OUT\n        SUB one\n        JMP loop\ndone    HLT\n        OUT\n" +
+		"one     WORD 1\n"
+
+	assert.Equal(t, Summary{
+		Program:         "countdown.lmc",
+		Instructions:    Count{3, 7, 100 * 3.0 / 7},
+		Branches:        Count{1, 2, 50},
+		UncoveredLines:  []int{3, 4, 5, 7},
+		PartialBranches: []int{2},
+	}, reportSet(t, set, code, 0).Summary, "expect branches and data to be found by their definitions")
+}
+
+func TestWriteAnnotated(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, report(t, 2).WriteAnnotated(&b))
+
+	assert.Equal(t, `        -:    0:Source:countdown.lmc
+        -:    0:Instructions:85.7% (6/7)
+        -:    0:Branches:100.0% (2/2)
+        1:    1:        INP
+        3:    2:loop    BRZ done
+         :     : branch taken 1, not taken 2
+        2:    3:        OUT
+        2:    4:        SUB one
+        2:    5:        BRA loop
+        1:    6:done    HLT
+    #####:    7:        OUT
+        -:    8:one     DAT 1
+`, b.String())
+}
+
+func TestWriteHTML(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, report(t, 0).WriteHTML(&b))
+
+	html := b.String()
+	assert.Contains(t, html, "<title>Coverage of countdown.lmc</title>")
+	assert.Contains(t, html, "Branches: 50.0% (1/2)")
+	assert.Contains(t, html, `<tr class="partial" title="taken 1, not taken 0">`)
+	assert.Contains(t, html, `<tr class="uncovered">`)
+	assert.Contains(t, html, `<td>loop    BRZ done</td>`)
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, report(t, 0, 2).WriteJSON(&b))
+
+	assert.JSONEq(t, `{
+		"program": "countdown.lmc",
+		"instructions": {"covered": 6, "total": 7, "percent": 85.71428571428571},
+		"branches": {"covered": 2, "total": 2, "percent": 100},
+		"uncoveredLines": [7],
+		"partialBranches": []
+	}`, b.String())
+}
@@ -0,0 +1,60 @@
+package coverage
+
+import (
+	"html/template"
+	"io"
+)
+
+// htmlTemplate shows the source with covered lines in green, uncovered ones in red and branches that were only taken
+// one way in yellow.
+var htmlTemplate = template.Must(template.New("coverage").Funcs(template.FuncMap{"class": class}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage of {{.Program}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; font-family: monospace; }
+td { padding: 0 0.75em; white-space: pre; }
+td.count, td.number { text-align: right; color: #666; }
+tr.covered { background: #dfd; }
+tr.uncovered { background: #fdd; }
+tr.partial { background: #ffd; }
+</style>
+</head>
+<body>
+<h1>{{.Program}}</h1>
+<p>
+Instructions: {{printf "%.1f" .Summary.Instructions.Percent}}% ({{.Summary.Instructions.Covered}}/{{.Summary.Instructions.Total}})<br>
+Branches: {{printf "%.1f" .Summary.Branches.Percent}}% ({{.Summary.Branches.Covered}}/{{.Summary.Branches.Total}})
+</p>
+<table>
+{{- range .Lines}}
+<tr class="{{class .}}"{{if .Branch}} title="taken {{.Taken}}, not taken {{.NotTaken}}"{{end}}>
+<td class="number">{{.Number}}</td><td class="count">{{if .Instruction}}{{.Executions}}{{end}}</td><td>{{.Text}}</td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// class returns the CSS class used to colour a line.
+func class(l Line) string {
+	switch {
+	case !l.Instruction:
+		return ""
+	case l.Covered():
+		return "covered"
+	case l.Executions > 0:
+		return "partial"
+	default:
+		return "uncovered"
+	}
+}
+
+// WriteHTML writes the report as an HTML page showing the source coloured by coverage. Hovering over a branch shows
+// how many times it was taken.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, r)
+}
@@ -0,0 +1,313 @@
+// Package dap implements a Debug Adapter Protocol server for LMC programs, so that editors can set breakpoints on
+// source lines, step through a program, inspect the accumulator, program counter and mailboxes, and provide input
+// through the debug console.
+package dap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/internal/wire"
+	"github.com/ollybritton/go-lmc/record"
+)
+
+// Server is a debug adapter that communicates with a single client over a reader and writer, usually stdin and
+// stdout.
+type Server struct {
+	OpcodeSize  int
+	OperandSize int
+
+	in  *bufio.Reader
+	out io.Writer
+
+	mu  sync.Mutex // Guards writing to out.
+	seq int
+
+	session     *session
+	breakpoints []int // One-indexed lines requested before the program was launched.
+	configured  bool
+}
+
+// NewServer returns a new server reading requests from in and writing responses and events to out. Programs are
+// assembled with a 1 digit opcode and 2 digit operand unless the sizes are changed before calling Serve.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		OpcodeSize:  1,
+		OperandSize: 2,
+		in:          bufio.NewReader(in),
+		out:         out,
+	}
+}
+
+// Serve handles requests until the client disconnects or the input is closed.
+func (s *Server) Serve() error {
+	defer func() {
+		if s.session != nil {
+			s.session.stop()
+		}
+	}()
+
+	for {
+		body, err := wire.Read(s.in)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return err
+		}
+
+		result, err := s.handle(req)
+		if err != nil {
+			s.send(&response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Message: err.Error()})
+		} else {
+			s.send(&response{Type: "response", RequestSeq: req.Seq, Success: true, Command: req.Command, Body: result})
+		}
+
+		switch req.Command {
+		case "initialize":
+			s.event("initialized", nil)
+		case "disconnect":
+			return nil
+		}
+	}
+}
+
+// send writes a response or event, giving it the next sequence number.
+func (s *Server) send(msg interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	switch m := msg.(type) {
+	case *response:
+		m.Seq = s.seq
+	case *event:
+		m.Seq = s.seq
+	}
+
+	wire.Write(s.out, msg)
+}
+
+// event sends an event to the client.
+func (s *Server) event(name string, body interface{}) {
+	s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+// output sends text to be shown in one of the client's output categories, such as stdout or console.
+func (s *Server) output(category, text string) {
+	s.event("output", map[string]string{"category": category, "output": text})
+}
+
+// handle carries out a request, returning the body of the response.
+func (s *Server) handle(req request) (interface{}, error) {
+	switch req.Command {
+	case "initialize":
+		return map[string]bool{
+			"supportsConfigurationDoneRequest": true,
+			"supportsTerminateRequest":         true,
+			"supportsReadMemoryRequest":        true,
+		}, nil
+
+	case "launch":
+		var args launchArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		return nil, s.launch(args)
+
+	case "setBreakpoints":
+		var args setBreakpointsArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		lines := []int{}
+		for _, bp := range args.Breakpoints {
+			lines = append(lines, bp.Line)
+		}
+
+		if s.session == nil {
+			// Breakpoints can't be checked against the source map until the program is loaded.
+			s.breakpoints = lines
+			breakpoints := []Breakpoint{}
+			for _, line := range lines {
+				breakpoints = append(breakpoints, Breakpoint{Verified: true, Line: line})
+			}
+
+			return map[string]interface{}{"breakpoints": breakpoints}, nil
+		}
+
+		return map[string]interface{}{"breakpoints": s.session.setBreakpoints(lines)}, nil
+
+	case "configurationDone":
+		s.configured = true
+		if s.session != nil {
+			go s.session.start()
+		}
+
+		return nil, nil
+
+	case "threads":
+		return map[string]interface{}{"threads": []Thread{{1, "Little Man"}}}, nil
+	}
+
+	if s.session == nil {
+		if req.Command == "disconnect" {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("%s: no program has been launched", req.Command)
+	}
+
+	switch req.Command {
+	case "stackTrace":
+		frames := s.session.stackTrace()
+		return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, nil
+
+	case "scopes":
+		return map[string]interface{}{"scopes": []Scope{
+			{Name: "Registers", PresentationHint: "registers", VariablesReference: registersReference},
+			{Name: "Mailboxes", VariablesReference: mailboxesReference, Expensive: true},
+		}}, nil
+
+	case "variables":
+		var args variablesArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"variables": s.session.variables(args.VariablesReference)}, nil
+
+	case "continue":
+		s.session.cont(false)
+		return map[string]bool{"allThreadsContinued": true}, nil
+
+	case "next", "stepIn", "stepOut":
+		s.session.cont(true)
+		return nil, nil
+
+	case "pause":
+		s.session.pause()
+		return nil, nil
+
+	case "evaluate":
+		var args evaluateArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		result, err := s.session.evaluate(args.Expression)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"result": result, "variablesReference": 0}, nil
+
+	case "readMemory":
+		var args readMemoryArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		addr, err := strconv.Atoi(args.MemoryReference)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory reference: %s", args.MemoryReference)
+		}
+
+		data, err := s.session.readMemory(addr+args.Offset, args.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{
+			"address":         fmt.Sprint(addr + args.Offset),
+			"data":            base64.StdEncoding.EncodeToString(data),
+			"unreadableBytes": args.Count - len(data),
+		}, nil
+
+	case "terminate", "disconnect":
+		s.session.stop()
+		if req.Command == "terminate" {
+			s.event("terminated", nil)
+		}
+
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unsupported request: %s", req.Command)
+}
+
+// launch loads the program to be debugged. It starts running once the client has finished configuring breakpoints.
+func (s *Server) launch(args launchArguments) error {
+	if s.session != nil {
+		return fmt.Errorf("a program has already been launched")
+	}
+
+	if args.Replay != "" {
+		return s.launchReplay(args)
+	}
+
+	p, err := loadProgram(args.Program, s.OpcodeSize, s.OperandSize)
+	if err != nil {
+		return err
+	}
+
+	s.session = newSession(s, p, s.OpcodeSize, s.OperandSize, args.StopOnEntry, args.MicroStep)
+	s.session.setBreakpoints(s.breakpoints)
+
+	for _, val := range args.Inputs {
+		if err := s.session.input(val); err != nil {
+			return err
+		}
+	}
+
+	if s.configured {
+		go s.session.start()
+	}
+
+	return nil
+}
+
+// launchReplay loads a recorded session to be replayed under the debugger, giving the program the inputs that were
+// recorded rather than asking for them and stopping it if it does anything differently. Only sessions recorded with
+// settings the debugger supports can be replayed.
+func (s *Server) launchReplay(args launchArguments) error {
+	recorded, err := record.Load(args.Replay)
+	if err != nil {
+		return err
+	}
+
+	settings := recorded.Settings
+	switch {
+	case settings.Radix != 10, settings.InstructionSet != "", len(settings.Devices) != 0, settings.InterruptVector >= 0,
+		settings.Arithmetic != "" && settings.Arithmetic != string(lmc.ArithmeticUnbounded):
+		return fmt.Errorf("%s: only sessions recorded with the default settings can be replayed", args.Replay)
+	}
+
+	p, err := assemble(recorded.Program, recorded.Source, settings.OpcodeSize, settings.OperandSize)
+	if err != nil {
+		return err
+	}
+
+	microStep := args.MicroStep || settings.MicroStep
+	s.session = newSession(s, p, settings.OpcodeSize, settings.OperandSize, args.StopOnEntry, microStep)
+	s.session.replayer = record.NewReplayer(recorded)
+	s.session.setBreakpoints(s.breakpoints)
+
+	if s.configured {
+		go s.session.start()
+	}
+
+	return nil
+}
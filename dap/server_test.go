@@ -0,0 +1,271 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ollybritton/go-lmc/internal/wire"
+	"github.com/ollybritton/go-lmc/record"
+	"github.com/stretchr/testify/assert"
+)
+
+// client is a minimal debug adapter client used to drive a server in tests.
+type client struct {
+	t       *testing.T
+	w       io.WriteCloser
+	msgs    chan map[string]interface{}
+	pending []map[string]interface{}
+	seq     int
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	go func() {
+		NewServer(inR, outW).Serve()
+		outW.Close()
+	}()
+
+	c := &client{t: t, w: inW, msgs: make(chan map[string]interface{}, 100)}
+
+	go func() {
+		r := bufio.NewReader(outR)
+		for {
+			body, err := wire.Read(r)
+			if err != nil {
+				close(c.msgs)
+				return
+			}
+
+			var msg map[string]interface{}
+			json.Unmarshal(body, &msg)
+			c.msgs <- msg
+		}
+	}()
+
+	return c
+}
+
+// next returns the first message matching a predicate, keeping any others for later.
+func (c *client) next(match func(map[string]interface{}) bool) map[string]interface{} {
+	for i, msg := range c.pending {
+		if match(msg) {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			return msg
+		}
+	}
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg, ok := <-c.msgs:
+			if !ok {
+				c.t.Fatal("server closed connection")
+			}
+
+			if match(msg) {
+				return msg
+			}
+
+			c.pending = append(c.pending, msg)
+
+		case <-timeout:
+			c.t.Fatal("timed out waiting for message")
+		}
+	}
+}
+
+func (c *client) request(command string, args interface{}) map[string]interface{} {
+	c.seq++
+	seq := c.seq
+
+	assert.NoError(c.t, wire.Write(c.w, map[string]interface{}{
+		"seq": seq, "type": "request", "command": command, "arguments": args,
+	}))
+
+	return c.next(func(msg map[string]interface{}) bool {
+		return msg["type"] == "response" && msg["request_seq"] == float64(seq)
+	})
+}
+
+func (c *client) event(name string) map[string]interface{} {
+	return c.next(func(msg map[string]interface{}) bool {
+		return msg["type"] == "event" && msg["event"] == name
+	})
+}
+
+func body(msg map[string]interface{}) map[string]interface{} {
+	return msg["body"].(map[string]interface{})
+}
+
+func TestServerSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dap")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "echo.lmc")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("        INP\n\n        OUT\n        HLT\n"), 0644))
+
+	c := newClient(t)
+	defer c.w.Close()
+
+	assert.Equal(t, true, c.request("initialize", map[string]interface{}{})["success"])
+	c.event("initialized")
+
+	assert.Equal(t, true, c.request("launch", map[string]interface{}{"program": path})["success"])
+
+	bps := c.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]string{"path": path},
+		"breakpoints": []map[string]int{{"line": 2}, {"line": 3}},
+	})
+	breakpoints := body(bps)["breakpoints"].([]interface{})
+	assert.Equal(t, false, breakpoints[0].(map[string]interface{})["verified"])
+	assert.Equal(t, true, breakpoints[1].(map[string]interface{})["verified"])
+
+	c.request("configurationDone", nil)
+
+	assert.Equal(t, "console", body(c.event("output"))["category"])
+	assert.Equal(t, "input 7 queued", body(c.request("evaluate", map[string]string{"expression": "7", "context": "repl"}))["result"])
+
+	assert.Equal(t, "breakpoint", body(c.event("stopped"))["reason"])
+
+	frames := body(c.request("stackTrace", map[string]int{"threadId": 1}))["stackFrames"].([]interface{})
+	assert.Equal(t, float64(3), frames[0].(map[string]interface{})["line"])
+
+	vars := body(c.request("variables", map[string]int{"variablesReference": registersReference}))["variables"].([]interface{})
+	assert.Equal(t, "7", vars[0].(map[string]interface{})["value"])
+	assert.Equal(t, "1", vars[1].(map[string]interface{})["value"])
+
+	memory := body(c.request("readMemory", map[string]interface{}{"memoryReference": "0", "count": 4}))
+	assert.Equal(t, "A4UDhg==", memory["data"], "expecting mailboxes 901 and 902 as two bytes each")
+
+	c.request("next", map[string]int{"threadId": 1})
+	assert.Equal(t, map[string]interface{}{"category": "stdout", "output": "7\n"}, body(c.event("output")))
+	assert.Equal(t, "step", body(c.event("stopped"))["reason"])
+
+	c.request("continue", map[string]int{"threadId": 1})
+	assert.Equal(t, float64(0), body(c.event("exited"))["exitCode"])
+	c.event("terminated")
+
+	assert.Equal(t, true, c.request("disconnect", nil)["success"])
+}
+
+func TestServerMicroStep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dap")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "load.lmc")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("        LDA 2\n        HLT\n        DAT 7\n"), 0644))
+
+	c := newClient(t)
+	defer c.w.Close()
+
+	c.request("initialize", map[string]interface{}{})
+	c.event("initialized")
+	assert.Equal(t, true, c.request("launch", map[string]interface{}{
+		"program": path, "stopOnEntry": true, "microStep": true,
+	})["success"])
+	c.request("configurationDone", nil)
+	assert.Equal(t, "entry", body(c.event("stopped"))["reason"])
+
+	registers := func() map[string]string {
+		vars := body(c.request("variables", map[string]int{"variablesReference": registersReference}))["variables"]
+		values := map[string]string{}
+		for _, v := range vars.([]interface{}) {
+			values[v.(map[string]interface{})["name"].(string)] = v.(map[string]interface{})["value"].(string)
+		}
+
+		return values
+	}
+
+	for _, phase := range []string{"fetch", "decode", "operand-fetch"} {
+		c.request("stepIn", map[string]int{"threadId": 1})
+		stopped := body(c.event("stopped"))
+		assert.Equal(t, "step", stopped["reason"])
+		assert.Equal(t, "after "+phase, stopped["description"])
+	}
+
+	regs := registers()
+	assert.Equal(t, "2", regs["MAR"])
+	assert.Equal(t, "7", regs["MDR"])
+	assert.Equal(t, "502", regs["CIR"])
+	assert.Equal(t, "0", regs["Accumulator"], "expect the accumulator to be set in the execute phase")
+	assert.Equal(t, "502", body(c.request("evaluate", map[string]string{"expression": "cir"}))["result"])
+
+	c.request("continue", map[string]int{"threadId": 1})
+	assert.Equal(t, float64(0), body(c.event("exited"))["exitCode"])
+	c.event("terminated")
+}
+
+func TestServerReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dap")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	session := &record.Session{
+		Version:  record.Version,
+		Program:  filepath.Join(dir, "add.lmc"),
+		Source:   "INP\nSTA 99\nINP\nADD 99\nOUT\nHLT\n",
+		Settings: record.Settings{OpcodeSize: 1, OperandSize: 2, Radix: 10, InterruptVector: -1},
+		Events: []record.Event{
+			{Cycle: 1, Kind: record.Input, Value: 5},
+			{Cycle: 3, Kind: record.Input, Value: 7},
+			{Cycle: 5, Kind: record.Output, Value: 12},
+		},
+		Cycles: 6,
+	}
+
+	replay := func(path string) *client {
+		c := newClient(t)
+		c.request("initialize", map[string]interface{}{})
+		c.event("initialized")
+		assert.Equal(t, true, c.request("launch", map[string]interface{}{"replay": path})["success"])
+		c.request("configurationDone", nil)
+
+		return c
+	}
+
+	matching := filepath.Join(dir, "matching.json")
+	assert.NoError(t, session.Save(matching))
+
+	c := replay(matching)
+	defer c.w.Close()
+
+	assert.Equal(t, "12\n", body(c.event("output"))["output"])
+	assert.Equal(t, float64(0), body(c.event("exited"))["exitCode"])
+
+	session.Events[2].Value = 13
+	diverging := filepath.Join(dir, "diverging.json")
+	assert.NoError(t, session.Save(diverging))
+
+	c = replay(diverging)
+	defer c.w.Close()
+
+	stderr := c.next(func(msg map[string]interface{}) bool {
+		return msg["event"] == "output" && body(msg)["category"] == "stderr"
+	})
+	assert.Contains(t, body(stderr)["output"], "recorded output 13 at cycle 5, replayed output 12 at cycle 5")
+	assert.Equal(t, float64(1), body(c.event("exited"))["exitCode"])
+	session.Events = []record.Event{{Cycle: 1, Kind: record.Input, Value: 5}, {Cycle: 3, Kind: record.NoInput}}
+	session.Cycles = 3
+	session.Error = "program asked for more input after 0 outputs"
+	exhausted := filepath.Join(dir, "exhausted.json")
+	assert.NoError(t, session.Save(exhausted))
+
+	c = replay(exhausted)
+	defer c.w.Close()
+
+	stderr = c.next(func(msg map[string]interface{}) bool {
+		return msg["event"] == "output" && body(msg)["category"] == "stderr"
+	})
+	assert.Equal(t, "Error running computer: program asked for more input after 0 outputs\n", body(stderr)["output"])
+	assert.Equal(t, float64(1), body(c.event("exited"))["exitCode"])
+}
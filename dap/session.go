@@ -0,0 +1,508 @@
+package dap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/record"
+)
+
+// Variable references used for the scopes shown when the program is stopped.
+const (
+	registersReference = 1
+	mailboxesReference = 2
+)
+
+// program is an assembled .lmc file along with its source.
+type program struct {
+	source    Source
+	lines     []string
+	mailboxes *lmc.Mailboxes
+	sourceMap lmc.SourceMap
+}
+
+// loadProgram reads, parses and assembles the file at path.
+func loadProgram(path string, opcodeSize, operandSize int) (*program, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return assemble(path, string(bytes), opcodeSize, operandSize)
+}
+
+// assemble parses and assembles the source of the file at path.
+func assemble(path, source string, opcodeSize, operandSize int) (*program, error) {
+	instructions, err := lmc.NewParser(lmc.NewLexer(source)).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	if errs := lmc.Validate(instructions, opcodeSize, operandSize); len(errs) != 0 {
+		return nil, errs[0]
+	}
+
+	mailboxes, sourceMap := lmc.AssembleWithSourceMap(instructions, opcodeSize, operandSize)
+
+	return &program{
+		source:    Source{Name: filepath.Base(path), Path: path},
+		lines:     strings.Split(source, "\n"),
+		mailboxes: mailboxes,
+		sourceMap: sourceMap,
+	}, nil
+}
+
+// session is a single run of a program under the debugger. The computer runs in its own goroutine and the session
+// decides, before every instruction, whether it should stop and wait for the client. When micro-stepping, stepping
+// also stops after each phase of an instruction.
+type session struct {
+	server   *Server
+	program  *program
+	computer *lmc.Computer
+	replayer *record.Replayer // Checks the run against a recording, if it's a replay.
+
+	mu          sync.Mutex
+	breakpoints map[int]bool // Zero-indexed lines with breakpoints.
+	entry       bool         // Stop before the first instruction.
+	stepping    bool         // Stop before the next instruction.
+	pausing     bool         // Stop as soon as possible.
+	stopped     bool
+
+	resume chan struct{}
+	inputs chan int
+	quit   chan struct{}
+	once   sync.Once
+}
+
+// newSession returns a session that will debug the program given once started.
+func newSession(server *Server, p *program, opcodeSize, operandSize int, stopOnEntry, microStep bool) *session {
+	computer := lmc.NewComputerFromMailboxes(p.mailboxes, opcodeSize, operandSize)
+	computer.MicroStepping = microStep
+
+	return &session{
+		server:      server,
+		program:     p,
+		computer:    computer,
+		breakpoints: make(map[int]bool),
+		entry:       stopOnEntry,
+		resume:      make(chan struct{}),
+		inputs:      make(chan int, 1024),
+		quit:        make(chan struct{}),
+	}
+}
+
+// start runs the program until it halts or the session is stopped.
+func (s *session) start() {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.computer.Run()
+	}()
+
+	for {
+		select {
+		case msg := <-s.computer.Messages:
+			if s.replayer != nil {
+				s.replayer.Observe(msg)
+				if s.replayer.Err() != nil {
+					s.computer.Halt()
+					s.finish(<-errs)
+					return
+				}
+			}
+
+			if !s.handle(msg) {
+				s.computer.Halt()
+				return
+			}
+
+		case err := <-errs:
+			s.finish(err)
+			return
+
+		case <-s.quit:
+			s.computer.Halt()
+			return
+		}
+	}
+}
+
+// stop ends the session, halting the computer if it's still running.
+func (s *session) stop() {
+	s.once.Do(func() {
+		close(s.quit)
+	})
+}
+
+// handle responds to a message from the computer. It returns false if the session was stopped while waiting for
+// the client, or if it's a replay that couldn't give the program input, in which case it has already finished.
+func (s *session) handle(msg lmc.Msg) bool {
+	switch msg.Status {
+	case lmc.NeedStep:
+		if reason := s.stopReason(true); reason != "" && !s.wait(reason, "") {
+			return false
+		}
+
+		s.computer.Step <- struct{}{}
+
+	case lmc.MicroStep:
+		if reason := s.stopReason(false); reason != "" && !s.wait(reason, "after "+msg.Val) {
+			return false
+		}
+
+		s.computer.Step <- struct{}{}
+
+	case lmc.NeedInput:
+		var val int
+
+		if s.replayer != nil {
+			val, err := s.replayer.Input(msg.Val == lmc.InputChar)
+			if err != nil {
+				s.computer.Halt()
+				s.finish(err)
+				return false
+			}
+
+			s.computer.Inbox <- val
+			break
+		}
+
+		select {
+		case val = <-s.inputs:
+		default:
+			s.server.output("console", "INP: enter a number in the debug console\n")
+
+			select {
+			case val = <-s.inputs:
+			case <-s.quit:
+				return false
+			}
+		}
+
+		s.computer.Inbox <- val
+
+	case lmc.Output:
+		s.server.output("stdout", msg.Val+"\n")
+
+	case lmc.OutputChar:
+		n, _ := strconv.Atoi(msg.Val)
+		s.server.output("stdout", string(rune(n)))
+	}
+
+	return true
+}
+
+// wait tells the client the program has stopped and waits for it to be resumed. It returns false if the session was
+// stopped instead.
+func (s *session) wait(reason, description string) bool {
+	body := map[string]interface{}{
+		"reason":            reason,
+		"threadId":          1,
+		"allThreadsStopped": true,
+	}
+
+	if description != "" {
+		body["description"] = description
+	}
+
+	s.server.event("stopped", body)
+
+	select {
+	case <-s.resume:
+		return true
+	case <-s.quit:
+		return false
+	}
+}
+
+// stopReason decides whether to stop before the instruction at the program counter, or between phases of it if
+// atInstruction is false, returning the reason to give to the client or the empty string if the program should keep
+// running. Breakpoints only stop the program before an instruction.
+func (s *session) stopReason(atInstruction bool) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reason := ""
+	line, hasLine := s.program.sourceMap.Line(s.computer.ProgramCounter)
+
+	switch {
+	case s.entry:
+		reason = "entry"
+	case s.pausing:
+		reason = "pause"
+	case s.stepping:
+		reason = "step"
+	case atInstruction && hasLine && s.breakpoints[line]:
+		reason = "breakpoint"
+	}
+
+	if reason != "" {
+		s.entry, s.pausing, s.stepping = false, false, false
+		s.stopped = true
+	}
+
+	return reason
+}
+
+// finish tells the client that the program has finished running. A replay that diverged from its recording finishes
+// as if the program had stopped with an error.
+func (s *session) finish(err error) {
+	if s.replayer != nil {
+		if diverged := s.replayer.Finish(err); diverged != nil {
+			s.server.output("stderr", fmt.Sprintf("Error replaying session: %s\n", diverged))
+			s.server.event("exited", map[string]int{"exitCode": 1})
+			s.server.event("terminated", nil)
+			return
+		}
+	}
+
+	switch err.(type) {
+	case nil:
+		s.server.event("exited", map[string]int{"exitCode": 0})
+	case lmc.ErrHalted:
+	default:
+		s.server.output("stderr", fmt.Sprintf("Error running computer: %s\n", err))
+		s.server.event("exited", map[string]int{"exitCode": 1})
+	}
+
+	s.server.event("terminated", nil)
+}
+
+// setBreakpoints replaces the breakpoints with those on the one-indexed lines given, returning whether each could be
+// verified.
+func (s *session) setBreakpoints(lines []int) []Breakpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.breakpoints = make(map[int]bool)
+	breakpoints := []Breakpoint{}
+
+	for _, line := range lines {
+		bp := Breakpoint{Verified: true, Line: line}
+
+		if len(s.program.sourceMap.Addresses(line-1)) == 0 {
+			bp.Verified = false
+			bp.Message = "no instruction on this line"
+		} else {
+			s.breakpoints[line-1] = true
+		}
+
+		breakpoints = append(breakpoints, bp)
+	}
+
+	return breakpoints
+}
+
+// cont resumes a stopped program, either until the next instruction or until something else stops it.
+func (s *session) cont(step bool) {
+	s.mu.Lock()
+	s.stepping = step
+	wasStopped := s.stopped
+	s.stopped = false
+	s.mu.Unlock()
+
+	if wasStopped {
+		select {
+		case s.resume <- struct{}{}:
+		case <-s.quit:
+		}
+	}
+}
+
+// pause asks the program to stop before the next instruction.
+func (s *session) pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.stopped {
+		s.pausing = true
+	}
+}
+
+// input queues a value for the next INP instruction.
+func (s *session) input(val int) error {
+	select {
+	case s.inputs <- val:
+		return nil
+	default:
+		return fmt.Errorf("too many inputs queued")
+	}
+}
+
+// whileStopped calls f if the program is stopped, so that it can safely inspect the computer.
+func (s *session) whileStopped(f func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.stopped {
+		return fmt.Errorf("program is running")
+	}
+
+	f()
+	return nil
+}
+
+// stackTrace returns the single frame of the program, positioned at the instruction about to be executed.
+func (s *session) stackTrace() []StackFrame {
+	frames := []StackFrame{}
+
+	s.whileStopped(func() {
+		pc := s.computer.ProgramCounter
+		frame := StackFrame{ID: 1, Name: fmt.Sprintf("mailbox %d", pc), Column: 1}
+
+		if line, ok := s.program.sourceMap.Line(pc); ok {
+			frame.Source = &s.program.source
+			frame.Line = line + 1
+			frame.Name = strings.TrimSpace(s.program.lines[line])
+		}
+
+		frames = append(frames, frame)
+	})
+
+	return frames
+}
+
+// variables returns the registers or the mailboxes of the computer.
+func (s *session) variables(ref int) []Variable {
+	vars := []Variable{}
+
+	s.whileStopped(func() {
+		c := s.computer
+
+		switch ref {
+		case registersReference:
+			vars = append(vars,
+				Variable{Name: "Accumulator", Value: fmt.Sprint(c.Accumulator)},
+				Variable{
+					Name:            "Program Counter",
+					Value:           fmt.Sprint(c.ProgramCounter),
+					MemoryReference: fmt.Sprint(c.ProgramCounter * s.mailboxBytes()),
+				},
+				Variable{Name: "MAR", Value: fmt.Sprint(c.MAR)},
+				Variable{Name: "MDR", Value: fmt.Sprint(c.MDR)},
+				Variable{Name: "CIR", Value: fmt.Sprint(c.CIR)},
+			)
+
+		case mailboxesReference:
+			for addr := 0; addr < s.addressable(); addr++ {
+				val, _ := c.Mailboxes.Peek(addr)
+				name := fmt.Sprintf("%0*d", c.OperandSize, addr)
+
+				if instruction, ok := s.program.sourceMap[addr]; ok && instruction.Label != "" {
+					name += " " + instruction.Label
+				}
+
+				vars = append(vars, Variable{Name: name, Value: val})
+			}
+		}
+	})
+
+	return vars
+}
+
+// evaluate looks up a register or a label. Numbers are queued as input instead, so that values for INP
+// instructions can be typed into the debug console.
+func (s *session) evaluate(expr string) (string, error) {
+	expr = strings.TrimSpace(expr)
+
+	var val int
+	if _, err := fmt.Sscanf(expr, "%d", &val); err == nil && fmt.Sprint(val) == strings.TrimPrefix(expr, "+") {
+		if err := s.input(val); err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("input %d queued", val), nil
+	}
+
+	result := ""
+	err := s.whileStopped(func() {
+		switch strings.ToLower(expr) {
+		case "acc", "accumulator":
+			result = fmt.Sprint(s.computer.Accumulator)
+			return
+		case "pc", "program counter":
+			result = fmt.Sprint(s.computer.ProgramCounter)
+			return
+		case "mar":
+			result = fmt.Sprint(s.computer.MAR)
+			return
+		case "mdr":
+			result = fmt.Sprint(s.computer.MDR)
+			return
+		case "cir":
+			result = fmt.Sprint(s.computer.CIR)
+			return
+		}
+
+		for addr, instruction := range s.program.sourceMap {
+			if instruction.Label == expr {
+				result, _ = s.computer.Mailboxes.Peek(addr)
+				return
+			}
+		}
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if result == "" {
+		return "", fmt.Errorf("unknown register or label: %s", expr)
+	}
+
+	return result, nil
+}
+
+// readMemory returns the mailboxes as bytes, starting at a byte address. Each mailbox is stored big-endian in as
+// many bytes as its largest value needs.
+func (s *session) readMemory(addr, count int) ([]byte, error) {
+	data := []byte{}
+	width := s.mailboxBytes()
+
+	err := s.whileStopped(func() {
+		for b := addr; b < addr+count && b/width < s.addressable(); b++ {
+			if b < 0 {
+				continue
+			}
+
+			val, _ := s.computer.Mailboxes.Peek(b / width)
+
+			var n int
+			fmt.Sscan(val, &n)
+
+			shift := uint(8 * (width - 1 - b%width))
+			data = append(data, byte(n>>shift))
+		}
+	})
+
+	return data, err
+}
+
+// addressable returns the number of mailboxes an operand can refer to.
+func (s *session) addressable() int {
+	n := 1
+	for i := 0; i < s.computer.OperandSize; i++ {
+		n *= 10
+	}
+
+	return n
+}
+
+// mailboxBytes returns the number of bytes used to show a single mailbox in a memory view.
+func (s *session) mailboxBytes() int {
+	largest := 1
+	for i := 0; i < s.computer.InstructionSize+s.computer.OperandSize; i++ {
+		largest *= 10
+	}
+
+	width := 1
+	for n := 256; n < largest; n *= 256 {
+		width++
+	}
+
+	return width
+}
@@ -0,0 +1,173 @@
+// Package device provides peripherals that can be mapped into the mailboxes of a Little Man Computer with
+// lmc.Mailboxes.Map, for exploring I/O beyond INP and OUT. Each device documents what its registers do, in order of
+// their offset from the mailbox it's mapped to.
+package device
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Timer counts the instructions the computer has executed since it was mapped or last reset. It has a single
+// register, which reads as the count and can be written to set it, such as to zero to restart it.
+//
+// If Interval is set, the timer raises an interrupt each time the count reaches a multiple of it.
+type Timer struct {
+	Interval int
+
+	mu      sync.Mutex
+	count   int
+	pending bool // Whether the count has reached a multiple of the interval since the last interrupt.
+}
+
+// NewTimer returns a timer starting from zero.
+func NewTimer() *Timer {
+	return &Timer{}
+}
+
+// Size returns the number of registers the timer has.
+func (t *Timer) Size() int {
+	return 1
+}
+
+// Reset sets the count back to zero and forgets any interrupt that hasn't been raised, keeping the interval.
+func (t *Timer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.count = 0
+	t.pending = false
+}
+
+// Tick counts an instruction.
+func (t *Timer) Tick() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.count++
+	if t.Interval > 0 && t.count%t.Interval == 0 {
+		t.pending = true
+	}
+}
+
+// Interrupting returns true once each time the count reaches a multiple of the interval.
+func (t *Timer) Interrupting() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending := t.pending
+	t.pending = false
+
+	return pending
+}
+
+// Read returns the count.
+func (t *Timer) Read(offset int) int {
+	return t.Peek(offset)
+}
+
+// Peek returns the count.
+func (t *Timer) Peek(offset int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.count
+}
+
+// Write sets the count.
+func (t *Timer) Write(offset, val int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.count = val
+}
+
+// Random produces pseudo-random numbers from a seed, so a program using it does the same thing every time it's run
+// with the same seed, and again once reset. Register 0 reads as the next number, from zero up to but not including
+// the maximum, and writing to it reseeds the generator. Register 1 is the maximum, and can be written to change it.
+type Random struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+	max  int
+	next int // The number register 0 will read as next, so that it can be peeked.
+
+	initialSeed int64 // The seed and maximum the source was created with, restored by Reset.
+	initialMax  int
+}
+
+// NewRandom returns a random number source with a seed and maximum.
+func NewRandom(seed int64, max int) *Random {
+	r := &Random{max: max, initialSeed: seed, initialMax: max}
+	r.seed(seed)
+
+	return r
+}
+
+// Size returns the number of registers the random number source has.
+func (r *Random) Size() int {
+	return 2
+}
+
+// Reset restores the seed and maximum the source was created with, so it produces the same numbers again.
+func (r *Random) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.max = r.initialMax
+	r.seed(r.initialSeed)
+}
+
+// seed restarts the generator from a seed.
+func (r *Random) seed(seed int64) {
+	r.rand = rand.New(rand.NewSource(seed))
+	r.generate()
+}
+
+// generate picks the next number.
+func (r *Random) generate() {
+	r.next = 0
+	if r.max > 0 {
+		r.next = r.rand.Intn(r.max)
+	}
+}
+
+// Read returns the next number or the maximum, moving on to another number if it was the former.
+func (r *Random) Read(offset int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if offset == 1 {
+		return r.max
+	}
+
+	next := r.next
+	r.generate()
+
+	return next
+}
+
+// Peek returns the next number or the maximum.
+func (r *Random) Peek(offset int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if offset == 1 {
+		return r.max
+	}
+
+	return r.next
+}
+
+// Write reseeds the generator or sets the maximum.
+func (r *Random) Write(offset, val int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if offset == 1 {
+		r.max = val
+		r.generate()
+		return
+	}
+
+	r.seed(int64(val))
+}
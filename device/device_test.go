@@ -0,0 +1,129 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+// Every device can be mapped into mailboxes and reset, and the timer is told about instructions.
+var (
+	_ lmc.Device   = (*Timer)(nil)
+	_ lmc.Ticker   = (*Timer)(nil)
+	_ lmc.Resetter = (*Timer)(nil)
+	_ lmc.Device   = (*Random)(nil)
+	_ lmc.Resetter = (*Random)(nil)
+	_ lmc.Device   = (*Display)(nil)
+	_ lmc.Resetter = (*Display)(nil)
+	_ lmc.Device   = (*Keyboard)(nil)
+	_ lmc.Resetter = (*Keyboard)(nil)
+)
+
+func TestTimer(t *testing.T) {
+	timer := NewTimer()
+	for i := 0; i < 3; i++ {
+		timer.Tick()
+	}
+
+	assert.Equal(t, 3, timer.Read(0))
+
+	timer.Write(0, 0)
+	timer.Tick()
+	assert.Equal(t, 1, timer.Read(0), "expect writing to restart the count")
+	assert.False(t, timer.Interrupting(), "expect no interrupts without an interval")
+
+	timer.Interval = 2
+	timer.Tick()
+	assert.True(t, timer.Interrupting(), "expect an interrupt once the interval has passed")
+	assert.False(t, timer.Interrupting(), "expect each interrupt to be raised once")
+
+	timer.Tick()
+	timer.Tick()
+	timer.Reset()
+	assert.Equal(t, 0, timer.Read(0), "expect resetting to restart the count")
+	assert.False(t, timer.Interrupting(), "expect resetting to forget pending interrupts")
+}
+
+func TestRandom(t *testing.T) {
+	a, b := NewRandom(42, 10), NewRandom(42, 10)
+
+	first := []int{}
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, a.Peek(0), a.Read(0), "expect peek to give the number read next")
+		first = append(first, b.Read(0))
+	}
+
+	for _, n := range first {
+		assert.True(t, n >= 0 && n < 10, "expect %d to be below the maximum", n)
+	}
+
+	b.Write(0, 42)
+	for i, n := range first {
+		assert.Equal(t, n, b.Read(0), "expect reseeding to repeat number %d", i)
+	}
+
+	b.Write(1, 1)
+	assert.Equal(t, 1, b.Read(1))
+	assert.Equal(t, 0, b.Read(0), "expect the maximum to be changed")
+
+	b.Reset()
+	assert.Equal(t, 10, b.Read(1), "expect resetting to restore the maximum")
+	for i, n := range first {
+		assert.Equal(t, n, b.Read(0), "expect resetting to repeat number %d", i)
+	}
+}
+
+func TestDisplay(t *testing.T) {
+	display := NewDisplay()
+
+	display.Write(DisplayColumn, 8)
+	for _, ch := range "Hi!" {
+		display.Write(DisplayChar, int(ch))
+	}
+
+	assert.Equal(t, 1, display.Read(DisplayColumn), "expect the cursor to wrap onto the next row")
+	assert.Equal(t, 1, display.Read(DisplayRow))
+
+	display.Write(DisplayColumn, -1)
+	assert.Equal(t, 9, display.Read(DisplayColumn), "expect positions to wrap")
+	display.Write(DisplayRow, 0)
+	assert.Equal(t, int('i'), display.Read(DisplayChar))
+
+	assert.Equal(t, "+----------+\n|        Hi|\n|!         |\n"+
+		"|          |\n|          |\n|          |\n|          |\n|          |\n|          |\n|          |\n|          |\n"+
+		"+----------+\n", display.String())
+
+	display.Write(DisplayClear, 1)
+	assert.Equal(t, 0, display.Read(DisplayChar), "expect clearing to blank the display")
+	assert.Equal(t, 0, display.Read(DisplayColumn), "expect clearing to move the cursor home")
+
+	display.Write(DisplayChar, int('x'))
+	display.Reset()
+	assert.Equal(t, NewDisplay().String(), display.String(), "expect resetting to blank the display")
+	assert.Equal(t, 0, display.Read(DisplayColumn), "expect resetting to move the cursor home")
+}
+
+func TestKeyboard(t *testing.T) {
+	keyboard := NewKeyboard()
+	keyboard.Press("ab")
+
+	assert.Equal(t, 2, keyboard.Read(KeyboardCount))
+	assert.Equal(t, int('a'), keyboard.Peek(KeyboardKey))
+	assert.Equal(t, int('a'), keyboard.Read(KeyboardKey))
+	assert.Equal(t, 1, keyboard.Read(KeyboardCount), "expect reading a key to take it out of the buffer")
+	assert.Equal(t, int('b'), keyboard.Read(KeyboardKey))
+	assert.Equal(t, 0, keyboard.Read(KeyboardKey), "expect an empty buffer to read as zero")
+
+	keyboard.Press("cd")
+	keyboard.Write(KeyboardCount, 0)
+	assert.Equal(t, 0, keyboard.Read(KeyboardCount), "expect writing to empty the buffer")
+
+	assert.True(t, keyboard.Interrupting(), "expect pressing keys to raise an interrupt")
+	assert.False(t, keyboard.Interrupting(), "expect each interrupt to be raised once")
+
+	keyboard.Press("ef")
+	keyboard.Reset()
+	assert.Equal(t, 0, keyboard.Read(KeyboardCount), "expect resetting to empty the buffer")
+	assert.False(t, keyboard.Interrupting(), "expect resetting to forget pressed keys")
+}
@@ -0,0 +1,118 @@
+package device
+
+import (
+	"strings"
+	"sync"
+)
+
+// DisplaySize is the number of rows and columns of a display.
+const DisplaySize = 10
+
+// Display registers, by offset.
+const (
+	DisplayColumn = iota // Column of the cursor.
+	DisplayRow           // Row of the cursor.
+	DisplayChar          // Character under the cursor.
+	DisplayClear         // Clears the display when written to.
+)
+
+// Display is a 10x10 grid of characters drawn by a program. Registers 0 and 1 are the column and row of the cursor,
+// counting from the top left. Writing a character code to register 2 draws it under the cursor and moves the cursor
+// along, onto the next row at the end of one; reading it gives the character under the cursor. Writing anything to
+// register 3 clears the display and moves the cursor back to the top left.
+type Display struct {
+	mu       sync.Mutex
+	cells    [DisplaySize][DisplaySize]int
+	col, row int
+}
+
+// NewDisplay returns a blank display.
+func NewDisplay() *Display {
+	return &Display{}
+}
+
+// Size returns the number of registers the display has.
+func (d *Display) Size() int {
+	return 4
+}
+
+// Reset clears the display and moves the cursor back to the top left.
+func (d *Display) Reset() {
+	d.Write(DisplayClear, 0)
+}
+
+// Read returns the value of a register.
+func (d *Display) Read(offset int) int {
+	return d.Peek(offset)
+}
+
+// Peek returns the value of a register.
+func (d *Display) Peek(offset int) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch offset {
+	case DisplayColumn:
+		return d.col
+	case DisplayRow:
+		return d.row
+	case DisplayChar:
+		return d.cells[d.row][d.col]
+	}
+
+	return 0
+}
+
+// Write sets the value of a register. Positions outside of the display wrap around.
+func (d *Display) Write(offset, val int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch offset {
+	case DisplayColumn:
+		d.col = wrap(val)
+	case DisplayRow:
+		d.row = wrap(val)
+	case DisplayChar:
+		d.cells[d.row][d.col] = val
+
+		d.col++
+		if d.col == DisplaySize {
+			d.col = 0
+			d.row = wrap(d.row + 1)
+		}
+	case DisplayClear:
+		d.cells = [DisplaySize][DisplaySize]int{}
+		d.col, d.row = 0, 0
+	}
+}
+
+// wrap wraps a position onto the display.
+func wrap(n int) int {
+	return ((n % DisplaySize) + DisplaySize) % DisplaySize
+}
+
+// String returns the contents of the display inside a border. Cells that have nothing printable in them are blank.
+func (d *Display) String() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var b strings.Builder
+	border := "+" + strings.Repeat("-", DisplaySize) + "+\n"
+
+	b.WriteString(border)
+	for _, row := range d.cells {
+		b.WriteString("|")
+		for _, cell := range row {
+			if cell < ' ' || cell > '~' {
+				cell = ' '
+			}
+
+			b.WriteRune(rune(cell))
+		}
+		b.WriteString("|\n")
+	}
+	b.WriteString(border)
+
+	return b.String()
+}
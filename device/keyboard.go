@@ -0,0 +1,103 @@
+package device
+
+import "sync"
+
+// Keyboard registers, by offset.
+const (
+	KeyboardCount = iota // Number of keys waiting to be read.
+	KeyboardKey          // Next key to be read.
+)
+
+// Keyboard is a buffer of keys that have been pressed but not yet read by the program. Register 0 reads as the number
+// of keys in the buffer, and register 1 reads as the code of the next key, taking it out of the buffer, or zero if
+// the buffer is empty. Writing to either register empties the buffer.
+//
+// The keyboard raises an interrupt whenever keys are pressed.
+type Keyboard struct {
+	mu      sync.Mutex
+	keys    []rune
+	pressed bool // Whether keys have been pressed since the last interrupt.
+}
+
+// NewKeyboard returns a keyboard with nothing in its buffer.
+func NewKeyboard() *Keyboard {
+	return &Keyboard{}
+}
+
+// Size returns the number of registers the keyboard has.
+func (k *Keyboard) Size() int {
+	return 2
+}
+
+// Press adds keys to the end of the buffer. It's safe to call while the program is running.
+func (k *Keyboard) Press(keys string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys = append(k.keys, []rune(keys)...)
+	k.pressed = k.pressed || keys != ""
+}
+
+// Reset empties the buffer and forgets any keys pressed since the last interrupt.
+func (k *Keyboard) Reset() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys = nil
+	k.pressed = false
+}
+
+// Interrupting returns true if keys have been pressed since it was last asked.
+func (k *Keyboard) Interrupting() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	pressed := k.pressed
+	k.pressed = false
+
+	return pressed
+}
+
+// Read returns the number of keys waiting or takes the next key out of the buffer.
+func (k *Keyboard) Read(offset int) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if offset == KeyboardCount || len(k.keys) == 0 {
+		return k.peek(offset)
+	}
+
+	key := k.keys[0]
+	k.keys = k.keys[1:]
+
+	return int(key)
+}
+
+// Peek returns the number of keys waiting or the next key.
+func (k *Keyboard) Peek(offset int) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.peek(offset)
+}
+
+// peek is Peek without taking the lock.
+func (k *Keyboard) peek(offset int) int {
+	if offset == KeyboardCount {
+		return len(k.keys)
+	}
+
+	if len(k.keys) == 0 {
+		return 0
+	}
+
+	return int(k.keys[0])
+}
+
+// Write empties the buffer.
+func (k *Keyboard) Write(offset, val int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys = nil
+}
@@ -0,0 +1,115 @@
+package lmc
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Device is a peripheral whose registers are mapped into a range of mailboxes, so that programs can use it by loading
+// from and storing to those mailboxes. Offsets are relative to the first mailbox the device is mapped to.
+type Device interface {
+	// Size returns the number of mailboxes the device needs.
+	Size() int
+
+	// Read returns the value of a register when a program reads it. Reading can change the device, such as taking a
+	// key out of a buffer.
+	Read(offset int) int
+
+	// Write sets the value of a register when a program writes to it.
+	Write(offset, val int)
+
+	// Peek returns the value of a register without changing the device, for showing in debuggers and the like.
+	Peek(offset int) int
+}
+
+// Ticker is a device that is told about every instruction the computer executes, such as a timer.
+type Ticker interface {
+	Tick()
+}
+
+// Resetter is a device that keeps state between runs, such as a buffer of keys, and can put it back as it was when
+// the device was created. Devices that are resetters are reset along with the computer they're mapped into.
+type Resetter interface {
+	Reset()
+}
+
+// mapping is a device mapped into a range of mailboxes.
+type mapping struct {
+	addr   int
+	device Device
+}
+
+// Map maps a device into the mailboxes starting at addr, so that getting and setting those mailboxes reads and writes
+// the device's registers rather than memory. It returns ErrDeviceMapping if the device doesn't fit or overlaps a
+// device that's already mapped.
+func (m *Mailboxes) Map(addr int, device Device) error {
+	end := addr + device.Size()
+	if addr < 0 || device.Size() < 1 || end > len(m.mem) {
+		return ErrDeviceMapping{addr, device.Size(), "outside of memory"}
+	}
+
+	for _, other := range m.devices {
+		if addr < other.addr+other.device.Size() && other.addr < end {
+			return ErrDeviceMapping{addr, device.Size(), "overlaps device at " + strconv.Itoa(other.addr)}
+		}
+	}
+
+	m.devices = append(m.devices, mapping{addr, device})
+	sort.Slice(m.devices, func(i, j int) bool {
+		return m.devices[i].addr < m.devices[j].addr
+	})
+
+	return nil
+}
+
+// Device returns the device mapped to a mailbox and the offset of the mailbox within it.
+func (m *Mailboxes) Device(n int) (Device, int, bool) {
+	for _, mapped := range m.devices {
+		if n >= mapped.addr && n < mapped.addr+mapped.device.Size() {
+			return mapped.device, n - mapped.addr, true
+		}
+	}
+
+	return nil, 0, false
+}
+
+// Peek is like Get, but reads device registers without changing the device.
+func (m *Mailboxes) Peek(n int) (string, error) {
+	if device, offset, ok := m.Device(n); ok {
+		return m.format(device.Peek(offset)), nil
+	}
+
+	return m.Get(n)
+}
+
+// tick tells every device that wants to know that an instruction is about to be executed.
+func (m *Mailboxes) tick() {
+	for _, mapped := range m.devices {
+		if ticker, ok := mapped.device.(Ticker); ok {
+			ticker.Tick()
+		}
+	}
+}
+
+// reset resets every device that can be.
+func (m *Mailboxes) reset() {
+	for _, mapped := range m.devices {
+		if resetter, ok := mapped.device.(Resetter); ok {
+			resetter.Reset()
+		}
+	}
+}
+
+// interrupting returns true if any device wants to raise an interrupt. Every device is asked, even once one has said
+// yes.
+func (m *Mailboxes) interrupting() bool {
+	interrupting := false
+
+	for _, mapped := range m.devices {
+		if interrupter, ok := mapped.device.(Interrupter); ok && interrupter.Interrupting() {
+			interrupting = true
+		}
+	}
+
+	return interrupting
+}
@@ -0,0 +1,87 @@
+package lmc_test
+
+import (
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+// counter is a device with one register that counts reads, writes and instructions.
+type counter struct {
+	reads, writes, ticks int
+	last                 int
+}
+
+func (c *counter) Size() int             { return 1 }
+func (c *counter) Read(offset int) int   { c.reads++; return c.reads }
+func (c *counter) Peek(offset int) int   { return c.reads }
+func (c *counter) Write(offset, val int) { c.writes++; c.last = val }
+func (c *counter) Tick()                 { c.ticks++ }
+func (c *counter) Reset()                { *c = counter{} }
+
+func TestMailboxesMap(t *testing.T) {
+	mailboxes := lmc.NewMailboxes(1, 2)
+
+	assert.NoError(t, mailboxes.Map(90, &counter{}))
+	assert.NoError(t, mailboxes.Map(91, &counter{}))
+
+	assert.Equal(t, lmc.ErrDeviceMapping{Addr: 90, Size: 1, Reason: "overlaps device at 90"}, mailboxes.Map(90, &counter{}))
+	assert.Equal(t, lmc.ErrDeviceMapping{Addr: 1000, Size: 1, Reason: "outside of memory"}, mailboxes.Map(1000, &counter{}))
+	assert.Equal(t, lmc.ErrDeviceMapping{Addr: -1, Size: 1, Reason: "outside of memory"}, mailboxes.Map(-1, &counter{}))
+}
+
+func TestMailboxesDevice(t *testing.T) {
+	mailboxes := lmc.NewMailboxes(1, 2)
+	device := &counter{}
+	assert.NoError(t, mailboxes.Map(50, device))
+
+	val, err := mailboxes.Peek(50)
+	assert.NoError(t, err)
+	assert.Equal(t, "000", val, "expect peeking not to read the device")
+
+	val, err = mailboxes.Get(50)
+	assert.NoError(t, err)
+	assert.Equal(t, "001", val, "expect getting to read the device")
+
+	assert.NoError(t, mailboxes.Set(50, "042"))
+	assert.Equal(t, 42, device.last, "expect setting to write to the device")
+
+	assert.Error(t, mailboxes.Set(50, "abc"), "expect non-numbers not to be written to devices")
+
+	val, _ = mailboxes.Get(49)
+	assert.Equal(t, "000", val, "expect other mailboxes to be memory")
+}
+
+func TestComputerDevice(t *testing.T) {
+	computer, err := lmc.NewComputerFromCode("LDA 90\nOUT\nSTA 90\nLDA 90\nOUT\nHLT", 1, 2)
+	assert.NoError(t, err)
+
+	device := &counter{}
+	assert.NoError(t, computer.Mailboxes.Map(90, device))
+
+	result, err := computer.RunWithInputs(nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, result.Outputs, "expect loads to read the device")
+	assert.Equal(t, 1, device.writes, "expect stores to write to the device")
+	assert.Equal(t, 6, device.ticks, "expect the device to be told about every instruction")
+}
+
+func TestComputerResetDevice(t *testing.T) {
+	computer, err := lmc.NewComputerFromCode("LDA 90\nOUT\nHLT", 1, 2)
+	assert.NoError(t, err)
+
+	device := &counter{}
+	assert.NoError(t, computer.Mailboxes.Map(90, device))
+
+	result, err := computer.RunWithInputs(nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, result.Outputs)
+
+	computer.Reset()
+	assert.Equal(t, counter{}, *device, "expect resetting the computer to reset the device")
+
+	result, err = computer.RunWithInputs(nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, result.Outputs, "expect the device to read as it did the first time")
+}
@@ -0,0 +1,71 @@
+package lmc
+
+import "strconv"
+
+// Disassemble converts the contents of mailboxes back into a list of instructions, one per mailbox, up to the last
+// mailbox that isn't zero. Operands are written as addresses, since labels aren't kept in the mailboxes, and any value
+// that isn't a valid instruction becomes a DAT. Numbers are written in decimal, whatever the radix of the mailboxes.
+// Assembling the result gives back the same mailboxes, as long as every mailbox holds a number that fits.
+func Disassemble(mailboxes *Mailboxes, opcodeSize, operandSize int) []Instruction {
+	return DefaultInstructionSet.Disassemble(mailboxes, opcodeSize, operandSize)
+}
+
+// Disassemble is like the Disassemble function, but gives instructions from this instruction set. Values that aren't
+// valid instructions use the first mnemonic with a data operand.
+func (s *InstructionSet) Disassemble(mailboxes *Mailboxes, opcodeSize, operandSize int) []Instruction {
+	instructions := []Instruction{}
+	last := -1
+
+	for i := range mailboxes.mem {
+		instruction := s.disassembleValue(mailboxes.mem[i], mailboxes.radix, opcodeSize, operandSize)
+		instructions = append(instructions, instruction)
+
+		if n, err := mailboxes.parse(mailboxes.mem[i]); err != nil || n != 0 {
+			last = i
+		}
+	}
+
+	return instructions[:last+1]
+}
+
+// disassembleValue converts the contents of a single mailbox into an instruction.
+func (s *InstructionSet) disassembleValue(val string, radix, opcodeSize, operandSize int) Instruction {
+	data := Instruction{Mnemonic: "DAT", Operand: val, Opcode: -1}
+	for _, def := range s.Instructions {
+		if def.Kind == OperandData {
+			data.Mnemonic = def.Mnemonic
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(val, radix, 64)
+	if err != nil {
+		return data
+	}
+
+	data.Operand = strconv.FormatInt(n, 10)
+	if n < 0 || len(val) > opcodeSize+operandSize {
+		return data
+	}
+
+	scale := int64(pow(radix, operandSize))
+	def, ok := s.Decode(int(n/scale), int(n%scale))
+	if !ok {
+		return data
+	}
+
+	instruction := Instruction{Mnemonic: def.Mnemonic, Opcode: def.Opcode}
+
+	switch def.Kind {
+	case OperandAddress:
+		instruction.Operand = strconv.FormatInt(n%scale, 10)
+	case OperandFixed:
+		instruction.Operand = strconv.Itoa(def.Operand)
+	case OperandNone:
+		if def.Operand != 0 {
+			instruction.Operand = strconv.Itoa(def.Operand)
+		}
+	}
+
+	return instruction
+}
@@ -0,0 +1,40 @@
+package lmc_test
+
+import (
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisassemble(t *testing.T) {
+	tests := []struct {
+		input  []string
+		output string
+	}{
+		{
+			[]string{"901", "310", "902", "000"},
+			"        INP\n        STA 10\n        OUT\n",
+		},
+		{
+			[]string{"110", "000", "005"},
+			"        ADD 10\n        HLT\n        DAT 5\n",
+		},
+		{
+			[]string{"400", "903", "099", "600"},
+			"        DAT 400\n        DAT 903\n        DAT 99\n        BRA 0\n",
+		},
+		{
+			[]string{"000"},
+			"",
+		},
+	}
+
+	for _, tc := range tests {
+		mailboxes := lmc.NewInitialisedMailboxes(1, 2, tc.input)
+		instructions := lmc.Disassemble(mailboxes, 1, 2)
+
+		assert.Equal(t, tc.output, lmc.Format(instructions), "expect disassembly to be correct")
+		assert.Equal(t, mailboxes, lmc.Assemble(instructions, 1, 2), "expect disassembly to assemble to the input")
+	}
+}
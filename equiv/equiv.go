@@ -0,0 +1,251 @@
+// Package equiv checks whether two programs behave the same way, by running both on the same inputs and comparing
+// what they read and output.
+package equiv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// Options controls which inputs the programs are compared on.
+type Options struct {
+	OpcodeSize  int
+	OperandSize int
+
+	Min, Max  int // Range of each input, inclusive.
+	MaxInputs int // Longest sequence of inputs to try.
+	MaxCycles int // Cycle budget for each run. Zero means no limit.
+
+	// Random, if greater than zero, compares the programs on that many random sequences of MaxInputs inputs instead of
+	// every sequence. Seed seeds the random number generator.
+	Random int
+	Seed   int64
+}
+
+// Event is something a program did that's visible from outside: reading an input or outputting a value.
+type Event struct {
+	Input bool // Whether the event is an input rather than an output.
+	Value int
+}
+
+// String returns the event as in 5 or out 25.
+func (e Event) String() string {
+	if e.Input {
+		return fmt.Sprintf("in  %d", e.Value)
+	}
+
+	return fmt.Sprintf("out %d", e.Value)
+}
+
+// Trace is everything a program did in a single run.
+type Trace struct {
+	Events []Event
+	Err    error // Why the program stopped, or nil if it halted.
+}
+
+// End describes how the program stopped.
+func (t Trace) End() string {
+	if t.Err == nil {
+		return "halt"
+	}
+
+	return t.Err.Error()
+}
+
+// same returns true if two traces show the same behaviour.
+func same(a, b Trace) bool {
+	if len(a.Events) != len(b.Events) || a.End() != b.End() {
+		return false
+	}
+
+	for i := range a.Events {
+		if a.Events[i] != b.Events[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Difference is an input sequence on which two programs behave differently.
+type Difference struct {
+	Inputs           []int
+	Reference, Other Trace
+}
+
+// Compare runs two programs on the same inputs and returns the first input sequence they behave differently on, or
+// nil if there isn't one. It also returns the number of input sequences tried, and an error if either program can't
+// be loaded.
+//
+// Unless random inputs are asked for, every sequence of inputs in the range is tried, shortest and smallest first.
+// Sequences are only extended while one of the programs asks for more input, so a program that reads a single
+// number is only run once for each number in the range.
+func Compare(reference, other []lmc.Instruction, opts Options) (*Difference, int, error) {
+	c := &comparer{opts: opts}
+
+	var err error
+	if c.reference, err = c.newMachine(reference); err != nil {
+		return nil, 0, err
+	}
+
+	if c.other, err = c.newMachine(other); err != nil {
+		return nil, 0, err
+	}
+
+	defer c.reference.Reset()
+	defer c.other.Reset()
+
+	if opts.Random > 0 {
+		d, tried := c.random()
+		return d, tried, nil
+	}
+
+	return c.explore([]int{}), c.tried, nil
+}
+
+// comparer holds the state of a comparison.
+type comparer struct {
+	reference, other lmc.Machine // Reset before each run, rather than created again.
+	opts             Options
+	tried            int
+
+	trace  *Trace // The trace of the current run.
+	inputs []int  // The inputs of the current run.
+	read   int    // Number of inputs read so far in the current run.
+}
+
+// newMachine returns a machine loaded with a program, which records what it does in the comparer's current trace.
+func (c *comparer) newMachine(instructions []lmc.Instruction) (lmc.Machine, error) {
+	m := lmc.NewMachine(
+		lmc.WithMemory(10, c.opts.OpcodeSize, c.opts.OperandSize),
+		lmc.WithMaxCycles(c.opts.MaxCycles),
+		lmc.WithObserver(c.observe),
+	)
+
+	return m, m.Load(lmc.Format(instructions))
+}
+
+// explore compares the programs on the inputs given, then on every extension of them if either program wanted more.
+func (c *comparer) explore(inputs []int) *Difference {
+	ref, other := c.run(c.reference, inputs), c.run(c.other, inputs)
+	c.tried++
+
+	if len(inputs) < c.opts.MaxInputs && (exhausted(ref.Err) || exhausted(other.Err)) {
+		for v := c.opts.Min; v <= c.opts.Max; v++ {
+			if d := c.explore(append(inputs[:len(inputs):len(inputs)], v)); d != nil {
+				return d
+			}
+		}
+
+		return nil
+	}
+
+	if !same(ref, other) {
+		return &Difference{inputs, ref, other}
+	}
+
+	return nil
+}
+
+// random compares the programs on random sequences of inputs.
+func (c *comparer) random() (*Difference, int) {
+	r := rand.New(rand.NewSource(c.opts.Seed))
+
+	for c.tried < c.opts.Random {
+		inputs := make([]int, c.opts.MaxInputs)
+		for i := range inputs {
+			inputs[i] = c.opts.Min + r.Intn(c.opts.Max-c.opts.Min+1)
+		}
+
+		ref, other := c.run(c.reference, inputs), c.run(c.other, inputs)
+		c.tried++
+
+		if !same(ref, other) {
+			return &Difference{inputs[:read(ref, other)], ref, other}, c.tried
+		}
+	}
+
+	return nil, c.tried
+}
+
+// read returns the number of inputs read by whichever program read more.
+func read(traces ...Trace) int {
+	most := 0
+
+	for _, t := range traces {
+		n := 0
+		for _, e := range t.Events {
+			if e.Input {
+				n++
+			}
+		}
+
+		if n > most {
+			most = n
+		}
+	}
+
+	return most
+}
+
+// run runs a program on the inputs given and records what it does.
+func (c *comparer) run(m lmc.Machine, inputs []int) Trace {
+	m.Reset()
+	m.SetInput(inputs...)
+
+	trace := Trace{Events: []Event{}}
+	c.trace, c.inputs, c.read = &trace, inputs, 0
+
+	trace.Err = m.Run(context.Background())
+	return trace
+}
+
+// observe records what the program in the current run did.
+func (c *comparer) observe(msg lmc.Msg) {
+	switch msg.Status {
+	case lmc.NeedInput:
+		if c.read < len(c.inputs) {
+			c.trace.Events = append(c.trace.Events, Event{true, c.inputs[c.read]})
+			c.read++
+		}
+	case lmc.Output, lmc.OutputChar:
+		n, _ := strconv.Atoi(msg.Val)
+		c.trace.Events = append(c.trace.Events, Event{false, n})
+	}
+}
+
+// exhausted returns true if a program stopped because it wanted more input.
+func exhausted(err error) bool {
+	var e lmc.ErrInputExhausted
+	return errors.As(err, &e)
+}
+
+// ParseRange parses a range of inputs written like 0..99.
+func ParseRange(s string) (min, max int, err error) {
+	parts := strings.Split(s, "..")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid range %q, expecting something like 0..99", s)
+	}
+
+	min, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q: %s", s, err)
+	}
+
+	max, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q: %s", s, err)
+	}
+
+	if min > max {
+		return 0, 0, fmt.Errorf("invalid range %q, the start is after the end", s)
+	}
+
+	return min, max, nil
+}
@@ -0,0 +1,137 @@
+package equiv
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(t *testing.T, code string) []lmc.Instruction {
+	t.Helper()
+
+	instructions, err := lmc.NewParser(lmc.NewLexer(code)).Parse()
+	assert.NoError(t, err)
+
+	return instructions
+}
+
+// double outputs twice its input, using ADD.
+const double = `
+        INP
+        STA x
+        ADD x
+        OUT
+        HLT
+x       DAT
+`
+
+// doubleBuggy outputs twice its input, except that it outputs 0 for 7.
+const doubleBuggy = `
+        INP
+        STA x
+        SUB seven
+        BRZ zero
+        LDA x
+        ADD x
+        OUT
+        HLT
+zero    OUT
+        HLT
+x       DAT
+seven   DAT 7
+`
+
+func TestCompareEquivalent(t *testing.T) {
+	square, err := ioutil.ReadFile("../examples/square.lmc")
+	assert.NoError(t, err)
+
+	optimised := lmc.Optimise(parse(t, string(square)))
+
+	d, tried, err := Compare(parse(t, string(square)), optimised, Options{OpcodeSize: 1, OperandSize: 2, Min: 0, Max: 20, MaxInputs: 3})
+	assert.NoError(t, err)
+	assert.Nil(t, d)
+	assert.Equal(t, 22, tried)
+}
+
+func TestCompareDifferent(t *testing.T) {
+	opts := Options{OpcodeSize: 1, OperandSize: 2, Min: 0, Max: 99, MaxInputs: 3}
+
+	d, tried, err := Compare(parse(t, double), parse(t, doubleBuggy), opts)
+	assert.NoError(t, err)
+	if !assert.NotNil(t, d) {
+		return
+	}
+
+	assert.Equal(t, []int{7}, d.Inputs)
+	assert.Equal(t, 9, tried)
+	assert.Equal(t, []Event{{true, 7}, {false, 14}}, d.Reference.Events)
+	assert.Equal(t, []Event{{true, 7}, {false, 0}}, d.Other.Events)
+
+	var b bytes.Buffer
+	assert.NoError(t, d.WriteSideBySide(&b, "double.lmc", "buggy.lmc"))
+	assert.Equal(t, `inputs: 7
+
+double.lmc    buggy.lmc
+in  7         in  7
+out 14      ! out 0
+halt          halt
+`, b.String())
+}
+
+func TestCompareInputCount(t *testing.T) {
+	// Reads a second input that the reference doesn't.
+	greedy := `
+        INP
+        STA x
+        ADD x
+        OUT
+        INP
+        HLT
+x       DAT
+`
+	d, _, err := Compare(parse(t, double), parse(t, greedy), Options{OpcodeSize: 1, OperandSize: 2, Min: 0, Max: 9, MaxInputs: 2})
+	assert.NoError(t, err)
+	if !assert.NotNil(t, d) {
+		return
+	}
+
+	assert.Equal(t, []int{0, 0}, d.Inputs)
+	assert.Equal(t, []Event{{true, 0}, {false, 0}}, d.Reference.Events)
+	assert.Equal(t, []Event{{true, 0}, {false, 0}, {true, 0}}, d.Other.Events)
+}
+
+func TestCompareRandom(t *testing.T) {
+	opts := Options{OpcodeSize: 1, OperandSize: 2, Min: 0, Max: 9, MaxInputs: 1, Random: 200, Seed: 1}
+
+	d, tried, err := Compare(parse(t, double), parse(t, doubleBuggy), opts)
+	assert.NoError(t, err)
+	if assert.NotNil(t, d) {
+		assert.Equal(t, []int{7}, d.Inputs)
+		assert.True(t, tried <= 200)
+	}
+
+	d, tried, err = Compare(parse(t, double), parse(t, double), opts)
+	assert.NoError(t, err)
+	assert.Nil(t, d)
+	assert.Equal(t, 200, tried)
+}
+
+func TestCompareInvalid(t *testing.T) {
+	_, _, err := Compare(parse(t, "LDA 150"), parse(t, double), Options{OpcodeSize: 1, OperandSize: 2, MaxInputs: 1})
+	assert.Error(t, err, "expect a program that doesn't fit in the mailboxes not to load")
+}
+
+func TestParseRange(t *testing.T) {
+	min, max, err := ParseRange("0..99")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, min)
+	assert.Equal(t, 99, max)
+
+	for _, s := range []string{"0", "a..9", "9..0", "0..9..10"} {
+		_, _, err := ParseRange(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,71 @@
+package equiv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// gutter is the space between the two columns of a side by side trace.
+const gutter = 4
+
+// WriteSideBySide writes the inputs that the programs differ on, then what each program did in two columns. The first
+// line that differs is marked with a !.
+func (d *Difference) WriteSideBySide(w io.Writer, referenceName, otherName string) error {
+	bw := bufio.NewWriter(w)
+
+	inputs := make([]string, len(d.Inputs))
+	for i, n := range d.Inputs {
+		inputs[i] = fmt.Sprint(n)
+	}
+
+	fmt.Fprintf(bw, "inputs: %s\n\n", strings.Join(inputs, ", "))
+
+	left, right := lines(d.Reference), lines(d.Other)
+
+	width := len(referenceName)
+	for _, l := range left {
+		if len(l) > width {
+			width = len(l)
+		}
+	}
+
+	row := func(marker, l, r string) {
+		line := fmt.Sprintf("%-*s%s %s", width+gutter-2, l, marker, r)
+		fmt.Fprintln(bw, strings.TrimRight(line, " "))
+	}
+
+	row(" ", referenceName, otherName)
+
+	marked := false
+	for i := 0; i < len(left) || i < len(right); i++ {
+		var l, r string
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+
+		marker := " "
+		if l != r && !marked {
+			marker = "!"
+			marked = true
+		}
+
+		row(marker, l, r)
+	}
+
+	return bw.Flush()
+}
+
+// lines returns each event of a trace and how it ended as lines of text.
+func lines(t Trace) []string {
+	ls := []string{}
+	for _, e := range t.Events {
+		ls = append(ls, e.String())
+	}
+
+	return append(ls, t.End())
+}
@@ -1,6 +1,9 @@
 package lmc
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 // ErrArgumentLen is an error that occurs when more or less than the desired amount of arguments are
 // specified.
@@ -24,3 +27,294 @@ type ErrInvalidMemory struct {
 func (e ErrInvalidMemory) Error() string {
 	return fmt.Sprintf("invalid memory access; attempted to get mailbox %d", e.Attempted)
 }
+
+// ErrHalted is returned by Run when the computer is stopped with Halt before it reaches a HLT instruction.
+type ErrHalted struct{}
+
+// Error returns the error string for ErrHalted.
+func (e ErrHalted) Error() string {
+	return "computer was halted"
+}
+
+// ErrCycleLimit occurs when a program runs for more instructions than it is allowed to.
+type ErrCycleLimit struct {
+	Limit int
+}
+
+// Error returns the error string for ErrCycleLimit.
+func (e ErrCycleLimit) Error() string {
+	return fmt.Sprintf("program did not halt within %d cycles", e.Limit)
+}
+
+// ErrInputExhausted occurs when a program asks for input but none is left.
+type ErrInputExhausted struct {
+	Outputs int // Number of values that had been output when the input ran out.
+}
+
+// Error returns the error string for ErrInputExhausted.
+func (e ErrInputExhausted) Error() string {
+	return fmt.Sprintf("program asked for more input after %d outputs", e.Outputs)
+}
+
+// ErrDeviceMapping occurs when a device can't be mapped into mailboxes.
+type ErrDeviceMapping struct {
+	Addr   int
+	Size   int
+	Reason string
+}
+
+// Error returns the error string for ErrDeviceMapping.
+func (e ErrDeviceMapping) Error() string {
+	return fmt.Sprintf("can't map device to mailboxes %d-%d: %s", e.Addr, e.Addr+e.Size-1, e.Reason)
+}
+
+// ErrNoInterrupt occurs when RTI is executed by a computer that doesn't have interrupts set up.
+type ErrNoInterrupt struct {
+	Addr int // Address of the RTI.
+}
+
+// Error returns the error string for ErrNoInterrupt.
+func (e ErrNoInterrupt) Error() string {
+	return fmt.Sprintf("return from interrupt at address %d without interrupts", e.Addr)
+}
+
+// ErrProcessor occurs when one of the processors in a multiprocessor stops with an error.
+type ErrProcessor struct {
+	Processor int
+	Err       error
+}
+
+// Error returns the error string for ErrProcessor.
+func (e ErrProcessor) Error() string {
+	return fmt.Sprintf("processor %d: %s", e.Processor, e.Err)
+}
+
+// Unwrap returns the error the processor stopped with.
+func (e ErrProcessor) Unwrap() error {
+	return e.Err
+}
+
+// ErrRunLimit occurs when exploring the interleavings of a multiprocessor would take more runs than it is allowed to.
+type ErrRunLimit struct {
+	Limit int
+}
+
+// Error returns the error string for ErrRunLimit.
+func (e ErrRunLimit) Error() string {
+	return fmt.Sprintf("interleavings were not all explored within %d runs", e.Limit)
+}
+
+// ErrAccumulatorRange occurs when the accumulator holds a number that doesn't fit in a mailbox and the computer is
+// using ArithmeticChecked.
+type ErrAccumulatorRange struct {
+	Addr  int // Address of the instruction that set the accumulator.
+	Value int
+}
+
+// Error returns the error string for ErrAccumulatorRange.
+func (e ErrAccumulatorRange) Error() string {
+	return fmt.Sprintf("accumulator value %d set at address %d does not fit in a mailbox", e.Value, e.Addr)
+}
+
+// ErrNoProgram occurs when a machine is used before a program has been loaded into it.
+type ErrNoProgram struct{}
+
+// Error returns the error string for ErrNoProgram.
+func (e ErrNoProgram) Error() string {
+	return "no program has been loaded"
+}
+
+// ErrStackOverflow occurs when a value is pushed onto a stack that has filled all of memory.
+type ErrStackOverflow struct {
+	Addr int // Address of the instruction that pushed.
+}
+
+// Error returns the error string for ErrStackOverflow.
+func (e ErrStackOverflow) Error() string {
+	return fmt.Sprintf("stack overflow at address %d", e.Addr)
+}
+
+// ErrStackUnderflow occurs when a value is popped off an empty stack.
+type ErrStackUnderflow struct {
+	Addr int // Address of the instruction that popped.
+}
+
+// Error returns the error string for ErrStackUnderflow.
+func (e ErrStackUnderflow) Error() string {
+	return fmt.Sprintf("stack underflow at address %d", e.Addr)
+}
+
+// ErrDivisionByZero occurs when the accumulator is divided by a mailbox holding zero.
+type ErrDivisionByZero struct {
+	Addr int // Address of the instruction that divided.
+}
+
+// Error returns the error string for ErrDivisionByZero.
+func (e ErrDivisionByZero) Error() string {
+	return fmt.Sprintf("division by zero at address %d", e.Addr)
+}
+
+// ErrOpcodeRange occurs when the opcode of an instruction has more digits than a mailbox has for it.
+type ErrOpcodeRange struct {
+	Token  Token
+	Digits int
+}
+
+// Error returns the error string for ErrOpcodeRange.
+func (e ErrOpcodeRange) Error() string {
+	return fmt.Sprintf("opcode of %s does not fit in %d digits", e.Token.Literal, e.Digits)
+}
+
+// ErrMailboxSize occurs when a program is assembled for mailboxes that are too small or too large, or that use a radix
+// that isn't supported.
+type ErrMailboxSize struct {
+	Radix       int
+	OpcodeSize  int
+	OperandSize int
+}
+
+// Error returns the error string for ErrMailboxSize.
+func (e ErrMailboxSize) Error() string {
+	return fmt.Sprintf(
+		"mailboxes with a %d digit opcode and %d digit operand in radix %d are not supported; the radix must be "+
+			"between 2 and 36, both need at least 1 digit and there can be at most %d mailboxes",
+		e.OpcodeSize, e.OperandSize, e.Radix, MaxMailboxes,
+	)
+}
+
+// ErrFixedOperandRange occurs when an instruction whose operand is fixed by the instruction set, like INA, is used
+// with an operand size too small to hold it.
+type ErrFixedOperandRange struct {
+	Token   Token
+	Operand int
+	Digits  int
+}
+
+// Error returns the error string for ErrFixedOperandRange.
+func (e ErrFixedOperandRange) Error() string {
+	return fmt.Sprintf(
+		"%s has the fixed operand %d, which does not fit in %d digits", e.Token.Literal, e.Operand, e.Digits,
+	)
+}
+
+// ErrInvalidInstructionSet occurs when an instruction set defines an instruction that can't be used.
+type ErrInvalidInstructionSet struct {
+	Mnemonic string
+	Reason   string
+}
+
+// Error returns the error string for ErrInvalidInstructionSet.
+func (e ErrInvalidInstructionSet) Error() string {
+	return fmt.Sprintf("invalid instruction set; %s: %s", e.Mnemonic, e.Reason)
+}
+
+// ErrIllegalToken occurs when the lexer produces a token that is not valid anywhere in a program.
+type ErrIllegalToken struct {
+	Token Token
+}
+
+// Error returns the error string for ErrIllegalToken.
+func (e ErrIllegalToken) Error() string {
+	return fmt.Sprintf("illegal token %s in input", e.Token)
+}
+
+// ErrInvalidMnemonic occurs when an identifier is used as a mnemonic but isn't one.
+type ErrInvalidMnemonic struct {
+	Token Token
+}
+
+// Error returns the error string for ErrInvalidMnemonic.
+func (e ErrInvalidMnemonic) Error() string {
+	return fmt.Sprintf("invalid mnemonic: %s", e.Token.Literal)
+}
+
+// ErrUnexpectedToken occurs when a token appears somewhere the parser doesn't expect it.
+type ErrUnexpectedToken struct {
+	Token Token
+}
+
+// Error returns the error string for ErrUnexpectedToken.
+func (e ErrUnexpectedToken) Error() string {
+	return fmt.Sprintf("unexpected token in input: %s", e.Token)
+}
+
+// ErrUndefinedLabel occurs when an operand refers to a label that is never defined.
+type ErrUndefinedLabel struct {
+	Token Token
+}
+
+// Error returns the error string for ErrUndefinedLabel.
+func (e ErrUndefinedLabel) Error() string {
+	return fmt.Sprintf("undefined label: %s", e.Token.Literal)
+}
+
+// ErrDuplicateLabel occurs when the same label is defined more than once.
+type ErrDuplicateLabel struct {
+	Token Token
+}
+
+// Error returns the error string for ErrDuplicateLabel.
+func (e ErrDuplicateLabel) Error() string {
+	return fmt.Sprintf("label %s is already defined", e.Token.Literal)
+}
+
+// ErrOperandRange occurs when an operand doesn't fit in the space available for it in a mailbox.
+type ErrOperandRange struct {
+	Token  Token
+	Digits int
+}
+
+// Error returns the error string for ErrOperandRange.
+func (e ErrOperandRange) Error() string {
+	return fmt.Sprintf("operand %s does not fit in %d digits", e.Token.Literal, e.Digits)
+}
+
+// ErrProgramSize occurs when a program has more instructions than there are mailboxes.
+type ErrProgramSize struct {
+	Token     Token
+	Mailboxes int
+}
+
+// Error returns the error string for ErrProgramSize.
+func (e ErrProgramSize) Error() string {
+	return fmt.Sprintf("program does not fit in %d mailboxes", e.Mailboxes)
+}
+
+// ErrorToken returns the token that an error from the parser or Validate occurred at. It returns false if the error
+// isn't associated with a particular token.
+func ErrorToken(err error) (Token, bool) {
+	var (
+		illegal    ErrIllegalToken
+		mnemonic   ErrInvalidMnemonic
+		unexpected ErrUnexpectedToken
+		undefined  ErrUndefinedLabel
+		duplicate  ErrDuplicateLabel
+		operand    ErrOperandRange
+		opcode     ErrOpcodeRange
+		fixed      ErrFixedOperandRange
+		size       ErrProgramSize
+	)
+
+	switch {
+	case errors.As(err, &illegal):
+		return illegal.Token, true
+	case errors.As(err, &mnemonic):
+		return mnemonic.Token, true
+	case errors.As(err, &unexpected):
+		return unexpected.Token, true
+	case errors.As(err, &undefined):
+		return undefined.Token, true
+	case errors.As(err, &duplicate):
+		return duplicate.Token, true
+	case errors.As(err, &operand):
+		return operand.Token, true
+	case errors.As(err, &opcode):
+		return opcode.Token, true
+	case errors.As(err, &fixed):
+		return fixed.Token, true
+	case errors.As(err, &size):
+		return size.Token, true
+	}
+
+	return Token{}, false
+}
@@ -0,0 +1,38 @@
+package lmc
+
+import "strings"
+
+// labelWidth is the width of the label column when formatting instructions, which matches the indentation used by
+// the examples.
+const labelWidth = 8
+
+// Format converts a list of instructions back into assembly, one instruction per line with labels in their own
+// column. Operands that weren't written in the source, such as those of INP and OUT, are left out.
+func Format(instructions []Instruction) string {
+	return DefaultInstructionSet.Format(instructions)
+}
+
+// Format is like the Format function, but leaves out the operands of every instruction with a fixed operand in this
+// instruction set.
+func (s *InstructionSet) Format(instructions []Instruction) string {
+	var b strings.Builder
+
+	for _, instruction := range instructions {
+		b.WriteString(instruction.Label)
+
+		padding := labelWidth - len(instruction.Label)
+		if padding < 1 {
+			padding = 1
+		}
+
+		b.WriteString(strings.Repeat(" ", padding) + instruction.Mnemonic)
+
+		if def, _ := s.Lookup(instruction.Mnemonic); instruction.Operand != "" && def.Kind != OperandFixed {
+			b.WriteString(" " + instruction.Operand)
+		}
+
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,163 @@
+package lmc_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// addExamples adds the source of every example program to the seed corpus of a fuzz target.
+func addExamples(f *testing.F) {
+	paths, err := filepath.Glob(filepath.Join("examples", "*.lmc"))
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add(string(src))
+	}
+}
+
+// image converts bytes into the contents of mailboxes, two bytes per mailbox. Every value fits in three digits unless
+// wide is true, in which case values can also be negative or too long.
+func image(data []byte, wide bool) []string {
+	values := []string{}
+
+	for i := 0; i+1 < len(data) && len(values) < 100; i += 2 {
+		n := int(data[i])<<8 | int(data[i+1])
+
+		if wide {
+			values = append(values, strconv.Itoa(n-32768))
+		} else {
+			values = append(values, strconv.Itoa(n%1000))
+		}
+	}
+
+	return values
+}
+
+func FuzzLexer(f *testing.F) {
+	addExamples(f)
+	f.Add("")
+	f.Add("LOOP BRA LOOP // forever")
+	f.Add("/")
+
+	f.Fuzz(func(t *testing.T, src string) {
+		lexer := lmc.NewLexer(src)
+
+		// Every token consumes at least one character, apart from the final EOF.
+		for i := 0; i <= len(src)+1; i++ {
+			if lexer.Next().Type == lmc.EOF {
+				return
+			}
+		}
+
+		t.Fatalf("lexer didn't reach EOF after %d tokens", len(src)+2)
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	addExamples(f)
+	f.Add("x DAT x")
+
+	f.Fuzz(func(t *testing.T, src string) {
+		instructions, err := lmc.NewParser(lmc.NewLexer(src)).Parse()
+		if err != nil {
+			return
+		}
+
+		// Invalid programs still assemble to something, Validate is what reports their problems.
+		lmc.Validate(instructions, 1, 2)
+		lmc.Assemble(instructions, 1, 2)
+
+		formatted, err := lmc.NewParser(lmc.NewLexer(lmc.Format(instructions))).Parse()
+		if err != nil {
+			t.Fatalf("formatted program doesn't parse: %v", err)
+		}
+
+		if len(formatted) != len(instructions) {
+			t.Fatalf("formatted program has %d instructions, want %d", len(formatted), len(instructions))
+		}
+
+		for i := range instructions {
+			got, want := formatted[i], instructions[i]
+			if got.Label != want.Label || got.Mnemonic != want.Mnemonic || got.Operand != want.Operand {
+				t.Fatalf("instruction %d is %s %s %s after formatting, want %s %s %s", i,
+					got.Label, got.Mnemonic, got.Operand, want.Label, want.Mnemonic, want.Operand)
+			}
+		}
+	})
+}
+
+func FuzzAssemble(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0x03, 0x85, 0x01, 0x36, 0x03, 0x86, 0x00, 0x00})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		mailboxes := lmc.NewInitialisedMailboxes(1, 2, image(data, false))
+
+		// Values are padded when assembled, so compare them as numbers.
+		instructions := lmc.Disassemble(mailboxes, 1, 2)
+		assembled := lmc.Assemble(instructions, 1, 2)
+
+		for i := 0; i < 100; i++ {
+			want, _ := mailboxes.Get(i)
+			got, _ := assembled.Get(i)
+
+			if w, _ := strconv.Atoi(want); got != leftPad(w) {
+				t.Fatalf("mailbox %d is %q after disassembling and assembling, want %q", i, got, want)
+			}
+		}
+
+		// The disassembly is valid assembly that means the same thing.
+		src := lmc.Format(instructions)
+		parsed, err := lmc.NewParser(lmc.NewLexer(src)).Parse()
+		if err != nil {
+			t.Fatalf("disassembly doesn't parse: %v\n%s", err, src)
+		}
+
+		if errs := lmc.Validate(parsed, 1, 2); len(errs) != 0 {
+			t.Fatalf("disassembly isn't valid: %v\n%s", errs, src)
+		}
+
+		reassembled := lmc.Assemble(parsed, 1, 2)
+		for i := 0; i < 100; i++ {
+			want, _ := assembled.Get(i)
+			got, _ := reassembled.Get(i)
+
+			if got != want {
+				t.Fatalf("mailbox %d is %q after formatting the disassembly, want %q\n%s", i, got, want, src)
+			}
+		}
+	})
+}
+
+// leftPad pads a value to the width of a mailbox.
+func leftPad(n int) string {
+	s := strconv.Itoa(n)
+	for len(s) < 3 {
+		s = "0" + s
+	}
+
+	return s
+}
+
+func FuzzComputer(f *testing.F) {
+	f.Add([]byte{0x03, 0x85, 0x01, 0x36, 0x03, 0x86, 0x00, 0x64}, false, 5, 7)
+
+	f.Fuzz(func(t *testing.T, data []byte, wide bool, first, second int) {
+		mailboxes := lmc.NewInitialisedMailboxes(1, 2, image(data, wide))
+		computer := lmc.NewComputerFromMailboxes(mailboxes, 1, 2)
+
+		// Any error is fine, as long as the computer doesn't panic.
+		computer.RunWithInputs([]int{first, second}, 1000)
+	})
+}
@@ -0,0 +1,322 @@
+// Package grade marks a class's worth of LMC submissions against an assignment: a set of weighted test cases along
+// with limits on how large the programs may be, how long they may run for and which constructs they may use.
+package grade
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/spec"
+)
+
+// SelfModifying can be listed in an assignment's banned constructs to forbid programs from changing their own
+// instructions.
+const SelfModifying = "self-modifying"
+
+// Assignment describes how submissions are marked. It is loaded from a JSON file such as:
+//
+//	{
+//	    "name": "Square a number",
+//	    "maxCycles": 5000,
+//	    "maxMailboxes": 30,
+//	    "timeout": "2s",
+//	    "banned": ["self-modifying"],
+//	    "cases": [
+//	        {"name": "zero", "inputs": [0], "outputs": [0]},
+//	        {"name": "large", "inputs": [31], "outputs": [961], "weight": 2}
+//	    ]
+//	}
+type Assignment struct {
+	Name string `json:"name"`
+
+	OpcodeSize  int `json:"opcodeSize"`
+	OperandSize int `json:"operandSize"`
+
+	MaxCycles    int      `json:"maxCycles"`    // Cycle budget for each case.
+	MaxMailboxes int      `json:"maxMailboxes"` // Most mailboxes a program may occupy, 0 for no limit.
+	Timeout      string   `json:"timeout"`      // Longest each case may run for, such as "2s".
+	Banned       []string `json:"banned"`       // Banned mnemonics, or SelfModifying.
+
+	Cases []Case `json:"cases"`
+
+	timeout time.Duration
+}
+
+// Case is a test case along with how much it counts towards the final score.
+type Case struct {
+	spec.Case
+	Weight float64 `json:"weight"` // Defaults to 1.
+}
+
+// Submission is a single student's program.
+type Submission struct {
+	Student string
+	Path    string
+}
+
+// Report is the mark given to a submission.
+type Report struct {
+	Student  string       `json:"student"`
+	Path     string       `json:"path"`
+	Score    float64      `json:"score"`
+	MaxScore float64      `json:"maxScore"`
+	Error    string       `json:"error,omitempty"` // Why the submission couldn't be run at all, if it couldn't.
+	Cases    []CaseReport `json:"cases"`
+}
+
+// CaseReport is the outcome of a single case for a submission.
+type CaseReport struct {
+	Name    string  `json:"name"`
+	Weight  float64 `json:"weight"`
+	Passed  bool    `json:"passed"`
+	Cycles  int     `json:"cycles"`
+	Failure string  `json:"failure,omitempty"`
+}
+
+// LoadAssignment reads an assignment from a JSON file.
+func LoadAssignment(path string) (*Assignment, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Assignment{OpcodeSize: 1, OperandSize: 2, MaxCycles: spec.DefaultMaxCycles}
+	if err := json.Unmarshal(bytes, a); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	if a.Timeout != "" {
+		a.timeout, err = time.ParseDuration(a.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid timeout: %s", path, err)
+		}
+	}
+
+	for i, c := range a.Cases {
+		if c.Weight == 0 {
+			a.Cases[i].Weight = 1
+		}
+
+		if c.Name == "" {
+			a.Cases[i].Name = fmt.Sprintf("case %d", i+1)
+		}
+	}
+
+	return a, nil
+}
+
+// Submissions finds the submissions in a directory. Each .lmc file directly inside it is a submission named after the
+// file, and each subdirectory containing a .lmc file is a submission named after the subdirectory.
+func Submissions(dir string) ([]Submission, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	subs := []Submission{}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+
+		if !entry.IsDir() {
+			if filepath.Ext(path) == ".lmc" {
+				subs = append(subs, Submission{strings.TrimSuffix(entry.Name(), ".lmc"), path})
+			}
+
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(path, "*.lmc"))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(matches) > 0 {
+			sort.Strings(matches)
+			subs = append(subs, Submission{entry.Name(), matches[0]})
+		}
+	}
+
+	return subs, nil
+}
+
+// GradeAll grades every submission, using up to workers goroutines at once. The reports are in the same order as
+// the submissions.
+func (a *Assignment) GradeAll(subs []Submission, workers int) []Report {
+	if workers < 1 {
+		workers = 1
+	}
+
+	reports := make([]Report, len(subs))
+	queue := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				reports[j] = a.Grade(subs[j])
+			}
+		}()
+	}
+
+	for i := range subs {
+		queue <- i
+	}
+	close(queue)
+
+	wg.Wait()
+	return reports
+}
+
+// Grade marks a single submission. A submission that can't be assembled, is too large or uses a banned mnemonic
+// scores zero without being run.
+func (a *Assignment) Grade(sub Submission) (report Report) {
+	report = Report{Student: sub.Student, Path: sub.Path, Cases: []CaseReport{}}
+	for _, c := range a.Cases {
+		report.MaxScore += c.Weight
+	}
+
+	// A broken submission shouldn't stop the rest of the class from being marked.
+	defer func() {
+		if r := recover(); r != nil {
+			report.Score = 0
+			report.Error = fmt.Sprintf("internal error: %v", r)
+		}
+	}()
+
+	code, err := ioutil.ReadFile(sub.Path)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	suite := &spec.Suite{
+		Path:        sub.Path,
+		OpcodeSize:  a.OpcodeSize,
+		OperandSize: a.OperandSize,
+		MaxCycles:   a.MaxCycles,
+		Timeout:     a.timeout,
+	}
+
+	if err := suite.Compile(string(code)); err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	if err := a.check(suite.Instructions()); err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	for _, c := range a.Cases {
+		var violation string
+		observers := []func(lmc.Msg){}
+		if a.banned(SelfModifying) {
+			observers = append(observers, watchSelfModifying(&violation))
+		}
+
+		result := suite.Run(c.Case, observers...)
+
+		failure := result.Failure
+		if violation != "" {
+			failure = strings.TrimSpace(violation + "\n" + failure)
+		}
+
+		cr := CaseReport{
+			Name:    c.Name,
+			Weight:  c.Weight,
+			Passed:  result.Passed && violation == "",
+			Cycles:  result.Cycles,
+			Failure: failure,
+		}
+
+		if cr.Passed {
+			report.Score += c.Weight
+		}
+
+		report.Cases = append(report.Cases, cr)
+	}
+
+	return report
+}
+
+// check looks for problems that can be found without running a program: its size and the constructs it uses.
+func (a *Assignment) check(instructions []lmc.Instruction) error {
+	if a.MaxMailboxes > 0 && len(instructions) > a.MaxMailboxes {
+		return fmt.Errorf("program uses %d mailboxes, the limit is %d", len(instructions), a.MaxMailboxes)
+	}
+
+	labels := make(map[string]int)
+	for i, instruction := range instructions {
+		if instruction.Label != "" {
+			labels[instruction.Label] = i
+		}
+	}
+
+	for _, instruction := range instructions {
+		line := instruction.MnemonicToken.Line + 1
+
+		if a.banned(instruction.Mnemonic) {
+			return fmt.Errorf("line %d: %s is not allowed in this assignment", line, instruction.Mnemonic)
+		}
+
+		if !a.banned(SelfModifying) || instruction.Opcode != lmc.DefaultMnemonicMap["STA"].Opcode {
+			continue
+		}
+
+		target, ok := labels[instruction.Operand]
+		if !ok {
+			n, err := lmc.ParseInt(instruction.Operand)
+			if err != nil {
+				continue
+			}
+
+			target = n
+		}
+
+		if target < len(instructions) && instructions[target].Mnemonic != "DAT" {
+			return fmt.Errorf("line %d: %s overwrites an instruction (self-modifying code is not allowed)", line, instruction.Mnemonic)
+		}
+	}
+
+	return nil
+}
+
+// banned returns true if a mnemonic or construct is banned by the assignment.
+func (a *Assignment) banned(construct string) bool {
+	for _, b := range a.Banned {
+		if strings.EqualFold(b, construct) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// watchSelfModifying returns an observer that records a violation if the program executes a mailbox it has written
+// to, which catches self-modifying code that check can't see, such as writing through a computed address.
+func watchSelfModifying(violation *string) func(lmc.Msg) {
+	written := make(map[int]bool)
+
+	return func(msg lmc.Msg) {
+		addr, _ := strconv.Atoi(msg.Val)
+
+		switch msg.Status {
+		case lmc.MemoryWrite:
+			written[addr] = true
+		case lmc.NeedStep:
+			if written[addr] && *violation == "" {
+				*violation = fmt.Sprintf("executed mailbox %d after writing to it (self-modifying code is not allowed)", addr)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package grade
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const assignment = `{
+	"name": "Double",
+	"maxCycles": 100,
+	"maxMailboxes": 10,
+	"timeout": "5s",
+	"banned": ["self-modifying", "BRP"],
+	"cases": [
+		{"name": "two", "inputs": [2], "outputs": [4]},
+		{"name": "five", "inputs": [5], "outputs": [10], "weight": 3}
+	]
+}`
+
+var submissions = map[string]string{
+	"alice.lmc":      "INP\nSTA n\nADD n\nOUT\nHLT\nn DAT",
+	"bob.lmc":        "INP\nSTA n\nADD n\nADD n\nOUT\nHLT\nn DAT",
+	"carol.lmc":      "INP\nBRP ok\nok OUT\nHLT",
+	"dave.lmc":       "INP\nSTA 1\nOUT",
+	"erin.lmc":       "INP\nLDA a\nADD a\nSTA a\nSTA b\nb DAT\nOUT\nHLT\na DAT 0",
+	"frank.lmc":      "INP\nBRA nowhere",
+	"grace/main.lmc": "INP\nSTA n\nADD n\nOUT\nHLT\nn DAT",
+}
+
+func TestGradeAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grade")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "assignment.json"), []byte(assignment), 0644))
+	for name, code := range submissions {
+		path := filepath.Join(dir, "submissions", name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(code), 0644))
+	}
+
+	a, err := LoadAssignment(filepath.Join(dir, "assignment.json"))
+	assert.NoError(t, err)
+
+	subs, err := Submissions(filepath.Join(dir, "submissions"))
+	assert.NoError(t, err)
+	assert.Len(t, subs, 7)
+
+	reports := a.GradeAll(subs, 3)
+
+	tests := []struct {
+		student string
+		score   float64
+		error   string
+		failure string
+	}{
+		{"alice", 4, "", ""},
+		{"bob", 0, "", "outputs differ at position 1\n  want: [4]\n  got:  [6]"},
+		{"carol", 0, "line 2: BRP is not allowed in this assignment", ""},
+		{"dave", 0, "line 2: STA overwrites an instruction (self-modifying code is not allowed)", ""},
+		{"erin", 0, "", "executed mailbox 5 after writing to it (self-modifying code is not allowed)\n" +
+			"outputs differ at position 1\n  want: [4]\n  got:  []"},
+		{"frank", 0, "undefined label: nowhere", ""},
+		{"grace", 4, "", ""},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.student, func(t *testing.T) {
+			r := reports[i]
+			assert.Equal(t, tc.student, r.Student)
+			assert.Equal(t, tc.score, r.Score)
+			assert.Equal(t, 4.0, r.MaxScore)
+			assert.Equal(t, tc.error, r.Error)
+
+			if tc.failure != "" {
+				assert.Equal(t, tc.failure, r.Cases[0].Failure)
+			}
+		})
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, WriteCSV(out, reports[:2]))
+	assert.Equal(t, "student,score,max_score,percent,passed,failed,notes\n"+
+		"alice,4,4,100.0,2,0,\n"+
+		"bob,0,4,0.0,0,2,two: outputs differ at position 1; five: outputs differ at position 1\n", out.String())
+}
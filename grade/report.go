@@ -0,0 +1,63 @@
+package grade
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteCSV writes one row per student with their score and a summary of why they lost marks.
+func WriteCSV(w io.Writer, reports []Report) error {
+	out := csv.NewWriter(w)
+
+	if err := out.Write([]string{"student", "score", "max_score", "percent", "passed", "failed", "notes"}); err != nil {
+		return err
+	}
+
+	for _, r := range reports {
+		passed, notes := 0, []string{}
+		if r.Error != "" {
+			notes = append(notes, r.Error)
+		}
+
+		for _, c := range r.Cases {
+			if c.Passed {
+				passed++
+				continue
+			}
+
+			notes = append(notes, fmt.Sprintf("%s: %s", c.Name, strings.SplitN(c.Failure, "\n", 2)[0]))
+		}
+
+		percent := 0.0
+		if r.MaxScore > 0 {
+			percent = 100 * r.Score / r.MaxScore
+		}
+
+		err := out.Write([]string{
+			r.Student,
+			fmt.Sprint(r.Score),
+			fmt.Sprint(r.MaxScore),
+			fmt.Sprintf("%.1f", percent),
+			fmt.Sprint(passed),
+			fmt.Sprint(len(r.Cases) - passed),
+			strings.Join(notes, "; "),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// WriteJSON writes every report in full, including the explanation for each failed case.
+func WriteJSON(w io.Writer, reports []Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(reports)
+}
@@ -0,0 +1,324 @@
+package lmc
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OperandKind describes what the operand of an instruction means and how it's written.
+type OperandKind string
+
+// Operand kinds
+const (
+	// OperandAddress is an address or label, which can be left out if it's zero. ADD and BRA have address operands.
+	OperandAddress OperandKind = "address"
+
+	// OperandFixed is part of the instruction and can't be written. The operand given in the instruction definition
+	// is always used, such as 1 for INP and 2 for OUT.
+	OperandFixed OperandKind = "fixed"
+
+	// OperandNone isn't used by the instruction, though it can still be written like an address. The instruction is
+	// only decoded when the operand is the one given in the instruction definition, such as 0 for HLT.
+	OperandNone OperandKind = "none"
+
+	// OperandData is a value or label stored in a mailbox as it is, rather than an instruction. DAT has a data
+	// operand.
+	OperandData OperandKind = "data"
+)
+
+// Semantics describes what an instruction does when it's executed.
+type Semantics string
+
+// Semantics definitions
+const (
+	SemanticsHalt           Semantics = "halt"            // Stop the computer.
+	SemanticsAdd            Semantics = "add"             // Add the mailbox at the operand to the accumulator.
+	SemanticsSubtract       Semantics = "subtract"        // Subtract the mailbox at the operand from the accumulator.
+	SemanticsStore          Semantics = "store"           // Store the accumulator in the mailbox at the operand.
+	SemanticsLoad           Semantics = "load"            // Load the mailbox at the operand into the accumulator.
+	SemanticsBranch         Semantics = "branch"          // Jump to the operand.
+	SemanticsBranchZero     Semantics = "branch-zero"     // Jump to the operand if the accumulator is zero.
+	SemanticsBranchPositive Semantics = "branch-positive" // Jump to the operand if the accumulator isn't negative.
+	SemanticsInput          Semantics = "input"           // Set the accumulator to the next input.
+	SemanticsOutput         Semantics = "output"          // Output the accumulator.
+	SemanticsInputChar      Semantics = "input-char"      // Set the accumulator to the code of the next character.
+	SemanticsOutputChar     Semantics = "output-char"     // Output the character whose code is in the accumulator.
+	SemanticsNone           Semantics = "none"            // Do nothing, as for data.
+
+	// Semantics for interrupts, which only do anything if the computer has Interrupts set.
+	SemanticsReturnInterrupt   Semantics = "return-interrupt"   // Return from an interrupt handler.
+	SemanticsEnableInterrupts  Semantics = "enable-interrupts"  // Unmask interrupts.
+	SemanticsDisableInterrupts Semantics = "disable-interrupts" // Mask interrupts.
+
+	// Semantics only used by the extended instruction set.
+	SemanticsLoadIndirect  Semantics = "load-indirect"  // Load the mailbox whose address is in the operand's mailbox.
+	SemanticsStoreIndirect Semantics = "store-indirect" // Store into the mailbox whose address is in the operand's.
+	SemanticsAnd           Semantics = "and"            // Bitwise and the mailbox at the operand into the accumulator.
+	SemanticsOr            Semantics = "or"             // Bitwise or the mailbox at the operand into the accumulator.
+	SemanticsMultiply      Semantics = "multiply"       // Multiply the accumulator by the mailbox at the operand.
+	SemanticsDivide        Semantics = "divide"         // Divide the accumulator by the mailbox at the operand.
+	SemanticsPush          Semantics = "push"           // Push the accumulator onto the stack.
+	SemanticsPop           Semantics = "pop"            // Pop the top of the stack into the accumulator.
+	SemanticsCall          Semantics = "call"           // Push the address of the next instruction and jump.
+	SemanticsReturn        Semantics = "return"         // Pop an address off the stack and jump to it.
+	SemanticsTestAndSet    Semantics = "test-and-set"   // Load the mailbox at the operand and set it to one, atomically.
+)
+
+// semantics lists every kind of semantics the computer knows how to execute.
+var semantics = []Semantics{
+	SemanticsHalt, SemanticsAdd, SemanticsSubtract, SemanticsStore, SemanticsLoad, SemanticsBranch,
+	SemanticsBranchZero, SemanticsBranchPositive, SemanticsInput, SemanticsOutput, SemanticsInputChar,
+	SemanticsOutputChar, SemanticsNone, SemanticsReturnInterrupt, SemanticsEnableInterrupts,
+	SemanticsDisableInterrupts, SemanticsLoadIndirect, SemanticsStoreIndirect, SemanticsAnd, SemanticsOr,
+	SemanticsMultiply, SemanticsDivide, SemanticsPush, SemanticsPop, SemanticsCall, SemanticsReturn,
+	SemanticsTestAndSet,
+}
+
+// InstructionDef defines a single instruction in an instruction set.
+type InstructionDef struct {
+	Mnemonic  string      `json:"mnemonic" yaml:"mnemonic"`
+	Opcode    int         `json:"opcode" yaml:"opcode"`
+	Operand   int         `json:"operand,omitempty" yaml:"operand,omitempty"` // Only used by fixed and none kinds.
+	Kind      OperandKind `json:"kind" yaml:"kind"`
+	Semantics Semantics   `json:"semantics" yaml:"semantics"`
+}
+
+// InstructionSet describes the mnemonics a program can use, the opcodes they're assembled to and what the computer
+// does when it executes them. Several mnemonics can share an opcode, in which case the first is used when
+// disassembling.
+type InstructionSet struct {
+	Name         string           `json:"name" yaml:"name"`
+	Instructions []InstructionDef `json:"instructions" yaml:"instructions"`
+}
+
+// DefaultInstructionSet is the instruction set used when no other is given, which is the one from the original Little
+// Man Computer, along with STO as another name for STA, the character input and output instructions INA and OTC found
+// in many variants.
+var DefaultInstructionSet = &InstructionSet{
+	Name: "lmc",
+	Instructions: []InstructionDef{
+		{Mnemonic: "HLT", Opcode: 0, Operand: 0, Kind: OperandNone, Semantics: SemanticsHalt},
+		{Mnemonic: "ADD", Opcode: 1, Kind: OperandAddress, Semantics: SemanticsAdd},
+		{Mnemonic: "SUB", Opcode: 2, Kind: OperandAddress, Semantics: SemanticsSubtract},
+		{Mnemonic: "STA", Opcode: 3, Kind: OperandAddress, Semantics: SemanticsStore},
+		{Mnemonic: "STO", Opcode: 3, Kind: OperandAddress, Semantics: SemanticsStore},
+		{Mnemonic: "LDA", Opcode: 5, Kind: OperandAddress, Semantics: SemanticsLoad},
+		{Mnemonic: "BRA", Opcode: 6, Kind: OperandAddress, Semantics: SemanticsBranch},
+		{Mnemonic: "BRZ", Opcode: 7, Kind: OperandAddress, Semantics: SemanticsBranchZero},
+		{Mnemonic: "BRP", Opcode: 8, Kind: OperandAddress, Semantics: SemanticsBranchPositive},
+		{Mnemonic: "INP", Opcode: 9, Operand: 1, Kind: OperandFixed, Semantics: SemanticsInput},
+		{Mnemonic: "OUT", Opcode: 9, Operand: 2, Kind: OperandFixed, Semantics: SemanticsOutput},
+		{Mnemonic: "INA", Opcode: 9, Operand: 11, Kind: OperandFixed, Semantics: SemanticsInputChar},
+		{Mnemonic: "OTC", Opcode: 9, Operand: 22, Kind: OperandFixed, Semantics: SemanticsOutputChar},
+		{Mnemonic: "DAT", Opcode: -1, Kind: OperandData, Semantics: SemanticsNone},
+	},
+}
+
+// ExtendedInstructionSet is the default instruction set with indirect addressing, a stack and more arithmetic. The new
+// instructions have two digit opcodes, so programs using them need an opcode size of at least two.
+//
+// The stack starts at the top of memory and grows down, and the computer's StackPointer holds the address of the
+// value on top of it.
+//
+// TAS loads a mailbox into the accumulator and sets it to one as a single instruction, so that processors sharing
+// mailboxes in a Multiprocessor can use it to build locks.
+var ExtendedInstructionSet = &InstructionSet{
+	Name: "extended",
+	Instructions: append(append([]InstructionDef{}, DefaultInstructionSet.Instructions...),
+		InstructionDef{Mnemonic: "LDI", Opcode: 10, Kind: OperandAddress, Semantics: SemanticsLoadIndirect},
+		InstructionDef{Mnemonic: "STI", Opcode: 11, Kind: OperandAddress, Semantics: SemanticsStoreIndirect},
+		InstructionDef{Mnemonic: "AND", Opcode: 12, Kind: OperandAddress, Semantics: SemanticsAnd},
+		InstructionDef{Mnemonic: "OR", Opcode: 13, Kind: OperandAddress, Semantics: SemanticsOr},
+		InstructionDef{Mnemonic: "MUL", Opcode: 14, Kind: OperandAddress, Semantics: SemanticsMultiply},
+		InstructionDef{Mnemonic: "DIV", Opcode: 15, Kind: OperandAddress, Semantics: SemanticsDivide},
+		InstructionDef{Mnemonic: "CALL", Opcode: 16, Kind: OperandAddress, Semantics: SemanticsCall},
+		InstructionDef{Mnemonic: "PUSH", Opcode: 17, Kind: OperandFixed, Semantics: SemanticsPush},
+		InstructionDef{Mnemonic: "POP", Opcode: 18, Kind: OperandFixed, Semantics: SemanticsPop},
+		InstructionDef{Mnemonic: "RET", Opcode: 19, Kind: OperandFixed, Semantics: SemanticsReturn},
+		InstructionDef{Mnemonic: "TAS", Opcode: 20, Kind: OperandAddress, Semantics: SemanticsTestAndSet},
+	),
+}
+
+// InterruptInstructions are RTI, EI and DI, for returning from interrupt handlers and masking and unmasking
+// interrupts. Interrupts have to be set up before they can be used, so the instructions aren't in any of the built in
+// sets, and are added to one with WithInterrupts instead.
+var InterruptInstructions = []InstructionDef{
+	{Mnemonic: "RTI", Opcode: 9, Operand: 30, Kind: OperandFixed, Semantics: SemanticsReturnInterrupt},
+	{Mnemonic: "EI", Opcode: 9, Operand: 31, Kind: OperandFixed, Semantics: SemanticsEnableInterrupts},
+	{Mnemonic: "DI", Opcode: 9, Operand: 32, Kind: OperandFixed, Semantics: SemanticsDisableInterrupts},
+}
+
+// InstructionSets are the built in instruction sets, by name.
+var InstructionSets = map[string]*InstructionSet{
+	DefaultInstructionSet.Name:  DefaultInstructionSet,
+	ExtendedInstructionSet.Name: ExtendedInstructionSet,
+}
+
+// instructionSet returns the instruction set given, or the default one if it's nil.
+func instructionSet(set *InstructionSet) *InstructionSet {
+	if set == nil {
+		return DefaultInstructionSet
+	}
+
+	return set
+}
+
+// WithInterrupts returns a copy of the set with the InterruptInstructions added, for running programs on a computer
+// with interrupts set up. Instructions whose mnemonic or opcode and operand the set already uses are left out, so sets
+// that define their own interrupt instructions keep them.
+func (s *InstructionSet) WithInterrupts() *InstructionSet {
+	set := &InstructionSet{Name: s.Name, Instructions: append([]InstructionDef{}, s.Instructions...)}
+
+	for _, def := range InterruptInstructions {
+		_, defined := s.Lookup(def.Mnemonic)
+		_, used := s.Decode(def.Opcode, def.Operand)
+
+		if !defined && !used {
+			set.Instructions = append(set.Instructions, def)
+		}
+	}
+
+	return set
+}
+
+// Lookup returns the definition of a mnemonic.
+func (s *InstructionSet) Lookup(mnemonic string) (InstructionDef, bool) {
+	for _, def := range s.Instructions {
+		if def.Mnemonic == mnemonic {
+			return def, true
+		}
+	}
+
+	return InstructionDef{}, false
+}
+
+// Decode returns the definition of the instruction with an opcode and operand. Instructions whose operand is fixed or
+// unused only match that exact operand, and are preferred over instructions with an address operand.
+func (s *InstructionSet) Decode(opcode, operand int) (InstructionDef, bool) {
+	for _, def := range s.Instructions {
+		if def.Opcode == opcode && (def.Kind == OperandFixed || def.Kind == OperandNone) && def.Operand == operand {
+			return def, true
+		}
+	}
+
+	for _, def := range s.Instructions {
+		if def.Opcode == opcode && def.Kind == OperandAddress {
+			return def, true
+		}
+	}
+
+	return InstructionDef{}, false
+}
+
+// MnemonicMap returns a map from each mnemonic to an Instruction with its mnemonic, opcode and default operand, in the
+// same form as DefaultMnemonicMap.
+func (s *InstructionSet) MnemonicMap() map[string]Instruction {
+	m := make(map[string]Instruction)
+
+	for _, def := range s.Instructions {
+		m[def.Mnemonic] = Instruction{Mnemonic: def.Mnemonic, Operand: fmt.Sprint(def.Operand), Opcode: def.Opcode}
+	}
+
+	return m
+}
+
+// Check makes sure an instruction set is well formed: that every instruction has a mnemonic that can be written in
+// source, a known kind and semantics, and that no two instructions are decoded from the same opcode and operand
+// unless they mean the same thing.
+func (s *InstructionSet) Check() error {
+	known := make(map[Semantics]bool)
+	for _, sem := range semantics {
+		known[sem] = true
+	}
+
+	mnemonics := make(map[string]bool)
+	decoded := make(map[string]InstructionDef)
+
+	for _, def := range s.Instructions {
+		if !isIdentifier(def.Mnemonic) {
+			return ErrInvalidInstructionSet{def.Mnemonic, "mnemonic must be letters followed by letters or digits"}
+		}
+
+		if mnemonics[def.Mnemonic] {
+			return ErrInvalidInstructionSet{def.Mnemonic, "mnemonic is defined more than once"}
+		}
+		mnemonics[def.Mnemonic] = true
+
+		if !known[def.Semantics] {
+			return ErrInvalidInstructionSet{def.Mnemonic, fmt.Sprintf("unknown semantics %q", def.Semantics)}
+		}
+
+		key := fmt.Sprint(def.Opcode)
+		switch def.Kind {
+		case OperandAddress:
+		case OperandFixed, OperandNone:
+			key += "/" + fmt.Sprint(def.Operand)
+		case OperandData:
+			continue
+		default:
+			return ErrInvalidInstructionSet{def.Mnemonic, fmt.Sprintf("unknown operand kind %q", def.Kind)}
+		}
+
+		if def.Opcode < 0 {
+			return ErrInvalidInstructionSet{def.Mnemonic, "opcode must not be negative"}
+		}
+
+		if other, ok := decoded[key]; ok && (other.Kind != def.Kind || other.Semantics != def.Semantics) {
+			return ErrInvalidInstructionSet{def.Mnemonic, fmt.Sprintf("opcode is already used by %s", other.Mnemonic)}
+		}
+		decoded[key] = def
+	}
+
+	return nil
+}
+
+// ReadInstructionSet reads an instruction set written in YAML or JSON and checks that it's well formed. The kind of
+// every instruction defaults to an address, and its semantics to doing nothing.
+func ReadInstructionSet(r io.Reader) (*InstructionSet, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &InstructionSet{}
+	if err := yaml.Unmarshal(data, set); err != nil {
+		return nil, err
+	}
+
+	for i := range set.Instructions {
+		def := &set.Instructions[i]
+
+		if def.Kind == "" {
+			def.Kind = OperandAddress
+		}
+
+		if def.Semantics == "" {
+			def.Semantics = SemanticsNone
+		}
+	}
+
+	if err := set.Check(); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// LoadInstructionSet reads an instruction set from a YAML or JSON file. If path is the name of one of the built in
+// InstructionSets, that is returned instead.
+func LoadInstructionSet(path string) (*InstructionSet, error) {
+	if set, ok := InstructionSets[path]; ok {
+		return set, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadInstructionSet(f)
+}
@@ -0,0 +1,188 @@
+package lmc_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+// courseSet is an instruction set with different opcode numbering and extra mnemonics, in the style of one a course
+// might use.
+const courseSet = `
+name: course
+instructions:
+  - mnemonic: LOAD
+    opcode: 1
+    semantics: load
+  - mnemonic: STORE
+    opcode: 2
+    semantics: store
+  - mnemonic: ADD
+    opcode: 3
+    semantics: add
+  - mnemonic: SUB
+    opcode: 4
+    semantics: subtract
+  - mnemonic: JUMP
+    opcode: 5
+    semantics: branch
+  - mnemonic: JZ
+    opcode: 6
+    semantics: branch-zero
+  - mnemonic: JP
+    opcode: 7
+    semantics: branch-positive
+  - mnemonic: READ
+    opcode: 8
+    operand: 1
+    kind: fixed
+    semantics: input
+  - mnemonic: PRINT
+    opcode: 8
+    operand: 2
+    kind: fixed
+    semantics: output
+  - mnemonic: STOP
+    opcode: 0
+    kind: none
+    semantics: halt
+  - mnemonic: WORD
+    kind: data
+`
+
+func TestReadInstructionSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   error
+	}{
+		{"yaml", courseSet, nil},
+		{
+			"json",
+			`{"name": "tiny", "instructions": [
+				{"mnemonic": "INP", "opcode": 9, "operand": 1, "kind": "fixed", "semantics": "input"},
+				{"mnemonic": "HLT", "opcode": 0, "kind": "none", "semantics": "halt"}
+			]}`,
+			nil,
+		},
+		{
+			"duplicate-mnemonic",
+			`{"instructions": [{"mnemonic": "ADD", "opcode": 1}, {"mnemonic": "ADD", "opcode": 2}]}`,
+			lmc.ErrInvalidInstructionSet{Mnemonic: "ADD", Reason: "mnemonic is defined more than once"},
+		},
+		{
+			"clashing-opcode",
+			`{"instructions": [
+				{"mnemonic": "ADD", "opcode": 1, "semantics": "add"},
+				{"mnemonic": "SUB", "opcode": 1, "semantics": "subtract"}
+			]}`,
+			lmc.ErrInvalidInstructionSet{Mnemonic: "SUB", Reason: "opcode is already used by ADD"},
+		},
+		{
+			"unknown-semantics",
+			`{"instructions": [{"mnemonic": "SQR", "opcode": 4, "semantics": "square"}]}`,
+			lmc.ErrInvalidInstructionSet{Mnemonic: "SQR", Reason: `unknown semantics "square"`},
+		},
+		{
+			"unknown-kind",
+			`{"instructions": [{"mnemonic": "ADD", "opcode": 1, "kind": "register"}]}`,
+			lmc.ErrInvalidInstructionSet{Mnemonic: "ADD", Reason: `unknown operand kind "register"`},
+		},
+		{
+			"bad-mnemonic",
+			`{"instructions": [{"mnemonic": "2ADD", "opcode": 1}]}`,
+			lmc.ErrInvalidInstructionSet{Mnemonic: "2ADD", Reason: "mnemonic must be letters followed by letters or digits"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := lmc.ReadInstructionSet(strings.NewReader(tc.input))
+			assert.Equal(t, tc.err, err)
+		})
+	}
+}
+
+func TestDefaultInstructionSet(t *testing.T) {
+	assert.NoError(t, lmc.DefaultInstructionSet.Check(), "expect default instruction set to be well formed")
+
+	def, ok := lmc.DefaultInstructionSet.Decode(3, 10)
+	assert.True(t, ok)
+	assert.Equal(t, "STA", def.Mnemonic, "expect first of several mnemonics to be decoded")
+
+	_, ok = lmc.DefaultInstructionSet.Decode(0, 5)
+	assert.False(t, ok, "expect HLT to only be decoded with a zero operand")
+
+	_, ok = lmc.DefaultInstructionSet.Decode(9, 3)
+	assert.False(t, ok, "expect fixed operands to only be decoded exactly")
+}
+
+func TestInstructionSetWithInterrupts(t *testing.T) {
+	_, ok := lmc.DefaultInstructionSet.Lookup("RTI")
+	assert.False(t, ok, "expect interrupt instructions not to be in the default set")
+
+	set := lmc.DefaultInstructionSet.WithInterrupts()
+	assert.NoError(t, set.Check(), "expect adding interrupt instructions to keep the set well formed")
+	assert.Equal(t, len(lmc.DefaultInstructionSet.Instructions)+3, len(set.Instructions))
+
+	def, ok := set.Decode(9, 31)
+	assert.True(t, ok)
+	assert.Equal(t, "EI", def.Mnemonic)
+
+	own := &lmc.InstructionSet{Instructions: []lmc.InstructionDef{
+		{Mnemonic: "IRET", Opcode: 9, Operand: 30, Kind: lmc.OperandFixed, Semantics: lmc.SemanticsReturnInterrupt},
+		{Mnemonic: "DI", Opcode: 8, Kind: lmc.OperandAddress, Semantics: lmc.SemanticsBranchPositive},
+	}}
+	assert.Equal(t, []lmc.InstructionDef{own.Instructions[0], own.Instructions[1], lmc.InterruptInstructions[1]},
+		own.WithInterrupts().Instructions, "expect instructions the set already uses to be left out")
+}
+
+func TestCustomInstructionSet(t *testing.T) {
+	set, err := lmc.ReadInstructionSet(strings.NewReader(courseSet))
+	assert.NoError(t, err)
+
+	code := `
+		READ
+		STORE n
+loop	LOAD n
+		PRINT
+		SUB one
+		STORE n
+		JP loop
+		STOP
+n		WORD
+one		WORD 1`
+
+	parser := lmc.NewParser(lmc.NewLexer(code))
+	parser.InstructionSet = set
+
+	instructions, err := parser.Parse()
+	assert.NoError(t, err, "not expecting error parsing program")
+	assert.Empty(t, set.Validate(instructions, 1, 2), "not expecting program to be invalid")
+
+	mailboxes := set.Assemble(instructions, 1, 2)
+	for i, want := range []string{"801", "208", "108", "802", "409", "208", "702", "000", "000", "001"} {
+		got, _ := mailboxes.Get(i)
+		assert.Equal(t, want, got, "expect mailbox %d to be correct", i)
+	}
+
+	assert.Equal(
+		t,
+		"        READ\n        STORE 8\n        LOAD 8\n        PRINT\n        SUB 9\n        STORE 8\n        JP 2\n"+
+			"        STOP\n        STOP\n        WORD 1\n",
+		set.Format(set.Disassemble(mailboxes, 1, 2)),
+		"expect disassembly to use the instruction set",
+	)
+
+	computer := lmc.NewComputerFromMailboxes(mailboxes, 1, 2)
+	computer.InstructionSet = set
+
+	result, err := computer.RunWithInputs([]int{3}, 0)
+	assert.NoError(t, err, "not expecting error running program")
+	assert.Equal(t, []int{3, 2, 1, 0}, result.Outputs, "expect outputs to be correct")
+
+	_, err = lmc.NewParser(lmc.NewLexer(code)).Parse()
+	assert.Error(t, err, "expect the default instruction set not to accept the program")
+}
@@ -0,0 +1,182 @@
+package lmc
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Interrupts configures how a computer responds to interrupts. When one is raised and interrupts aren't masked, the
+// computer saves the program counter and accumulator to mailboxes and jumps to the vector, before the next
+// instruction would have been executed. Further interrupts are held until the handler returns with RTI, which
+// restores the program counter and accumulator from the mailboxes they were saved to. Programs can mask and unmask
+// interrupts with DI and EI; interrupts raised while masked are taken once they're unmasked, with several raised
+// interrupts being taken as one.
+type Interrupts struct {
+	Vector  int // Address of the interrupt handler.
+	SavePC  int // Mailbox the program counter is saved to.
+	SaveAcc int // Mailbox the accumulator is saved to.
+
+	// Schedule lists the cycles at which interrupts are raised, so that programs using interrupts can be tested
+	// deterministically. An interrupt at cycle n is raised once n instructions have been executed.
+	Schedule []int
+
+	// Masked is true if interrupts are being held back. It's changed by DI and EI, and put back to what it was when
+	// the computer first used the interrupts whenever the computer is reset.
+	Masked bool
+
+	mu       sync.Mutex
+	pending  bool // Whether an interrupt has been raised and not yet taken.
+	handling bool // Whether the handler is running.
+
+	recorded bool // Whether the masked state the interrupts were configured with has been recorded.
+	masked   bool // The masked state the interrupts were configured with, restored by reset.
+}
+
+// Interrupter is a device that can raise interrupts. It's asked before every instruction whether it wants to raise
+// one.
+type Interrupter interface {
+	Interrupting() bool
+}
+
+// record records the masked state the interrupts were configured with, the first time they're used, so that reset can
+// restore it. It must be called with the lock held.
+func (i *Interrupts) record() {
+	if !i.recorded {
+		i.recorded = true
+		i.masked = i.Masked
+	}
+}
+
+// copy returns interrupts configured the same way and in the same state, for a copy of the computer using them.
+func (i *Interrupts) copy() *Interrupts {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return &Interrupts{
+		Vector:   i.Vector,
+		SavePC:   i.SavePC,
+		SaveAcc:  i.SaveAcc,
+		Schedule: i.Schedule,
+		Masked:   i.Masked,
+		pending:  i.pending,
+		handling: i.handling,
+		recorded: i.recorded,
+		masked:   i.masked,
+	}
+}
+
+// raise raises an interrupt, which is taken before the next instruction if it isn't masked.
+func (i *Interrupts) raise() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.pending = true
+}
+
+// take returns true if an interrupt should be taken, marking it as being handled if so.
+func (i *Interrupts) take() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.record()
+	if !i.pending || i.Masked || i.handling {
+		return false
+	}
+
+	i.pending = false
+	i.handling = true
+	return true
+}
+
+// mask masks or unmasks interrupts.
+func (i *Interrupts) mask(masked bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.record()
+	i.Masked = masked
+}
+
+// finish marks the handler as having returned.
+func (i *Interrupts) finish() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.handling = false
+}
+
+// reset forgets any interrupt that has been raised but not taken, and any handler that was running, and puts Masked
+// back to what the interrupts were configured with.
+func (i *Interrupts) reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.record()
+	i.pending = false
+	i.handling = false
+	i.Masked = i.masked
+}
+
+// Interrupt raises an interrupt, which is taken before the next instruction that is executed once interrupts aren't
+// masked. It does nothing if the computer doesn't have Interrupts set. It's safe to call while the computer is
+// running, though for deterministic behaviour the Schedule should be used instead.
+func (c *Computer) Interrupt() {
+	if c.Interrupts != nil {
+		c.Interrupts.raise()
+	}
+}
+
+// interrupt raises any interrupts due from the schedule or devices, then takes an interrupt if there is one.
+func (c *Computer) interrupt() error {
+	in := c.Interrupts
+
+	for _, cycle := range in.Schedule {
+		if cycle == c.Cycles {
+			in.raise()
+		}
+	}
+
+	if c.Mailboxes.interrupting() {
+		in.raise()
+	}
+
+	if !in.take() {
+		return nil
+	}
+
+	c.send(Msg{Interrupt, fmt.Sprint(c.ProgramCounter)})
+	c.send(Msg{Log, fmt.Sprintf("Interrupted at address %d, jumping to %d", c.ProgramCounter, in.Vector)})
+
+	if err := c.write(in.SavePC, c.ProgramCounter); err != nil {
+		return err
+	}
+
+	if err := c.write(in.SaveAcc, c.Accumulator); err != nil {
+		return err
+	}
+
+	c.ProgramCounter = in.Vector
+	return nil
+}
+
+// returnFromInterrupt restores the program counter and accumulator saved when an interrupt was taken.
+func (c *Computer) returnFromInterrupt() error {
+	in := c.Interrupts
+	if in == nil {
+		return ErrNoInterrupt{c.ProgramCounter}
+	}
+
+	pc, err := c.read(in.SavePC)
+	if err != nil {
+		return err
+	}
+
+	acc, err := c.read(in.SaveAcc)
+	if err != nil {
+		return err
+	}
+
+	in.finish()
+	c.ProgramCounter, c.Accumulator = pc, acc
+	return nil
+}
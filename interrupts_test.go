@@ -0,0 +1,134 @@
+package lmc_test
+
+import (
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/device"
+	"github.com/stretchr/testify/assert"
+)
+
+// handler is an interrupt handler that outputs 50 and returns, assembled at the end of the programs below.
+const handler = `
+handler	LDA flag
+		OUT
+		RTI
+flag	DAT 50`
+
+// newComputer returns a computer running code written using the default instruction set with interrupt instructions.
+func newComputer(t *testing.T, code string) *lmc.Computer {
+	t.Helper()
+
+	set := lmc.DefaultInstructionSet.WithInterrupts()
+	parser := lmc.NewParser(lmc.NewLexer(code))
+	parser.InstructionSet = set
+
+	instructions, err := parser.Parse()
+	assert.NoError(t, err)
+
+	computer := lmc.NewComputerFromMailboxes(set.Assemble(instructions, 1, 2), 1, 2)
+	computer.InstructionSet = set
+
+	return computer
+}
+
+func TestInterruptSchedule(t *testing.T) {
+	code := `
+loop	LDA n
+		ADD one
+		STA n
+		OUT
+		SUB three
+		BRZ done
+		BRA loop
+done	HLT
+n		DAT 0
+one		DAT 1
+three	DAT 3` + handler
+
+	computer := newComputer(t, code)
+	computer.Interrupts = &lmc.Interrupts{Vector: 11, SavePC: 98, SaveAcc: 99, Schedule: []int{2}}
+
+	result, err := computer.RunWithInputs(nil, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{50, 1, 2, 3}, result.Outputs, "expect the handler to run before the STA and restore the accumulator")
+
+	pc, _ := computer.Mailboxes.Get(98)
+	assert.Equal(t, "002", pc, "expect the program counter to be saved")
+}
+
+func TestInterruptMasking(t *testing.T) {
+	code := `
+		DI
+		LDA a
+		OUT
+		EI
+		LDA b
+		OUT
+		HLT
+a		DAT 1
+b		DAT 2` + handler
+
+	computer := newComputer(t, code)
+	computer.Interrupts = &lmc.Interrupts{Vector: 9, SavePC: 98, SaveAcc: 99, Schedule: []int{1, 2}}
+
+	result, err := computer.RunWithInputs(nil, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 50, 2}, result.Outputs, "expect masked interrupts to be taken once, after EI")
+}
+
+func TestInterruptReset(t *testing.T) {
+	code := `
+		DI
+		LDA a
+		OUT
+		HLT
+a		DAT 1` + handler
+
+	computer := newComputer(t, code)
+	computer.Interrupts = &lmc.Interrupts{Vector: 5, SavePC: 98, SaveAcc: 99}
+
+	_, err := computer.RunWithInputs(nil, 100)
+	assert.NoError(t, err)
+	assert.True(t, computer.Interrupts.Masked)
+
+	computer.Reset()
+	assert.False(t, computer.Interrupts.Masked, "expect resetting to unmask interrupts masked by the program")
+
+	computer.Interrupts.Schedule = []int{0}
+	result, err := computer.RunWithInputs(nil, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{50, 1}, result.Outputs, "expect the interrupt to be taken after resetting")
+}
+
+func TestInterruptDevice(t *testing.T) {
+	code := `
+wait	LDA key
+		BRZ wait
+		OUT
+		HLT
+key		DAT 0
+handler	LDA 91
+		STA key
+		RTI`
+
+	computer := newComputer(t, code)
+	computer.Interrupts = &lmc.Interrupts{Vector: 5, SavePC: 98, SaveAcc: 99}
+
+	keyboard := device.NewKeyboard()
+	keyboard.Press("A")
+	assert.NoError(t, computer.Mailboxes.Map(90, keyboard))
+
+	result, err := computer.RunWithInputs(nil, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{'A'}, result.Outputs, "expect the keyboard to interrupt the loop")
+}
+
+func TestInterruptWithoutInterrupts(t *testing.T) {
+	_, err := lmc.NewComputerFromCode("LDA 5\nRTI\nHLT", 1, 2)
+	assert.Error(t, err, "expect RTI not to be an instruction unless interrupts are")
+
+	computer := newComputer(t, "LDA 5\nRTI\nHLT")
+	_, err = computer.RunWithInputs(nil, 100)
+	assert.Equal(t, lmc.ErrNoInterrupt{Addr: 1}, err)
+}
@@ -1,6 +1,9 @@
 package lmc
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Lexer is a lexer for LMC assembly.
 // It translates some series of characters into tokens.
@@ -65,7 +68,7 @@ func (l *Lexer) skipComment() {
 		return
 	}
 
-	for l.ch != '\n' {
+	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
 }
@@ -84,20 +87,68 @@ func (l *Lexer) readIdentifier() (string, int, int) {
 }
 
 // readInteger reads a number and returns it's value as a string, along with the start and end index of the integer
-// relative to the current line.
-func (l *Lexer) readInteger() (string, int, int) {
+// relative to the current line. Numbers starting with 0x, 0o or 0b are read as hexadecimal, octal or binary, and it
+// returns false if one of those has no digits or has digits that aren't valid in its radix.
+func (l *Lexer) readInteger() (string, int, int, bool) {
 	l.positionStart = l.position
 	colStart := l.col
+	valid := true
+
+	if radix, ok := literalRadixes[string([]byte{l.ch, l.peekChar()})]; ok {
+		l.readChar()
+		l.readChar()
+
+		valid = isRadixDigit(l.ch, radix)
+		for isLetter(l.ch) || isDigit(l.ch) {
+			valid = valid && isRadixDigit(l.ch, radix)
+			l.readChar()
+		}
+	}
 
 	for isDigit(l.ch) {
 		l.readChar()
 	}
 
 	if colStart == l.col {
-		return l.input[l.positionStart:l.position], colStart, l.col
+		return l.input[l.positionStart:l.position], colStart, l.col, valid
 	}
 
-	return l.input[l.positionStart:l.position], colStart, l.col - 1
+	return l.input[l.positionStart:l.position], colStart, l.col - 1, valid
+}
+
+// readString reads a string in double quotes, returning its contents with any escapes replaced, along with the start
+// and end index of the string relative to the current line. It returns false if the string isn't closed before the end
+// of the line.
+func (l *Lexer) readString() (string, int, int, bool) {
+	colStart, colEnd := l.col, l.col
+	var b strings.Builder
+
+	for l.readChar(); l.ch != '"'; l.readChar() {
+		if l.ch == '\\' {
+			l.readChar()
+
+			switch l.ch {
+			case 'n':
+				b.WriteByte('\n')
+				continue
+			case 't':
+				b.WriteByte('\t')
+				continue
+			}
+		}
+
+		if l.ch == '\n' || l.ch == 0 {
+			return b.String(), colStart, colEnd, false
+		}
+
+		b.WriteByte(l.ch)
+		colEnd = l.col
+	}
+
+	colEnd = l.col
+	l.readChar()
+
+	return b.String(), colStart, colEnd, true
 }
 
 // Next returns the next token in the input.
@@ -112,13 +163,25 @@ func (l *Lexer) Next() Token {
 
 	switch {
 	case isDigit(l.ch):
-		lit, start, end := l.readInteger()
+		lit, start, end, ok := l.readInteger()
+		if !ok {
+			return NewToken(ILLEGAL, lit, l.line, start, end)
+		}
+
 		return NewToken(INT, lit, l.line, start, end)
 
 	case isLetter(l.ch):
 		lit, start, end := l.readIdentifier()
 		return NewToken(IDENT, lit, l.line, start, end)
 
+	case l.ch == '"':
+		lit, start, end, ok := l.readString()
+		if !ok {
+			return NewToken(ILLEGAL, "\""+lit, l.line, start, end)
+		}
+
+		return NewToken(STRING, lit, l.line, start, end)
+
 	case l.ch == 0:
 		return NewToken(EOF, "", l.line, l.col, l.col)
 
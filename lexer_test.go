@@ -56,3 +56,42 @@ label ADD 2`
 	assert.Equal(t, lmc.EOF, final.Type, "expected last token to be EOF")
 
 }
+
+func TestLexerString(t *testing.T) {
+	tests := []struct {
+		input string
+		token lmc.Token
+	}{
+		{`"hello"`, lmc.Token{Type: lmc.STRING, Literal: "hello", StartCol: 0, EndCol: 6}},
+		{`"say \"hi\"\t\\"`, lmc.Token{Type: lmc.STRING, Literal: "say \"hi\"\t\\", StartCol: 0, EndCol: 15}},
+		{`"a\nb"`, lmc.Token{Type: lmc.STRING, Literal: "a\nb", StartCol: 0, EndCol: 5}},
+		{`"open`, lmc.Token{Type: lmc.ILLEGAL, Literal: `"open`, StartCol: 0, EndCol: 4}},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.token, lmc.NewLexer(tc.input).Next(), "expect token for %s to be correct", tc.input)
+	}
+}
+
+func TestLexerRadixLiterals(t *testing.T) {
+	tests := []struct {
+		input string
+		token lmc.Token
+	}{
+		{"0x1F ", lmc.Token{Type: lmc.INT, Literal: "0x1F", StartCol: 0, EndCol: 3}},
+		{"0b101 ", lmc.Token{Type: lmc.INT, Literal: "0b101", StartCol: 0, EndCol: 4}},
+		{"0o17 ", lmc.Token{Type: lmc.INT, Literal: "0o17", StartCol: 0, EndCol: 3}},
+		{"0b102 ", lmc.Token{Type: lmc.ILLEGAL, Literal: "0b102", StartCol: 0, EndCol: 4}},
+		{"0x ", lmc.Token{Type: lmc.ILLEGAL, Literal: "0x", StartCol: 0, EndCol: 1}},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.token, lmc.NewLexer(tc.input).Next(), "expect token for %s to be correct", tc.input)
+	}
+
+	for lit, want := range map[string]int{"0x1F": 31, "0b101": 5, "0o17": 15, "017": 17} {
+		n, err := lmc.ParseInt(lit)
+		assert.NoError(t, err)
+		assert.Equal(t, want, n, "expect %s to have the right value", lit)
+	}
+}
@@ -0,0 +1,185 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// document is an open .lmc file along with everything the server has worked out about it.
+type document struct {
+	uri  string
+	text string
+
+	instructions []lmc.Instruction // Instructions from the last successful parse.
+	mailboxes    *lmc.Mailboxes    // Assembled program, nil if the document has errors.
+	diagnostics  []Diagnostic
+
+	opcodeSize  int
+	operandSize int
+}
+
+// newDocument analyses the text given. If the text can't be parsed, the instructions from the previous version of
+// the document are kept so that completion and navigation keep working while the user is typing.
+func newDocument(uri, text string, opcodeSize, operandSize int, previous *document) *document {
+	doc := &document{
+		uri:         uri,
+		text:        text,
+		diagnostics: []Diagnostic{},
+		opcodeSize:  opcodeSize,
+		operandSize: operandSize,
+	}
+
+	parser := lmc.NewParser(lmc.NewLexer(text))
+	instructions, err := parser.Parse()
+	if err != nil {
+		doc.diagnostics = append(doc.diagnostics, newDiagnostic(err))
+
+		if previous != nil {
+			doc.instructions = previous.instructions
+		}
+
+		return doc
+	}
+
+	doc.instructions = instructions
+
+	errs := lmc.Validate(instructions, opcodeSize, operandSize)
+	for _, err := range errs {
+		doc.diagnostics = append(doc.diagnostics, newDiagnostic(err))
+	}
+
+	if len(errs) == 0 {
+		doc.mailboxes = lmc.Assemble(instructions, opcodeSize, operandSize)
+	}
+
+	return doc
+}
+
+// newDiagnostic converts an error from the parser or assembler into a diagnostic at the token that caused it.
+func newDiagnostic(err error) Diagnostic {
+	tok, _ := lmc.ErrorToken(err)
+
+	return Diagnostic{
+		Range:    tokenRange(tok),
+		Severity: severityError,
+		Source:   "lmc",
+		Message:  err.Error(),
+	}
+}
+
+// tokenRange returns the range a token covers. The range is computed from the literal rather than the token's end
+// column so that it also covers tokens at the very end of the input.
+func tokenRange(tok lmc.Token) Range {
+	return Range{
+		Start: Position{tok.Line, tok.StartCol},
+		End:   Position{tok.Line, tok.StartCol + len(tok.Literal)},
+	}
+}
+
+// tokenAt finds the instruction and token at a position. Positions just after the end of a token count as being
+// inside it, since that's where the cursor sits after typing a word.
+func (d *document) tokenAt(pos Position) (int, lmc.Token, bool) {
+	for i, instruction := range d.instructions {
+		for _, tok := range []lmc.Token{instruction.LabelToken, instruction.MnemonicToken, instruction.OperandToken} {
+			if tok.Type == "" || tok.Line != pos.Line {
+				continue
+			}
+
+			if pos.Character >= tok.StartCol && pos.Character <= tok.StartCol+len(tok.Literal) {
+				return i, tok, true
+			}
+		}
+	}
+
+	return 0, lmc.Token{}, false
+}
+
+// labelAt returns the name of the label at a position, whether it's where the label is defined or an operand that
+// refers to it.
+func (d *document) labelAt(pos Position) (string, bool) {
+	i, tok, ok := d.tokenAt(pos)
+	if !ok {
+		return "", false
+	}
+
+	instruction := d.instructions[i]
+	if tok == instruction.LabelToken || (tok == instruction.OperandToken && tok.Type == lmc.IDENT) {
+		return tok.Literal, true
+	}
+
+	return "", false
+}
+
+// definition returns the index of the instruction that defines a label.
+func (d *document) definition(label string) (int, bool) {
+	for i, instruction := range d.instructions {
+		if instruction.Label == label {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// references returns every token that mentions a label, optionally including where it is defined.
+func (d *document) references(label string, includeDeclaration bool) []lmc.Token {
+	toks := []lmc.Token{}
+
+	for _, instruction := range d.instructions {
+		if includeDeclaration && instruction.Label == label {
+			toks = append(toks, instruction.LabelToken)
+		}
+
+		if instruction.OperandToken.Type == lmc.IDENT && instruction.Operand == label {
+			toks = append(toks, instruction.OperandToken)
+		}
+	}
+
+	return toks
+}
+
+// hover describes the mailbox an instruction is assembled into and, if its operand is a label, where that label
+// points.
+func (d *document) hover(pos Position) (*Hover, bool) {
+	i, tok, ok := d.tokenAt(pos)
+	if !ok {
+		return nil, false
+	}
+
+	instruction := d.instructions[i]
+	lines := []string{}
+
+	if d.mailboxes != nil {
+		val, _ := d.mailboxes.Get(i)
+		lines = append(lines, fmt.Sprintf("Mailbox `%0*d` contains `%s`", d.operandSize, i, val))
+	} else {
+		lines = append(lines, fmt.Sprintf("Mailbox `%0*d`", d.operandSize, i))
+	}
+
+	if instruction.OperandToken.Type == lmc.IDENT {
+		if j, ok := d.definition(instruction.Operand); ok {
+			lines = append(lines, fmt.Sprintf("Label `%s` is mailbox `%0*d`", instruction.Operand, d.operandSize, j))
+		}
+	}
+
+	r := tokenRange(tok)
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: strings.Join(lines, "\n\n")},
+		Range:    &r,
+	}, true
+}
+
+// labels returns the names of every label defined in the document.
+func (d *document) labels() []string {
+	names := []string{}
+
+	for _, instruction := range d.instructions {
+		if instruction.Label != "" {
+			names = append(names, instruction.Label)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,156 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// minLabelWidth is the narrowest the label column is allowed to be, so that programs without long labels still have
+// their mnemonics indented.
+const minLabelWidth = 8
+
+// line is a single line of a program split into its columns.
+type line struct {
+	label, mnemonic, operand string
+	comment                  string
+	code                     bool // false if the line isn't an instruction and should be left as it is.
+	raw                      string
+}
+
+// splitLine breaks a line of source into a label, mnemonic, operand and comment.
+func splitLine(raw string) line {
+	l := line{raw: strings.TrimRight(raw, " \t\r")}
+
+	fields, comment := scanLine(l.raw)
+	l.comment = comment
+
+	isMnemonic := func(s string) bool {
+		_, ok := lmc.DefaultMnemonicMap[s]
+		return ok
+	}
+
+	switch {
+	case len(fields) == 0:
+		return l
+	case len(fields) >= 2 && isMnemonic(fields[1]):
+		l.label, fields = fields[0], fields[1:]
+	case !isMnemonic(fields[0]):
+		return l
+	}
+
+	if len(fields) > 2 {
+		return l
+	}
+
+	l.mnemonic = fields[0]
+	if len(fields) == 2 {
+		l.operand = fields[1]
+	}
+
+	l.code = true
+	return l
+}
+
+// scanLine splits a line of source into whitespace separated fields and a comment starting with //. Strings in double
+// quotes are kept whole as a single field, so that spaces and slashes inside them aren't mistaken for the end of a
+// field or the start of a comment.
+func scanLine(raw string) ([]string, string) {
+	fields := []string{}
+	start := -1
+	quoted := false
+
+	for i := 0; i < len(raw); i++ {
+		ch := raw[i]
+
+		switch {
+		case quoted && ch == '\\':
+			i++
+			continue
+		case quoted:
+			quoted = ch != '"'
+			continue
+		case ch == '/' && strings.HasPrefix(raw[i:], "//"):
+			if start != -1 {
+				fields = append(fields, raw[start:i])
+			}
+
+			return fields, raw[i:]
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			if start != -1 {
+				fields = append(fields, raw[start:i])
+				start = -1
+			}
+
+			continue
+		case ch == '"':
+			quoted = true
+		}
+
+		if start == -1 {
+			start = i
+		}
+	}
+
+	if start != -1 {
+		fields = append(fields, raw[start:])
+	}
+
+	return fields, ""
+}
+
+// format lays a program out in columns: labels, then mnemonics and operands, then comments. Lines that aren't
+// recognisable instructions are left alone, apart from trailing whitespace being removed.
+func format(text string) string {
+	lines := []line{}
+	labelWidth := minLabelWidth
+
+	for _, raw := range strings.Split(text, "\n") {
+		l := splitLine(raw)
+		if l.code && len(l.label)+1 > labelWidth {
+			labelWidth = len(l.label) + 1
+		}
+
+		lines = append(lines, l)
+	}
+
+	codeWidth := 0
+	codes := make([]string, len(lines))
+	for i, l := range lines {
+		if !l.code {
+			continue
+		}
+
+		code := l.label + strings.Repeat(" ", labelWidth-len(l.label)) + l.mnemonic
+		if l.operand != "" {
+			code += " " + l.operand
+		}
+
+		codes[i] = code
+		if l.comment != "" && len(code) > codeWidth {
+			codeWidth = len(code)
+		}
+	}
+
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		switch {
+		case !l.code && l.comment != "" && strings.TrimSpace(l.raw) == l.comment:
+			out[i] = l.comment
+		case !l.code:
+			out[i] = l.raw
+		case l.comment != "":
+			out[i] = codes[i] + strings.Repeat(" ", codeWidth-len(codes[i])+1) + l.comment
+		default:
+			out[i] = codes[i]
+		}
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// endPosition returns the position just after the last character of some text.
+func endPosition(text string) Position {
+	lines := strings.Split(text, "\n")
+	return Position{len(lines) - 1, len(lines[len(lines)-1])}
+}
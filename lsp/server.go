@@ -0,0 +1,368 @@
+// Package lsp implements a Language Server Protocol server for LMC assembly, giving editors diagnostics, navigation,
+// hover information, completion, renaming and formatting for .lmc files.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/internal/wire"
+)
+
+// Server is a language server that communicates with a single client over a reader and writer, usually stdin and
+// stdout.
+type Server struct {
+	OpcodeSize  int
+	OperandSize int
+
+	in  *bufio.Reader
+	out io.Writer
+
+	documents map[string]*document
+	shutdown  bool
+}
+
+// NewServer returns a new server reading requests from in and writing responses to out. Programs are assembled
+// with a 1 digit opcode and 2 digit operand unless the sizes are changed before calling Serve.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		OpcodeSize:  1,
+		OperandSize: 2,
+		in:          bufio.NewReader(in),
+		out:         out,
+		documents:   make(map[string]*document),
+	}
+}
+
+// Serve handles messages until the client sends the exit notification or the input is closed.
+func (s *Server) Serve() error {
+	for {
+		body, err := wire.Read(s.in)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.replyError(json.RawMessage("null"), &rpcError{codeParseError, err.Error()}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(req)
+
+		if req.ID == nil {
+			// Notifications don't get a response, even if something went wrong.
+			continue
+		}
+
+		if err != nil {
+			rerr, ok := err.(*rpcError)
+			if !ok {
+				rerr = &rpcError{codeInvalidParams, err.Error()}
+			}
+
+			err = s.replyError(*req.ID, rerr)
+		} else {
+			err = wire.Write(s.out, response{"2.0", *req.ID, result})
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// replyError sends an error response for the request with the ID given.
+func (s *Server) replyError(id json.RawMessage, err *rpcError) error {
+	return wire.Write(s.out, errorResponse{"2.0", id, err})
+}
+
+// notify sends a notification to the client.
+func (s *Server) notify(method string, params interface{}) error {
+	return wire.Write(s.out, notification{"2.0", method, params})
+}
+
+// handle dispatches a request or notification to the method that implements it.
+func (s *Server) handle(req request) (interface{}, error) {
+	if s.shutdown && req.Method != "exit" {
+		return nil, &rpcError{codeInvalidRequest, "server is shutting down"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		return s.initialize()
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+
+		// The server asks for full document sync, so the last change holds the whole document.
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return nil, s.update(params.TextDocument.URI, text)
+
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		delete(s.documents, params.TextDocument.URI)
+		return nil, s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{params.TextDocument.URI, []Diagnostic{}})
+
+	case "textDocument/definition":
+		var params positionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return s.definition(params)
+
+	case "textDocument/references":
+		var params referenceParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return s.references(params)
+
+	case "textDocument/hover":
+		var params positionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return s.hover(params)
+
+	case "textDocument/completion":
+		var params positionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return s.completion(params)
+
+	case "textDocument/rename":
+		var params renameParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return s.rename(params)
+
+	case "textDocument/formatting":
+		var params formattingParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return s.formatting(params)
+	}
+
+	return nil, &rpcError{codeMethodNotFound, fmt.Sprintf("method not supported: %s", req.Method)}
+}
+
+// initialize tells the client which features the server supports.
+func (s *Server) initialize() (interface{}, error) {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":           syncFull,
+			"definitionProvider":         true,
+			"referencesProvider":         true,
+			"hoverProvider":              true,
+			"completionProvider":         map[string]interface{}{},
+			"renameProvider":             true,
+			"documentFormattingProvider": true,
+		},
+		"serverInfo": map[string]string{"name": "lmc"},
+	}, nil
+}
+
+// update re-analyses a document after it has been opened or changed and publishes its diagnostics.
+func (s *Server) update(uri, text string) error {
+	doc := newDocument(uri, text, s.OpcodeSize, s.OperandSize, s.documents[uri])
+	s.documents[uri] = doc
+
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{uri, doc.diagnostics})
+}
+
+// document returns an open document, or an error if the client never opened it.
+func (s *Server) document(uri string) (*document, error) {
+	doc, ok := s.documents[uri]
+	if !ok {
+		return nil, &rpcError{codeInvalidParams, fmt.Sprintf("document not open: %s", uri)}
+	}
+
+	return doc, nil
+}
+
+// definition finds where the label under the cursor is defined.
+func (s *Server) definition(params positionParams) (interface{}, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	label, ok := doc.labelAt(params.Position)
+	if !ok {
+		return nil, nil
+	}
+
+	i, ok := doc.definition(label)
+	if !ok {
+		return nil, nil
+	}
+
+	return Location{doc.uri, tokenRange(doc.instructions[i].LabelToken)}, nil
+}
+
+// references finds every use of the label under the cursor.
+func (s *Server) references(params referenceParams) (interface{}, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := []Location{}
+
+	label, ok := doc.labelAt(params.Position)
+	if !ok {
+		return locations, nil
+	}
+
+	for _, tok := range doc.references(label, params.Context.IncludeDeclaration) {
+		locations = append(locations, Location{doc.uri, tokenRange(tok)})
+	}
+
+	return locations, nil
+}
+
+// hover shows the assembled mailbox for the instruction under the cursor.
+func (s *Server) hover(params positionParams) (interface{}, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	hover, ok := doc.hover(params.Position)
+	if !ok {
+		return nil, nil
+	}
+
+	return hover, nil
+}
+
+// completion suggests every mnemonic and every label defined in the document.
+func (s *Server) completion(params positionParams) (interface{}, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []CompletionItem{}
+
+	mnemonics := []string{}
+	for mnemonic := range lmc.DefaultMnemonicMap {
+		mnemonics = append(mnemonics, mnemonic)
+	}
+	sort.Strings(mnemonics)
+
+	for _, mnemonic := range mnemonics {
+		detail := "data"
+		if opcode := lmc.DefaultMnemonicMap[mnemonic].Opcode; opcode != -1 {
+			detail = fmt.Sprintf("opcode %d", opcode)
+		}
+
+		items = append(items, CompletionItem{mnemonic, completionKeyword, detail})
+	}
+
+	for _, label := range doc.labels() {
+		i, _ := doc.definition(label)
+		items = append(items, CompletionItem{label, completionConstant, fmt.Sprintf("mailbox %0*d", s.OperandSize, i)})
+	}
+
+	return items, nil
+}
+
+// rename changes the name of the label under the cursor everywhere it is used.
+func (s *Server) rename(params renameParams) (interface{}, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	label, ok := doc.labelAt(params.Position)
+	if !ok {
+		return nil, &rpcError{codeInvalidParams, "no label at position"}
+	}
+
+	if !isLabel(params.NewName) {
+		return nil, &rpcError{codeInvalidParams, fmt.Sprintf("%q is not a valid label", params.NewName)}
+	}
+
+	edits := []TextEdit{}
+	for _, tok := range doc.references(label, true) {
+		edits = append(edits, TextEdit{tokenRange(tok), params.NewName})
+	}
+
+	return WorkspaceEdit{map[string][]TextEdit{doc.uri: edits}}, nil
+}
+
+// formatting lays out the whole document in columns.
+func (s *Server) formatting(params formattingParams) (interface{}, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted := format(doc.text)
+	if formatted == doc.text {
+		return []TextEdit{}, nil
+	}
+
+	return []TextEdit{{Range{Position{0, 0}, endPosition(doc.text)}, formatted}}, nil
+}
+
+// isLabel returns true if a string can be used as a label: it must lex as a single identifier and not be a
+// mnemonic.
+func isLabel(s string) bool {
+	if _, ok := lmc.DefaultMnemonicMap[s]; ok {
+		return false
+	}
+
+	lexer := lmc.NewLexer(s)
+	tok := lexer.Next()
+
+	return tok.Type == lmc.IDENT && tok.Literal == s && lexer.Next().Type == lmc.EOF
+}
@@ -0,0 +1,207 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/ollybritton/go-lmc/internal/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+const testURI = "file:///square.lmc"
+
+const testProgram = `        INP
+        STA VALUE
+LOOP    LDA VALUE
+        BRZ DONE
+        BRA LOOP
+DONE    HLT
+VALUE   DAT`
+
+// session sends a sequence of messages to a new server and returns every message it wrote back.
+func session(t *testing.T, msgs ...map[string]interface{}) []map[string]interface{} {
+	in := &bytes.Buffer{}
+	for _, msg := range msgs {
+		msg["jsonrpc"] = "2.0"
+		assert.NoError(t, wire.Write(in, msg))
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, NewServer(in, out).Serve())
+
+	replies := []map[string]interface{}{}
+	r := bufio.NewReader(out)
+	for r.Buffered() > 0 || out.Len() > 0 {
+		body, err := wire.Read(r)
+		if err != nil {
+			break
+		}
+
+		var reply map[string]interface{}
+		assert.NoError(t, json.Unmarshal(body, &reply))
+		replies = append(replies, reply)
+	}
+
+	return replies
+}
+
+func open(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"method": "textDocument/didOpen",
+		"params": map[string]interface{}{"textDocument": map[string]interface{}{"uri": testURI, "text": text}},
+	}
+}
+
+func at(id int, method string, line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"id":     id,
+		"method": method,
+		"params": map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": testURI},
+			"position":     map[string]interface{}{"line": line, "character": character},
+			"context":      map[string]interface{}{"includeDeclaration": true},
+			"newName":      "VAL",
+		},
+	}
+}
+
+// result returns the JSON encoding of the result of the reply to request id.
+func result(t *testing.T, replies []map[string]interface{}, id int) string {
+	for _, reply := range replies {
+		if reply["id"] == float64(id) {
+			bs, err := json.Marshal(reply["result"])
+			assert.NoError(t, err)
+			return string(bs)
+		}
+	}
+
+	t.Fatalf("no reply to request %d", id)
+	return ""
+}
+
+func TestServerDiagnostics(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+		line    int
+		start   int
+		end     int
+	}{
+		{"STA @", "illegal token @<ILLEGAL>(line=0,col=4-4) in input", 0, 4, 5},
+		{"INP\nFOO 10", "invalid mnemonic: FOO", 1, 0, 3},
+		{"BRA missing", "undefined label: missing", 0, 4, 11},
+		{"a DAT\na DAT", "label a is already defined", 1, 0, 1},
+		{"STA 100", "operand 100 does not fit in 2 digits", 0, 4, 7},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.message, func(t *testing.T) {
+			replies := session(t, open(tc.input))
+			assert.Len(t, replies, 1)
+
+			params := replies[0]["params"].(map[string]interface{})
+			diagnostics := params["diagnostics"].([]interface{})
+			assert.Len(t, diagnostics, 1)
+
+			diagnostic := diagnostics[0].(map[string]interface{})
+			assert.Equal(t, tc.message, diagnostic["message"])
+
+			r := diagnostic["range"].(map[string]interface{})
+			start, end := r["start"].(map[string]interface{}), r["end"].(map[string]interface{})
+			assert.Equal(t, float64(tc.line), start["line"])
+			assert.Equal(t, float64(tc.start), start["character"])
+			assert.Equal(t, float64(tc.end), end["character"])
+		})
+	}
+}
+
+func TestServerNavigation(t *testing.T) {
+	replies := session(t,
+		open(testProgram),
+		at(1, "textDocument/definition", 4, 12),
+		at(2, "textDocument/references", 6, 2),
+		at(3, "textDocument/hover", 2, 13),
+		at(4, "textDocument/rename", 1, 13),
+	)
+
+	assert.Equal(t,
+		`{"range":{"end":{"character":4,"line":2},"start":{"character":0,"line":2}},"uri":"file:///square.lmc"}`,
+		result(t, replies, 1),
+	)
+
+	var refs []Location
+	assert.NoError(t, json.Unmarshal([]byte(result(t, replies, 2)), &refs))
+	assert.Equal(t, []Location{
+		{testURI, Range{Position{1, 12}, Position{1, 17}}},
+		{testURI, Range{Position{2, 12}, Position{2, 17}}},
+		{testURI, Range{Position{6, 0}, Position{6, 5}}},
+	}, refs)
+
+	var hover Hover
+	assert.NoError(t, json.Unmarshal([]byte(result(t, replies, 3)), &hover))
+	assert.Equal(t, "Mailbox `02` contains `506`\n\nLabel `VALUE` is mailbox `06`", hover.Contents.Value)
+
+	var edit WorkspaceEdit
+	assert.NoError(t, json.Unmarshal([]byte(result(t, replies, 4)), &edit))
+	assert.Len(t, edit.Changes[testURI], 3)
+	for _, e := range edit.Changes[testURI] {
+		assert.Equal(t, "VAL", e.NewText)
+	}
+}
+
+func TestServerCompletion(t *testing.T) {
+	replies := session(t, open(testProgram), at(1, "textDocument/completion", 0, 0))
+
+	var items []CompletionItem
+	assert.NoError(t, json.Unmarshal([]byte(result(t, replies, 1)), &items))
+
+	labels := []string{}
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+
+	assert.Contains(t, labels, "BRZ")
+	assert.Contains(t, labels, "HLT")
+	assert.Contains(t, labels, "LOOP")
+	assert.Contains(t, labels, "VALUE")
+}
+
+func TestServerUnknownMethod(t *testing.T) {
+	replies := session(t, map[string]interface{}{"id": 1, "method": "workspace/symbol"})
+
+	assert.Len(t, replies, 1)
+	assert.Equal(t, float64(codeMethodNotFound), replies[0]["error"].(map[string]interface{})["code"])
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{
+			"INP\n  OUT\nHLT",
+			"        INP\n        OUT\n        HLT",
+		},
+		{
+			"loop INP   // read\nBRZ end // stop on zero\n  BRA loop\nend HLT",
+			"loop    INP     // read\n        BRZ end // stop on zero\n        BRA loop\nend     HLT",
+		},
+		{
+			"   // just a comment\nsomething odd here too many\ninputLoop INP",
+			"// just a comment\nsomething odd here too many\ninputLoop INP",
+		},
+		{
+			"msg DAT \"a//b\" // url\nsp  DAT \"x \\\" y\"\n  OTC",
+			"msg     DAT \"a//b\" // url\nsp      DAT \"x \\\" y\"\n        OTC",
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			assert.Equal(t, tc.output, format(tc.input))
+		})
+	}
+}
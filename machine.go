@@ -0,0 +1,403 @@
+package lmc
+
+import (
+	"context"
+	"strconv"
+)
+
+// Machine is a Little Man Computer for embedding in other programs. Unlike Computer, which is driven by exchanging
+// messages over channels, a machine is driven by calling its methods, and is set up with options given to
+// NewMachine:
+//
+//	m := lmc.NewMachine(lmc.WithMaxCycles(1000))
+//	if err := m.Load(source); err != nil {
+//		return err
+//	}
+//
+//	m.SetInput(5, 7)
+//	err := m.Run(ctx)
+//	fmt.Println(m.Outputs())
+//
+// A machine isn't safe to use from more than one goroutine at once.
+type Machine interface {
+	// Load assembles a program and loads it into memory, then resets the machine. The program is checked with
+	// Validate first, and the first problem found is returned.
+	Load(program string) error
+
+	// Reset puts the program that was loaded back into memory, clears the registers, inputs, outputs and cycle count,
+	// and resets the mapped devices that are Resetters, as if it had just been loaded. A machine that's no longer
+	// needed part way through a program should be reset, which stops the goroutine running it.
+	Reset()
+
+	// Step executes a single instruction. It returns ErrInputExhausted if the instruction needs input and there is
+	// none left, in which case it can be finished by calling Step again once SetInput has been called, and
+	// ErrCycleLimit if the machine has executed as many instructions as it's allowed to. Once the program has stopped,
+	// Step does nothing and returns the error it stopped with, if any.
+	Step() error
+
+	// Run executes instructions until the program halts, Step returns an error, or the context is done, in which
+	// case the context's error is returned and the machine is left where it was.
+	Run(ctx context.Context) error
+
+	// State returns the registers and memory of the machine.
+	State() State
+
+	// SetInput sets the values to be given to the program when it asks for input, replacing any that are left.
+	SetInput(inputs ...int)
+
+	// Outputs returns every value output by the program so far, in order. Characters output by OTC are given as their
+	// codes.
+	Outputs() []int
+
+	// Decode decodes the instruction in a mailbox, returning its definition and operand, or false if the mailbox
+	// doesn't hold an instruction.
+	Decode(addr int) (InstructionDef, int, bool)
+}
+
+// State is a snapshot of a machine.
+type State struct {
+	ProgramCounter int
+	Accumulator    int
+	StackPointer   int
+
+	MAR int
+	MDR int
+	CIR int
+
+	Cycles    int      // Number of instructions executed.
+	Halted    bool     // Whether the program has stopped, either at a HLT or with an error.
+	Mailboxes []string // Contents of every mailbox, with devices shown as they are without changing them.
+}
+
+// Option configures a machine created with NewMachine.
+type Option func(*machine)
+
+// WithInstructionSet sets the instruction set programs are assembled and run with. DefaultInstructionSet is used
+// otherwise.
+func WithInstructionSet(set *InstructionSet) Option {
+	return func(m *machine) {
+		m.set = set
+	}
+}
+
+// WithArithmetic sets what happens when the accumulator doesn't fit in a mailbox. ArithmeticUnbounded is used
+// otherwise.
+func WithArithmetic(arithmetic Arithmetic) Option {
+	return func(m *machine) {
+		m.arithmetic = arithmetic
+	}
+}
+
+// WithMemory sets the radix of the mailboxes and how many digits they have for an opcode and an operand, which
+// decides how many mailboxes there are. Mailboxes are decimal with a 1 digit opcode and a 2 digit operand otherwise.
+func WithMemory(radix, opcodeSize, operandSize int) Option {
+	return func(m *machine) {
+		m.radix, m.opcodeSize, m.operandSize = radix, opcodeSize, operandSize
+	}
+}
+
+// WithDevice maps a device into the mailboxes starting at addr each time a program is loaded. The same device is used
+// for every program, so if it keeps state between runs it should be a Resetter, which is reset along with the machine.
+func WithDevice(addr int, device Device) Option {
+	return func(m *machine) {
+		m.devices = append(m.devices, mapping{addr, device})
+	}
+}
+
+// WithObserver adds a function that is passed every message the computer sends while it runs, which can be used to
+// watch the program run. Observers are called from within Step and Run, while the computer waits.
+func WithObserver(observe func(Msg)) Option {
+	return func(m *machine) {
+		m.observers = append(m.observers, observe)
+	}
+}
+
+// WithInterrupts lets programs be interrupted, as configured by interrupts, and use the InterruptInstructions. The
+// interrupts given are shared by every program loaded. Whenever the machine is reset, raised interrupts are forgotten
+// and Masked is put back to what it was when the interrupts were given.
+func WithInterrupts(interrupts *Interrupts) Option {
+	return func(m *machine) {
+		m.interrupts = interrupts
+	}
+}
+
+// WithMicroStepping makes the computer send a MicroStep message to the observers after each phase of every
+// instruction, so that they can watch the MAR, MDR and CIR change.
+func WithMicroStepping() Option {
+	return func(m *machine) {
+		m.microStepping = true
+	}
+}
+
+// WithMaxCycles limits the number of instructions a program can execute before Step returns ErrCycleLimit.
+func WithMaxCycles(maxCycles int) Option {
+	return func(m *machine) {
+		m.maxCycles = maxCycles
+	}
+}
+
+// WithCoverage records which instructions the machine executes and which way its branches go.
+func WithCoverage(coverage *Coverage) Option {
+	return func(m *machine) {
+		m.coverage = coverage
+	}
+}
+
+// machine is a Machine that drives a Computer running in its own goroutine, started by the first Step.
+type machine struct {
+	set         *InstructionSet
+	arithmetic  Arithmetic
+	radix       int
+	opcodeSize  int
+	operandSize int
+	devices     []mapping
+	observers   []func(Msg)
+	maxCycles   int
+	coverage    *Coverage
+
+	interrupts    *Interrupts
+	microStepping bool
+
+	computer *Computer
+
+	inputs  []int
+	outputs []int
+
+	errs     chan error // Receives the error Run returns, nil if the computer isn't running.
+	awaiting bool       // Whether the computer is waiting for input.
+	halted   bool
+	err      error // The error the program stopped with.
+}
+
+// NewMachine returns a machine configured with the options given. A program needs to be loaded before it can run.
+func NewMachine(opts ...Option) Machine {
+	m := &machine{radix: 10, opcodeSize: 1, operandSize: 2}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.interrupts != nil {
+		m.set = instructionSet(m.set).WithInterrupts()
+	}
+
+	return m
+}
+
+// Load assembles a program and loads it into memory, then resets the machine.
+func (m *machine) Load(program string) error {
+	set := instructionSet(m.set)
+
+	parser := NewParser(NewLexer(program))
+	parser.InstructionSet = set
+
+	instructions, err := parser.Parse()
+	if err != nil {
+		return err
+	}
+
+	if errs := set.ValidateRadix(instructions, m.radix, m.opcodeSize, m.operandSize); len(errs) != 0 {
+		return errs[0]
+	}
+
+	mailboxes, _ := set.AssembleRadix(instructions, m.radix, m.opcodeSize, m.operandSize)
+	for _, mapped := range m.devices {
+		if err := mailboxes.Map(mapped.addr, mapped.device); err != nil {
+			return err
+		}
+	}
+
+	m.stop()
+	if m.computer == nil {
+		m.computer = m.newComputer(mailboxes)
+	} else {
+		m.computer.Reload(mailboxes)
+	}
+
+	m.Reset()
+
+	return nil
+}
+
+// newComputer returns a computer configured with the machine's options.
+func (m *machine) newComputer(mailboxes *Mailboxes) *Computer {
+	c := NewComputerFromMailboxes(mailboxes, m.opcodeSize, m.operandSize)
+	c.InstructionSet = m.set
+	c.Arithmetic = m.arithmetic
+	c.Coverage = m.coverage
+	c.Interrupts = m.interrupts
+	c.MicroStepping = m.microStepping
+
+	return c
+}
+
+// Reset puts the program back into memory and clears everything else.
+func (m *machine) Reset() {
+	m.stop()
+
+	if m.computer != nil {
+		m.computer.Reset()
+	}
+
+	m.inputs = nil
+	m.outputs = []int{}
+	m.halted = false
+	m.err = nil
+}
+
+// stop halts the computer if it's running.
+func (m *machine) stop() {
+	if m.errs != nil {
+		m.computer.Halt()
+		<-m.errs
+		m.errs = nil
+	}
+
+	m.awaiting = false
+}
+
+// Step executes a single instruction.
+func (m *machine) Step() error {
+	if m.computer == nil {
+		return ErrNoProgram{}
+	}
+
+	if m.halted {
+		return m.err
+	}
+
+	c := m.computer
+
+	if m.errs == nil {
+		m.errs = make(chan error, 1)
+		go func() {
+			m.errs <- c.Run()
+		}()
+
+		if err := m.advance(); err != nil {
+			return err
+		}
+	}
+
+	if m.awaiting {
+		if len(m.inputs) == 0 {
+			return ErrInputExhausted{len(m.outputs)}
+		}
+
+		m.awaiting = false
+		c.Inbox <- m.inputs[0]
+		m.inputs = m.inputs[1:]
+
+		return m.advance()
+	}
+
+	if m.maxCycles > 0 && c.Cycles >= m.maxCycles {
+		return ErrCycleLimit{m.maxCycles}
+	}
+
+	c.Step <- struct{}{}
+	return m.advance()
+}
+
+// advance handles the messages from the computer until it's ready to execute another instruction, it needs input
+// that hasn't been given, or it stops.
+func (m *machine) advance() error {
+	c := m.computer
+
+	for {
+		select {
+		case msg := <-c.Messages:
+			for _, observe := range m.observers {
+				observe(msg)
+			}
+
+			switch msg.Status {
+			case NeedStep:
+				return nil
+
+			case MicroStep:
+				c.Step <- struct{}{}
+
+			case NeedInput:
+				if len(m.inputs) == 0 {
+					m.awaiting = true
+					return ErrInputExhausted{len(m.outputs)}
+				}
+
+				c.Inbox <- m.inputs[0]
+				m.inputs = m.inputs[1:]
+
+			case Output, OutputChar:
+				val, err := strconv.Atoi(msg.Val)
+				if err != nil {
+					return err
+				}
+
+				m.outputs = append(m.outputs, val)
+			}
+
+		case err := <-m.errs:
+			m.errs = nil
+			m.halted = true
+			m.err = err
+			return err
+		}
+	}
+}
+
+// Run executes instructions until the program stops.
+func (m *machine) Run(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := m.Step(); err != nil || m.halted {
+			return err
+		}
+	}
+}
+
+// State returns the registers and memory of the machine.
+func (m *machine) State() State {
+	c := m.computer
+	if c == nil {
+		return State{}
+	}
+
+	mailboxes := []string{}
+	for addr := 0; addr < c.Mailboxes.Len(); addr++ {
+		val, _ := c.Mailboxes.Peek(addr)
+		mailboxes = append(mailboxes, val)
+	}
+
+	return State{
+		ProgramCounter: c.ProgramCounter,
+		Accumulator:    c.Accumulator,
+		StackPointer:   c.StackPointer,
+		MAR:            c.MAR,
+		MDR:            c.MDR,
+		CIR:            c.CIR,
+		Cycles:         c.Cycles,
+		Halted:         m.halted,
+		Mailboxes:      mailboxes,
+	}
+}
+
+// SetInput sets the values to be given to the program when it asks for input.
+func (m *machine) SetInput(inputs ...int) {
+	m.inputs = append([]int{}, inputs...)
+}
+
+// Outputs returns every value output by the program so far.
+func (m *machine) Outputs() []int {
+	return append([]int{}, m.outputs...)
+}
+
+// Decode decodes the instruction in a mailbox.
+func (m *machine) Decode(addr int) (InstructionDef, int, bool) {
+	if m.computer == nil {
+		return InstructionDef{}, 0, false
+	}
+
+	return m.computer.Decode(addr)
+}
@@ -0,0 +1,152 @@
+package lmc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+// sum is a program that outputs the sum of two inputs and stores it in mailbox 9.
+const sum = `
+	INP
+	STA 9
+	INP
+	ADD 9
+	STA 9
+	OUT
+	HLT`
+
+func TestMachineRun(t *testing.T) {
+	m := lmc.NewMachine()
+	assert.NoError(t, m.Load(sum))
+
+	m.SetInput(5, 7)
+	assert.NoError(t, m.Run(context.Background()))
+	assert.Equal(t, []int{12}, m.Outputs())
+
+	state := m.State()
+	assert.True(t, state.Halted)
+	assert.Equal(t, 7, state.Cycles)
+	assert.Equal(t, 12, state.Accumulator)
+	assert.Equal(t, "012", state.Mailboxes[9])
+	assert.NoError(t, m.Step(), "expect stepping a halted machine to do nothing")
+
+	m.Reset()
+	state = m.State()
+	assert.False(t, state.Halted)
+	assert.Equal(t, 0, state.Cycles)
+	assert.Equal(t, 0, state.Accumulator)
+	assert.Equal(t, "000", state.Mailboxes[9], "expect the program to be put back")
+	assert.Equal(t, []int{}, m.Outputs())
+
+	m.SetInput(1, 2)
+	assert.NoError(t, m.Run(context.Background()))
+	assert.Equal(t, []int{3}, m.Outputs())
+}
+
+func TestMachineStep(t *testing.T) {
+	m := lmc.NewMachine()
+	assert.Equal(t, lmc.ErrNoProgram{}, m.Step())
+	assert.NoError(t, m.Load(sum))
+
+	assert.Equal(t, lmc.ErrInputExhausted{}, m.Step())
+	m.SetInput(5)
+	assert.NoError(t, m.Step(), "expect the input to finish the instruction")
+	assert.Equal(t, 5, m.State().Accumulator)
+	assert.Equal(t, 1, m.State().ProgramCounter)
+
+	assert.NoError(t, m.Step())
+	assert.Equal(t, lmc.ErrInputExhausted{}, m.Run(context.Background()))
+	assert.False(t, m.State().Halted)
+
+	m.SetInput(8)
+	assert.NoError(t, m.Run(context.Background()))
+	assert.Equal(t, []int{13}, m.Outputs())
+}
+
+func TestMachineOptions(t *testing.T) {
+	messages := 0
+	m := lmc.NewMachine(
+		lmc.WithInstructionSet(lmc.ExtendedInstructionSet),
+		lmc.WithMemory(10, 2, 2),
+		lmc.WithMaxCycles(3),
+		lmc.WithObserver(func(lmc.Msg) { messages++ }),
+	)
+
+	assert.NoError(t, m.Load("LDA 10\nMUL 10\nOUT\nHLT\nDAT 0\nDAT 0\nDAT 0\nDAT 0\nDAT 0\nDAT 0\nDAT 12"))
+	assert.Equal(t, lmc.ErrCycleLimit{Limit: 3}, m.Run(context.Background()))
+	assert.Equal(t, []int{144}, m.Outputs())
+	assert.Len(t, m.State().Mailboxes, 10000)
+	assert.True(t, messages > 0)
+
+	_, undefined := m.Load("BRA nowhere").(lmc.ErrUndefinedLabel)
+	assert.True(t, undefined)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m = lmc.NewMachine()
+	assert.NoError(t, m.Load("BRA 0"))
+	assert.Equal(t, context.Canceled, m.Run(ctx))
+}
+
+func TestMachineInterruptsAndMicroStepping(t *testing.T) {
+	phases := []string{}
+	m := lmc.NewMachine(
+		lmc.WithInterrupts(&lmc.Interrupts{Vector: 3, SavePC: 98, SaveAcc: 99, Schedule: []int{1}}),
+		lmc.WithMicroStepping(),
+		lmc.WithObserver(func(msg lmc.Msg) {
+			if msg.Status == lmc.MicroStep {
+				phases = append(phases, msg.Val)
+			}
+		}),
+	)
+
+	assert.NoError(t, m.Load("LDA 5\nOUT\nHLT\nOUT\nRTI\nDAT 7"))
+	assert.NoError(t, m.Run(context.Background()))
+	assert.Equal(t, []int{7, 7}, m.Outputs(), "expect the handler to output before the program does")
+	assert.Equal(t, []string{"fetch", "decode", "operand-fetch", "execute"}, phases[:4])
+
+	def, operand, ok := m.Decode(0)
+	assert.True(t, ok)
+	assert.Equal(t, "LDA", def.Mnemonic)
+	assert.Equal(t, 5, operand)
+}
+
+// overflow is a program that outputs 999 + 1 and 0 - 999 - 1, neither of which fit in a mailbox.
+const overflow = `
+	LDA big
+	ADD one
+	OUT
+	LDA zero
+	SUB big
+	SUB one
+	OUT
+	HLT
+big		DAT 999
+one		DAT 1
+zero	DAT 0`
+
+func TestMachineArithmetic(t *testing.T) {
+	tests := []struct {
+		arithmetic lmc.Arithmetic
+		outputs    []int
+		err        error
+	}{
+		{lmc.ArithmeticUnbounded, []int{1000, -1000}, nil},
+		{lmc.ArithmeticWrap, []int{0, 0}, nil},
+		{lmc.ArithmeticChecked, []int{}, lmc.ErrAccumulatorRange{Addr: 1, Value: 1000}},
+	}
+
+	for _, tc := range tests {
+		t.Run(string(tc.arithmetic), func(t *testing.T) {
+			m := lmc.NewMachine(lmc.WithArithmetic(tc.arithmetic))
+			assert.NoError(t, m.Load(overflow))
+
+			assert.Equal(t, tc.err, m.Run(context.Background()))
+			assert.Equal(t, tc.outputs, m.Outputs())
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package lmc
+
+import "fmt"
+
+// Phase is one of the stages a computer goes through to execute an instruction, as the fetch-decode-execute cycle is
+// usually taught. Fetch copies the program counter to the MAR, reads the mailbox it points to into the MDR and copies
+// that to the CIR. Decode splits the CIR into an instruction code and an operand. Operand fetch copies an address to
+// the MAR and reads the mailbox it points to into the MDR, for instructions like ADD and LDA that need a value from
+// memory. Execute carries out the instruction, changing the accumulator or program counter. Write-back copies a value
+// to the MDR and writes it to the mailbox the MAR points to, for instructions like STA.
+//
+// Instructions only go through the phases they need, and one that reads or writes more than one mailbox goes through
+// operand fetch or write-back more than once. The program counter is moved on to the next instruction once the
+// instruction has been carried out rather than during fetch, so that branches and CALL see the address of the
+// instruction being executed.
+type Phase string
+
+// Phase definitions
+const (
+	PhaseFetch        Phase = "fetch"
+	PhaseDecode       Phase = "decode"
+	PhaseOperandFetch Phase = "operand-fetch"
+	PhaseExecute      Phase = "execute"
+	PhaseWriteBack    Phase = "write-back"
+)
+
+// phase finishes a phase of the current instruction. In micro-step mode it tells the user of the computer which phase
+// was finished and waits for a value on the Step channel before the next one.
+func (c *Computer) phase(p Phase) error {
+	if !c.MicroStepping {
+		return nil
+	}
+
+	c.send(Msg{MicroStep, string(p)})
+	return c.wait()
+}
+
+// execute finishes the execute phase of the current instruction, if it hasn't been already.
+func (c *Computer) execute() error {
+	if !c.executing {
+		return nil
+	}
+
+	c.executing = false
+	return c.phase(PhaseExecute)
+}
+
+// Registers describes the registers of a computer, as shown after each phase in micro-step mode.
+func (c *Computer) Registers() string {
+	return fmt.Sprintf(
+		"PC=%d ACC=%d MAR=%d MDR=%d CIR=%s",
+		c.ProgramCounter, c.Accumulator, c.MAR, c.MDR, c.Mailboxes.format(c.CIR),
+	)
+}
@@ -0,0 +1,64 @@
+package lmc_test
+
+import (
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputerMicroStep(t *testing.T) {
+	computer, err := lmc.NewComputerFromCode("LDA 3\nSTA 4\nHLT\nDAT 7", 1, 2)
+	assert.NoError(t, err)
+	computer.MicroStepping = true
+
+	type step struct {
+		phase         lmc.Phase
+		mar, mdr, cir int
+	}
+
+	steps := []step{}
+	result, err := computer.RunWithInputs(nil, 0, func(msg lmc.Msg) {
+		if msg.Status == lmc.MicroStep {
+			steps = append(steps, step{lmc.Phase(msg.Val), computer.MAR, computer.MDR, computer.CIR})
+		}
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Cycles)
+	assert.Equal(t, []step{
+		{lmc.PhaseFetch, 0, 503, 503},
+		{lmc.PhaseDecode, 0, 503, 503},
+		{lmc.PhaseOperandFetch, 3, 7, 503},
+		{lmc.PhaseExecute, 3, 7, 503},
+		{lmc.PhaseFetch, 1, 304, 304},
+		{lmc.PhaseDecode, 1, 304, 304},
+		{lmc.PhaseExecute, 1, 304, 304},
+		{lmc.PhaseWriteBack, 4, 7, 304},
+		{lmc.PhaseFetch, 2, 0, 0},
+		{lmc.PhaseDecode, 2, 0, 0},
+		{lmc.PhaseExecute, 2, 0, 0},
+	}, steps)
+
+	val, _ := computer.Mailboxes.Get(4)
+	assert.Equal(t, "007", val)
+}
+
+func TestComputerMicroStepBranch(t *testing.T) {
+	computer, err := lmc.NewComputerFromCode("BRA 2\nHLT\nHLT", 1, 2)
+	assert.NoError(t, err)
+	computer.MicroStepping = true
+
+	phases := []string{}
+	pcs := []int{}
+	_, err = computer.RunWithInputs(nil, 0, func(msg lmc.Msg) {
+		if msg.Status == lmc.MicroStep {
+			phases = append(phases, msg.Val)
+			pcs = append(pcs, computer.ProgramCounter)
+		}
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fetch", "decode", "execute", "fetch", "decode", "execute"}, phases)
+	assert.Equal(t, []int{0, 0, 2, 2, 2, 2}, pcs, "expect the branch to be taken in the execute phase")
+}
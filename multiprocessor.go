@@ -0,0 +1,436 @@
+package lmc
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Multiprocessor is several computers sharing the same mailboxes. Only one of them executes an instruction at a time,
+// and the Scheduler decides which, so that a run can be repeated exactly. This makes it possible to show how programs
+// sharing memory can race, and how a lock built on TAS from the extended instruction set prevents it.
+//
+// Each processor has its own program counter, accumulator, stack pointer and inputs and outputs. Processors that use
+// the stack should be given stack pointers that don't overlap, since they all start at the top of memory.
+type Multiprocessor struct {
+	Mailboxes  *Mailboxes
+	Processors []*Computer
+
+	// Scheduler decides which processor executes each instruction. If it's nil, processors take turns.
+	Scheduler Scheduler
+}
+
+// NewMultiprocessor returns a multiprocessor with a processor for each of the entry points given, which is the
+// address the processor starts executing from.
+func NewMultiprocessor(mailboxes *Mailboxes, entries []int, inSize, opSize int) *Multiprocessor {
+	m := &Multiprocessor{Mailboxes: mailboxes}
+
+	for _, entry := range entries {
+		c := NewComputerFromMailboxes(mailboxes, inSize, opSize)
+		c.ProgramCounter = entry
+		m.Processors = append(m.Processors, c)
+	}
+
+	return m
+}
+
+// Scheduler decides which processor in a multiprocessor executes the next instruction.
+type Scheduler interface {
+	// Next returns the processor to step, given the indexes of the processors that are ready to execute an
+	// instruction. ready is in ascending order and never empty.
+	Next(ready []int) int
+}
+
+// RoundRobin is a scheduler where processors take turns executing one instruction each.
+type RoundRobin struct {
+	next int
+}
+
+// Next returns the first ready processor after the one that was last stepped.
+func (r *RoundRobin) Next(ready []int) int {
+	choice := ready[0]
+	for _, p := range ready {
+		if p >= r.next {
+			choice = p
+			break
+		}
+	}
+
+	r.next = choice + 1
+	return choice
+}
+
+// RandomScheduler is a scheduler that steps a random ready processor each time. Runs with the same seed are the same.
+type RandomScheduler struct {
+	rand *rand.Rand
+}
+
+// NewRandomScheduler returns a random scheduler using the seed given.
+func NewRandomScheduler(seed int64) *RandomScheduler {
+	return &RandomScheduler{rand: rand.New(rand.NewSource(seed))}
+}
+
+// Next returns a random ready processor.
+func (r *RandomScheduler) Next(ready []int) int {
+	return ready[r.rand.Intn(len(ready))]
+}
+
+// ScriptedScheduler is a scheduler that steps processors in the order given, such as the Schedule of a previous run.
+// Entries for processors that aren't ready are skipped, and once the order runs out processors take turns.
+type ScriptedScheduler struct {
+	Order []int
+
+	step       int
+	roundRobin RoundRobin
+}
+
+// Next returns the next ready processor in the order.
+func (s *ScriptedScheduler) Next(ready []int) int {
+	for s.step < len(s.Order) {
+		p := s.Order[s.step]
+		s.step++
+
+		for _, r := range ready {
+			if r == p {
+				s.roundRobin.next = p + 1
+				return p
+			}
+		}
+	}
+
+	return s.roundRobin.Next(ready)
+}
+
+// MultiprocessorResult is the result of running a multiprocessor.
+type MultiprocessorResult struct {
+	Results  []Result // The result of each processor.
+	Schedule []int    // The processor that executed each instruction, in order.
+}
+
+// RunWithInputs runs every processor until they've all halted, giving each the inputs at its index and collecting
+// what they output. If maxCycles is greater than zero, the processors are halted with ErrCycleLimit once that many
+// instructions have been executed between them. A processor running out of input or stopping with an error halts the
+// rest, and the error is returned as an ErrProcessor along with the result so far.
+func (m *Multiprocessor) RunWithInputs(inputs [][]int, maxCycles int) (MultiprocessorResult, error) {
+	return m.run(inputs, maxCycles, nil)
+}
+
+// run runs the multiprocessor as RunWithInputs does. If visit is given, it's called with a description of the state of
+// the multiprocessor before each instruction is scheduled, and if it returns false the processors are halted and
+// ErrHalted is returned.
+func (m *Multiprocessor) run(
+	inputs [][]int, maxCycles int, visit func(state string) bool,
+) (MultiprocessorResult, error) {
+	scheduler := m.Scheduler
+	if scheduler == nil {
+		scheduler = &RoundRobin{}
+	}
+
+	result := MultiprocessorResult{Results: make([]Result, len(m.Processors)), Schedule: []int{}}
+	queues := make([][]int, len(m.Processors))
+	errs := make([]chan error, len(m.Processors))
+	running := make([]bool, len(m.Processors))
+	written := map[int]bool{}
+
+	for i, c := range m.Processors {
+		result.Results[i].Outputs = []int{}
+		if i < len(inputs) {
+			queues[i] = inputs[i]
+		}
+
+		errs[i] = make(chan error, 1)
+		running[i] = true
+
+		go func(c *Computer, errs chan error) {
+			errs <- c.Run()
+		}(c, errs[i])
+	}
+
+	halt := func(err error) (MultiprocessorResult, error) {
+		for i, c := range m.Processors {
+			if running[i] {
+				c.Halt()
+				<-errs[i]
+			}
+		}
+
+		return result, err
+	}
+
+	// advance handles the messages from a processor until it's ready to execute another instruction or it stops.
+	advance := func(i int) error {
+		c := m.Processors[i]
+
+		for {
+			select {
+			case msg := <-c.Messages:
+				switch msg.Status {
+				case NeedStep:
+					return nil
+
+				case NeedInput:
+					if len(queues[i]) == 0 {
+						return ErrInputExhausted{len(result.Results[i].Outputs)}
+					}
+
+					c.Inbox <- queues[i][0]
+					queues[i] = queues[i][1:]
+
+				case Output, OutputChar:
+					val, err := strconv.Atoi(msg.Val)
+					if err != nil {
+						return err
+					}
+
+					result.Results[i].Outputs = append(result.Results[i].Outputs, val)
+
+				case MemoryWrite:
+					addr, err := strconv.Atoi(msg.Val)
+					if err != nil {
+						return err
+					}
+
+					written[addr] = true
+				}
+
+			case err := <-errs[i]:
+				running[i] = false
+				return err
+			}
+		}
+	}
+
+	for i := range m.Processors {
+		if err := advance(i); err != nil {
+			return halt(ErrProcessor{i, err})
+		}
+	}
+
+	for {
+		ready := []int{}
+		for i := range m.Processors {
+			if running[i] {
+				ready = append(ready, i)
+			}
+		}
+
+		if len(ready) == 0 {
+			return result, nil
+		}
+
+		if maxCycles > 0 && len(result.Schedule) >= maxCycles {
+			return halt(ErrCycleLimit{maxCycles})
+		}
+
+		if visit != nil && !visit(m.state(result, queues, running, written)) {
+			return halt(ErrHalted{})
+		}
+
+		p := scheduler.Next(ready)
+		result.Schedule = append(result.Schedule, p)
+		result.Results[p].Cycles++
+		m.Processors[p].Step <- struct{}{}
+
+		if err := advance(p); err != nil {
+			return halt(ErrProcessor{p, err})
+		}
+	}
+}
+
+// state describes everything that decides how a run will continue from the point it's at: the registers, inputs and
+// outputs of every processor, and the contents of the mailboxes that have been written to.
+func (m *Multiprocessor) state(
+	result MultiprocessorResult, queues [][]int, running []bool, written map[int]bool,
+) string {
+	var b strings.Builder
+
+	for i, c := range m.Processors {
+		fmt.Fprintf(
+			&b, "%t %d %d %d %d %v;",
+			running[i], c.ProgramCounter, c.Accumulator, c.StackPointer, len(queues[i]), result.Results[i].Outputs,
+		)
+	}
+
+	addrs := []int{}
+	for addr := range written {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+
+	for _, addr := range addrs {
+		val, _ := m.Mailboxes.Peek(addr)
+		fmt.Fprintf(&b, "%d=%s;", addr, val)
+	}
+
+	return b.String()
+}
+
+// Outcome is one of the ways a multiprocessor's run can end, found by Explore.
+type Outcome struct {
+	Outputs  [][]int // What each processor output.
+	Err      error   // The error the run stopped with, if any.
+	Schedule []int   // The first interleaving found that ends this way, which can be given to a ScriptedScheduler.
+
+	// Count is the number of runs explored that end this way. Runs that reach a state an earlier run has been through
+	// are cut short and not counted, so this counts the distinct paths explored rather than every interleaving.
+	Count int
+}
+
+// explorer is a scheduler that follows a prefix, then always steps the lowest ready processor, recording every
+// choice it makes so that the next interleaving can be found.
+type explorer struct {
+	prefix  []int
+	choices []choice
+}
+
+// choice is a point in a run where the scheduler chose between processors.
+type choice struct {
+	ready  []int
+	picked int // Index into ready of the processor stepped.
+}
+
+// Next returns the processor given by the prefix, or the lowest ready processor once it has run out.
+func (e *explorer) Next(ready []int) int {
+	picked := 0
+	if step := len(e.choices); step < len(e.prefix) {
+		for i, p := range ready {
+			if p == e.prefix[step] {
+				picked = i
+			}
+		}
+	}
+
+	e.choices = append(e.choices, choice{append([]int{}, ready...), picked})
+	return ready[picked]
+}
+
+// next returns the prefix for the interleaving after the one that was just run, or false if there are no more.
+func (e *explorer) next() ([]int, bool) {
+	for step := len(e.choices) - 1; step >= 0; step-- {
+		c := e.choices[step]
+		if c.picked+1 < len(c.ready) {
+			prefix := []int{}
+			for _, earlier := range e.choices[:step] {
+				prefix = append(prefix, earlier.ready[earlier.picked])
+			}
+
+			return append(prefix, c.ready[c.picked+1]), true
+		}
+	}
+
+	return nil, false
+}
+
+// Explore runs the multiprocessor under every possible interleaving of its processors' instructions and returns the
+// distinct ways the runs end, in the order they're found. A program with a race has more than one outcome. Each run
+// starts from the current state of the mailboxes and processors, which are left unchanged.
+//
+// A run that reaches a state an earlier run has already been through is cut short, since it can only end in the ways
+// already found. This keeps processors spinning on a lock from being explored forever, but it also means a program
+// that can get stuck spinning isn't reported as an outcome. Even so, the number of interleavings grows very quickly
+// with the length of the programs, so this is only practical for small ones. Each run is limited to maxCycles
+// instructions if it's greater than zero, in which case states are only the same if they're reached after the same
+// number of instructions, since how a run ends then depends on how many it has left. If more than maxRuns runs would
+// be needed the outcomes found so far are returned along with ErrRunLimit. Devices are shared between runs rather
+// than reset, so shouldn't be mapped into the mailboxes.
+func (m *Multiprocessor) Explore(inputs [][]int, maxCycles, maxRuns int) ([]Outcome, error) {
+	outcomes := []Outcome{}
+	index := map[string]int{}
+	seen := map[string]bool{}
+
+	prefix := []int{}
+	for runs := 0; ; runs++ {
+		if maxRuns > 0 && runs >= maxRuns {
+			return outcomes, ErrRunLimit{maxRuns}
+		}
+
+		e := &explorer{prefix: prefix}
+		run := m.copy()
+		run.Scheduler = e
+
+		result, err := run.run(inputs, maxCycles, func(state string) bool {
+			if len(e.choices) < len(prefix) {
+				return true // Replaying the prefix, whose states have been seen before.
+			}
+
+			// With a cycle limit, how a run ends also depends on how many instructions are left before the limit.
+			if maxCycles > 0 {
+				state = strconv.Itoa(len(e.choices)) + " " + state
+			}
+
+			if seen[state] {
+				return false
+			}
+
+			seen[state] = true
+			return true
+		})
+
+		if err == (ErrHalted{}) {
+			var ok bool
+			if prefix, ok = e.next(); !ok {
+				return outcomes, nil
+			}
+
+			continue
+		}
+
+		outputs := [][]int{}
+		for _, r := range result.Results {
+			outputs = append(outputs, r.Outputs)
+		}
+
+		key := fmt.Sprint(outputs)
+		if err != nil {
+			key += err.Error()
+		}
+
+		if i, ok := index[key]; ok {
+			outcomes[i].Count++
+		} else {
+			index[key] = len(outcomes)
+			outcomes = append(outcomes, Outcome{outputs, err, result.Schedule, 1})
+		}
+
+		var ok bool
+		if prefix, ok = e.next(); !ok {
+			return outcomes, nil
+		}
+	}
+}
+
+// copy returns a multiprocessor with copies of the mailboxes and processors, ready to be run. Each processor is copied
+// with its registers and every setting, so that it runs the same way as the original would. Coverage is shared, so
+// that it covers every run.
+func (m *Multiprocessor) copy() *Multiprocessor {
+	mailboxes := &Mailboxes{
+		mem:     append([]string{}, m.Mailboxes.mem...),
+		width:   m.Mailboxes.width,
+		radix:   m.Mailboxes.radix,
+		devices: m.Mailboxes.devices,
+	}
+
+	run := &Multiprocessor{Mailboxes: mailboxes}
+	for _, p := range m.Processors {
+		c := NewComputerFromMailboxes(mailboxes, p.InstructionSize, p.OperandSize)
+		c.ProgramCounter = p.ProgramCounter
+		c.Accumulator = p.Accumulator
+		c.StackPointer = p.StackPointer
+		c.MAR, c.MDR, c.CIR = p.MAR, p.MDR, p.CIR
+		c.Cycles = p.Cycles
+		c.InstructionSet = p.InstructionSet
+		c.Arithmetic = p.Arithmetic
+		c.Coverage = p.Coverage
+		c.MicroStepping = p.MicroStepping
+
+		if p.Interrupts != nil {
+			c.Interrupts = p.Interrupts.copy()
+		}
+
+		run.Processors = append(run.Processors, c)
+	}
+
+	return run
+}
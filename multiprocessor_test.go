@@ -0,0 +1,182 @@
+package lmc_test
+
+import (
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+// increment is a program that increments a shared count without a lock, then outputs the count it stored.
+const increment = `
+		LDA count
+		ADD one
+		STA count
+		OUT
+		HLT
+count	DAT 0
+one		DAT 1`
+
+// lockedIncrement is increment with the count protected by a lock taken with TAS.
+const lockedIncrement = `
+lock	TAS flag
+		BRZ crit
+		BRA lock
+crit	LDA count
+		ADD one
+		STA count
+		OUT
+		LDA zero
+		STA flag
+		HLT
+flag	DAT 0
+count	DAT 0
+one		DAT 1
+zero	DAT 0`
+
+// multiprocessor returns a multiprocessor with two processors running the code given from the start.
+func multiprocessor(t *testing.T, code string, set *lmc.InstructionSet, opcodeSize int) *lmc.Multiprocessor {
+	parser := lmc.NewParser(lmc.NewLexer(code))
+	parser.InstructionSet = set
+
+	instructions, err := parser.Parse()
+	assert.NoError(t, err)
+
+	m := lmc.NewMultiprocessor(set.Assemble(instructions, opcodeSize, 2), []int{0, 0}, opcodeSize, 2)
+	for _, p := range m.Processors {
+		p.InstructionSet = set
+	}
+
+	return m
+}
+
+func TestMultiprocessorSchedulers(t *testing.T) {
+	tests := []struct {
+		name      string
+		scheduler lmc.Scheduler
+		outputs   [][]int
+		schedule  []int
+	}{
+		{"round-robin", &lmc.RoundRobin{}, [][]int{{1}, {1}}, []int{0, 1, 0, 1, 0, 1, 0, 1, 0, 1}},
+		{"scripted", &lmc.ScriptedScheduler{Order: []int{1, 1, 1, 1, 1, 0}}, [][]int{{2}, {1}}, []int{1, 1, 1, 1, 1, 0, 0, 0, 0, 0}},
+		{"scripted-skips", &lmc.ScriptedScheduler{Order: []int{0, 0, 0, 0, 0, 0, 0, 1}}, [][]int{{1}, {2}}, []int{0, 0, 0, 0, 0, 1, 1, 1, 1, 1}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := multiprocessor(t, increment, lmc.DefaultInstructionSet, 1)
+			m.Scheduler = tc.scheduler
+
+			result, err := m.RunWithInputs(nil, 100)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.outputs, [][]int{result.Results[0].Outputs, result.Results[1].Outputs})
+			assert.Equal(t, tc.schedule, result.Schedule)
+		})
+	}
+}
+
+func TestMultiprocessorRandom(t *testing.T) {
+	run := func(seed int64) []int {
+		m := multiprocessor(t, increment, lmc.DefaultInstructionSet, 1)
+		m.Scheduler = lmc.NewRandomScheduler(seed)
+
+		result, err := m.RunWithInputs(nil, 100)
+		assert.NoError(t, err)
+		return result.Schedule
+	}
+
+	assert.Equal(t, run(7), run(7), "expect runs with the same seed to be the same")
+}
+
+func TestMultiprocessorErrors(t *testing.T) {
+	m := multiprocessor(t, "INP\nHLT", lmc.DefaultInstructionSet, 1)
+
+	_, err := m.RunWithInputs([][]int{{1}}, 100)
+	assert.Equal(t, lmc.ErrProcessor{Processor: 1, Err: lmc.ErrInputExhausted{}}, err)
+
+	m = multiprocessor(t, "BRA 0", lmc.DefaultInstructionSet, 1)
+	_, err = m.RunWithInputs(nil, 10)
+	assert.Equal(t, lmc.ErrCycleLimit{Limit: 10}, err)
+}
+
+func TestMultiprocessorExplore(t *testing.T) {
+	m := multiprocessor(t, increment, lmc.DefaultInstructionSet, 1)
+
+	outcomes, err := m.Explore(nil, 100, 0)
+	assert.NoError(t, err)
+
+	outputs := [][][]int{}
+	for _, outcome := range outcomes {
+		outputs = append(outputs, outcome.Outputs)
+	}
+	assert.ElementsMatch(t, [][][]int{{{1}, {1}}, {{1}, {2}}, {{2}, {1}}}, outputs, "expect the lost update to be found")
+
+	for _, outcome := range outcomes {
+		replay := multiprocessor(t, increment, lmc.DefaultInstructionSet, 1)
+		replay.Scheduler = &lmc.ScriptedScheduler{Order: outcome.Schedule}
+
+		result, err := replay.RunWithInputs(nil, 100)
+		assert.NoError(t, err)
+		assert.Equal(t, outcome.Outputs, [][]int{result.Results[0].Outputs, result.Results[1].Outputs},
+			"expect the schedule to reproduce the outcome")
+	}
+
+	count, _ := m.Mailboxes.Get(5)
+	assert.Equal(t, "000", count, "expect exploring not to change the mailboxes")
+
+	_, err = m.Explore(nil, 100, 2)
+	assert.Equal(t, lmc.ErrRunLimit{Limit: 2}, err)
+}
+
+func TestMultiprocessorExploreCycleLimit(t *testing.T) {
+	code := `
+wait	LDA flag
+		BRZ wait
+		OUT
+		HLT
+		LDA one
+		STA flag
+		HLT
+flag	DAT 0
+one		DAT 1`
+
+	instructions, err := lmc.NewParser(lmc.NewLexer(code)).Parse()
+	assert.NoError(t, err)
+
+	m := lmc.NewMultiprocessor(lmc.Assemble(instructions, 1, 2), []int{0, 4}, 1, 2)
+	outcomes, err := m.Explore(nil, 7, 0)
+	assert.NoError(t, err)
+
+	halted := false
+	for _, outcome := range outcomes {
+		halted = halted || outcome.Err == nil
+	}
+	assert.True(t, halted, "expect runs that wait less not to be merged with ones that hit the limit")
+}
+
+func TestMultiprocessorExploreSettings(t *testing.T) {
+	m := multiprocessor(t, "LDA big\nADD one\nOUT\nHLT\nbig DAT 999\none DAT 1", lmc.DefaultInstructionSet, 1)
+	for _, p := range m.Processors {
+		p.Arithmetic = lmc.ArithmeticWrap
+	}
+
+	outcomes, err := m.Explore(nil, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(outcomes))
+	assert.Equal(t, [][]int{{0}, {0}}, outcomes[0].Outputs, "expect runs to use the processors' arithmetic")
+}
+
+func TestMultiprocessorTestAndSet(t *testing.T) {
+	m := multiprocessor(t, lockedIncrement, lmc.ExtendedInstructionSet, 2)
+
+	// Without a cycle limit, a processor waiting for the lock can't be starved.
+	outcomes, err := m.Explore(nil, 0, 0)
+	assert.NoError(t, err)
+
+	outputs := [][][]int{}
+	for _, outcome := range outcomes {
+		assert.NoError(t, outcome.Err)
+		outputs = append(outputs, outcome.Outputs)
+	}
+	assert.ElementsMatch(t, [][][]int{{{1}, {2}}, {{2}, {1}}}, outputs, "expect the lock to prevent the lost update")
+}
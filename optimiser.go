@@ -0,0 +1,378 @@
+package lmc
+
+// An optimisation pass takes a program along with an analysis of it and returns the optimised program, and whether
+// anything was changed.
+type optimisation func(code []Instruction, a *analysis) ([]Instruction, bool)
+
+// optimisations are the passes Optimise runs, in order.
+var optimisations = []optimisation{
+	removeRedundantLoads,
+	removeDeadStores,
+	removeBranchesToNext,
+	shortenBranchChains,
+	removeDeadCode,
+	removeUnusedData,
+}
+
+// Optimise runs a peephole optimiser over a program until it stops finding improvements. It removes loads of a
+// mailbox that was just stored to, stores to mailboxes that are never read, branches to the next instruction, dead
+// code after unconditional branches and DAT mailboxes that are never used, and makes branches to a BRA go straight
+// to its target.
+//
+// Since removing instructions moves everything after them, every address in the program has to be given by a label.
+// If any instruction refers to a mailbox by number, the program executes a DAT holding a numeric instruction or it
+// uses an instruction from outside the default instruction set, such as CALL, the program is returned unchanged.
+// Instructions that the program reads or writes as data are never changed or removed, which keeps self-modifying code
+// working as long as any addresses it computes are built from labels.
+func Optimise(instructions []Instruction) []Instruction {
+	code := append([]Instruction{}, instructions...)
+
+	for changed := true; changed; {
+		changed = false
+
+		for _, optimise := range optimisations {
+			a := analyse(code)
+			if !a.relocatable {
+				return code
+			}
+
+			var ok bool
+			code, ok = optimise(code, a)
+			changed = changed || ok
+		}
+	}
+
+	return code
+}
+
+// optimisable are the mnemonics the optimiser knows how to analyse, which are those of the default instruction set
+// other than the interrupt instructions. Others could use labels or move the program counter in ways it can't follow.
+var optimisable = map[string]bool{
+	"HLT": true, "ADD": true, "SUB": true, "STA": true, "STO": true, "LDA": true, "BRA": true, "BRZ": true,
+	"BRP": true, "INP": true, "OUT": true, "INA": true, "OTC": true, "DAT": true,
+}
+
+// analysis describes how a program uses its labels.
+type analysis struct {
+	labels   map[string]int  // Index of the instruction with each label.
+	reads    map[string]bool // Labels used as the operand of ADD, SUB or LDA.
+	writes   map[string]bool // Labels used as the operand of STA.
+	branches map[string]bool // Labels used as the operand of BRA, BRZ or BRP.
+	taken    map[string]bool // Labels whose address is stored with DAT, which might be used as a computed address.
+
+	reachable []bool // Whether each instruction can be executed.
+	protected []bool // Whether each instruction is code that is also read or written as data.
+
+	// Whether the program can be moved around, because every address is given by a label and every instruction is
+	// optimisable.
+	relocatable bool
+}
+
+// analyse works out how a program uses its labels and which instructions can be executed.
+func analyse(code []Instruction) *analysis {
+	a := &analysis{
+		labels:      make(map[string]int),
+		reads:       make(map[string]bool),
+		writes:      make(map[string]bool),
+		branches:    make(map[string]bool),
+		taken:       make(map[string]bool),
+		relocatable: true,
+	}
+
+	for i, instruction := range code {
+		if instruction.Label != "" {
+			a.labels[instruction.Label] = i
+		}
+	}
+
+	for _, instruction := range code {
+		if !optimisable[instruction.Mnemonic] {
+			a.relocatable = false
+			return a
+		}
+
+		operand := instruction.Operand
+		labelled := operand != "" && isIdentifier(operand)
+
+		var uses map[string]bool
+		switch instruction.Mnemonic {
+		case "ADD", "SUB", "LDA":
+			uses = a.reads
+		case "STA", "STO":
+			uses = a.writes
+		case "BRA", "BRZ", "BRP":
+			uses = a.branches
+		case "DAT":
+			if !labelled {
+				continue
+			}
+
+			uses = a.taken
+		default:
+			continue
+		}
+
+		if _, ok := a.labels[operand]; !labelled || !ok {
+			a.relocatable = false
+			return a
+		}
+
+		uses[operand] = true
+	}
+
+	a.reachable = a.reach(code, true)
+	a.protected = make([]bool, len(code))
+
+	// Mailboxes after an instruction that's written to are usually data, which a subroutine return never falls
+	// into, so they're ignored when looking for hard-coded addresses.
+	executed := a.reach(code, false)
+
+	for i, instruction := range code {
+		code := instruction.Mnemonic != "DAT" || a.reachable[i]
+		written := instruction.Label != "" && a.writes[instruction.Label]
+
+		if instruction.Label != "" && code {
+			a.protected[i] = written || a.reads[instruction.Label]
+		}
+
+		// An executed DAT holding a number other than zero is an instruction with a hard-coded address.
+		n, err := ParseInt(instruction.Operand)
+		nonzero := instruction.Operand != "" && !isIdentifier(instruction.Operand) && (err != nil || n != 0)
+		if instruction.Mnemonic == "DAT" && executed[i] && nonzero {
+			a.relocatable = false
+		}
+	}
+
+	return a
+}
+
+// reach works out which instructions can be executed, starting from the first instruction and any label whose
+// address is taken. An instruction that is written to could be replaced with anything, so if fallThrough is true
+// it's assumed to carry on to the next instruction as well as wherever it goes to now.
+func (a *analysis) reach(code []Instruction, fallThrough bool) []bool {
+	reachable := make([]bool, len(code))
+
+	queue := []int{0}
+	for label := range a.taken {
+		queue = append(queue, a.labels[label])
+	}
+
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+
+		if i >= len(code) || reachable[i] {
+			continue
+		}
+
+		reachable[i] = true
+		instruction := code[i]
+
+		switch {
+		case fallThrough && instruction.Label != "" && a.writes[instruction.Label]:
+			queue = append(queue, i+1)
+		case instruction.Mnemonic == "HLT", instruction.Mnemonic == "BRA", instruction.Mnemonic == "DAT":
+			// A DAT that's executed without being written to holds either zero or an address, which are both HLT.
+		default:
+			queue = append(queue, i+1)
+		}
+
+		switch instruction.Mnemonic {
+		case "BRA", "BRZ", "BRP":
+			queue = append(queue, a.labels[instruction.Operand])
+		}
+	}
+
+	return reachable
+}
+
+// referenced returns true if a label is used anywhere in the program.
+func (a *analysis) referenced(label string) bool {
+	return label != "" && (a.reads[label] || a.writes[label] || a.branches[label] || a.taken[label])
+}
+
+// remove removes the instructions at the indexes given. The labels of removed instructions are moved onto the next
+// instruction that's kept, or renamed to that instruction's label if it already has one.
+func (a *analysis) remove(code []Instruction, indexes map[int]bool) ([]Instruction, bool) {
+	// Labels can only be moved forwards, so a referenced label at the end of the program has to stay where it is.
+	for i := len(code) - 1; i >= 0 && indexes[i]; i-- {
+		if a.referenced(code[i].Label) {
+			delete(indexes, i)
+			break
+		}
+	}
+
+	if len(indexes) == 0 {
+		return code, false
+	}
+
+	kept := []Instruction{}
+	renamed := make(map[string]string)
+	pending := []Instruction{}
+
+	for i, instruction := range code {
+		if indexes[i] {
+			if instruction.Label != "" {
+				pending = append(pending, instruction)
+			}
+
+			continue
+		}
+
+		for _, removed := range pending {
+			if instruction.Label == "" {
+				instruction.Label = removed.Label
+				instruction.LabelToken = removed.LabelToken
+				continue
+			}
+
+			renamed[removed.Label] = instruction.Label
+		}
+
+		pending = nil
+		kept = append(kept, instruction)
+	}
+
+	for i := range kept {
+		if label, ok := renamed[kept[i].Operand]; ok {
+			kept[i].Operand = label
+			kept[i].OperandToken.Literal = label
+		}
+	}
+
+	return kept, true
+}
+
+// removeRedundantLoads removes a LDA straight after a STA to the same mailbox, since the accumulator already holds the
+// value being loaded.
+func removeRedundantLoads(code []Instruction, a *analysis) ([]Instruction, bool) {
+	indexes := make(map[int]bool)
+
+	for i := 1; i < len(code); i++ {
+		store, load := code[i-1], code[i]
+
+		if load.Mnemonic != "LDA" || (store.Mnemonic != "STA" && store.Mnemonic != "STO") || load.Operand != store.Operand {
+			continue
+		}
+
+		// If anything branches to the LDA, the accumulator might not hold the value stored.
+		if a.referenced(load.Label) || a.protected[i] || a.protected[i-1] || indexes[i-1] {
+			continue
+		}
+
+		indexes[i] = true
+	}
+
+	return a.remove(code, indexes)
+}
+
+// removeDeadStores removes stores to DAT mailboxes that are never read.
+func removeDeadStores(code []Instruction, a *analysis) ([]Instruction, bool) {
+	indexes := make(map[int]bool)
+
+	for i, instruction := range code {
+		if (instruction.Mnemonic != "STA" && instruction.Mnemonic != "STO") || a.protected[i] {
+			continue
+		}
+
+		target := a.labels[instruction.Operand]
+		if code[target].Mnemonic != "DAT" || a.reachable[target] {
+			continue
+		}
+
+		if a.reads[instruction.Operand] || a.taken[instruction.Operand] {
+			continue
+		}
+
+		indexes[i] = true
+	}
+
+	return a.remove(code, indexes)
+}
+
+// removeBranchesToNext removes branches to the instruction straight after them, which would be executed anyway.
+func removeBranchesToNext(code []Instruction, a *analysis) ([]Instruction, bool) {
+	indexes := make(map[int]bool)
+
+	for i, instruction := range code {
+		switch instruction.Mnemonic {
+		case "BRA", "BRZ", "BRP":
+			if a.labels[instruction.Operand] == i+1 && !a.protected[i] {
+				indexes[i] = true
+			}
+		}
+	}
+
+	return a.remove(code, indexes)
+}
+
+// shortenBranchChains changes branches to a BRA so that they go straight to where the BRA goes.
+func shortenBranchChains(code []Instruction, a *analysis) ([]Instruction, bool) {
+	code = append([]Instruction{}, code...)
+	changed := false
+
+	for i, instruction := range code {
+		switch instruction.Mnemonic {
+		case "BRA", "BRZ", "BRP":
+		default:
+			continue
+		}
+
+		if a.protected[i] {
+			continue
+		}
+
+		target := a.labels[instruction.Operand]
+		seen := map[int]bool{}
+
+		for code[target].Mnemonic == "BRA" && !a.protected[target] && !seen[target] {
+			seen[target] = true
+			target = a.labels[code[target].Operand]
+		}
+
+		// Branches that go round in a loop never get anywhere, so are left alone.
+		if seen[target] || code[target].Label == instruction.Operand {
+			continue
+		}
+
+		code[i].Operand = code[target].Label
+		code[i].OperandToken.Literal = code[target].Label
+		changed = true
+	}
+
+	return code, changed
+}
+
+// removeDeadCode removes instructions that can never be executed, such as those after a BRA or HLT that nothing
+// branches to.
+func removeDeadCode(code []Instruction, a *analysis) ([]Instruction, bool) {
+	indexes := make(map[int]bool)
+
+	for i, instruction := range code {
+		if instruction.Mnemonic == "DAT" || a.reachable[i] || a.protected[i] {
+			continue
+		}
+
+		label := instruction.Label
+		if label != "" && (a.reads[label] || a.writes[label] || a.taken[label]) {
+			continue
+		}
+
+		indexes[i] = true
+	}
+
+	return a.remove(code, indexes)
+}
+
+// removeUnusedData removes DAT mailboxes that are never executed or referred to.
+func removeUnusedData(code []Instruction, a *analysis) ([]Instruction, bool) {
+	indexes := make(map[int]bool)
+
+	for i, instruction := range code {
+		if instruction.Mnemonic == "DAT" && !a.reachable[i] && !a.referenced(instruction.Label) {
+			indexes[i] = true
+		}
+	}
+
+	return a.remove(code, indexes)
+}
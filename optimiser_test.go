@@ -0,0 +1,133 @@
+package lmc_test
+
+import (
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/ollybritton/go-lmc/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptimise(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			"redundant load",
+			"INP\nSTA x\nLDA x\nADD x\nOUT\nHLT\nx DAT",
+			"        INP\n        STA x\n        ADD x\n        OUT\n        HLT\nx       DAT\n",
+		},
+		{
+			"load after a branch target is kept",
+			"INP\nSTA x\nl LDA x\nOUT\nBRA l\nx DAT",
+			"        INP\n        STA x\nl       LDA x\n        OUT\n        BRA l\nx       DAT\n",
+		},
+		{
+			"dead store and unused data",
+			"INP\nSTA x\nSTA y\nOUT\nHLT\nx DAT\ny DAT\nz DAT 5",
+			"        INP\n        OUT\n        HLT\n",
+		},
+		{
+			"branch to next",
+			"INP\nBRZ a\na BRA b\nb OUT\nHLT",
+			"        INP\nb       OUT\n        HLT\n",
+		},
+		{
+			"branch chain",
+			"INP\nBRZ a\nOUT\nHLT\na BRA b\nb BRA c\nc SUB one\nOUT\nHLT\none DAT 1",
+			"        INP\n        BRZ c\n        OUT\n        HLT\nc       SUB one\n        OUT\n        HLT\none     DAT 1\n",
+		},
+		{
+			"dead code",
+			"a INP\nBRZ b\nBRA a\nOUT\nOUT\nb HLT",
+			"a       INP\n        BRZ b\n        BRA a\nb       HLT\n",
+		},
+		{
+			"infinite loop is left alone",
+			"a BRA a\nHLT",
+			"a       BRA a\n",
+		},
+		{
+			"numeric addresses",
+			"INP\nSTA 5\nLDA 5\nOUT\nHLT",
+			"        INP\n        STA 5\n        LDA 5\n        OUT\n        HLT\n",
+		},
+		{
+			"written instructions are kept",
+			"LDA k\nSTA c\nINP\nBRP c\nOUT\nHLT\nc BRA d\nHLT\nd OUT\nHLT\nk DAT d",
+			"        LDA k\n        STA c\n        INP\n        BRP c\n        OUT\n        HLT\nc       BRA d\n        HLT\nd       OUT\n        HLT\nk       DAT d\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instructions, err := lmc.NewParser(lmc.NewLexer(tt.input)).Parse()
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.want, lmc.Format(lmc.Optimise(instructions)))
+		})
+	}
+}
+
+func TestOptimiseOtherInstructions(t *testing.T) {
+	parser := lmc.NewParser(lmc.NewLexer("INP\nCALL sub\nOUT\nHLT\nsub ADD one\nRET\none DAT 1"))
+	parser.InstructionSet = lmc.ExtendedInstructionSet
+
+	instructions, err := parser.Parse()
+	assert.NoError(t, err)
+
+	assert.Equal(t, instructions, lmc.Optimise(instructions), "expect a program using CALL to be left unchanged")
+}
+
+// TestOptimiseExamples checks that optimising the examples doesn't change what they do, and reports how much smaller
+// and faster they get across all of their test cases.
+func TestOptimiseExamples(t *testing.T) {
+	tests := []struct {
+		spec                                  string
+		instructionsBefore, instructionsAfter int
+		cyclesBefore, cyclesAfter             int
+	}{
+		// add and bubble refer to mailboxes by number, so can't be moved around.
+		{"examples/add_test.json", 5, 5, 18, 18},
+		{"examples/bubble_test.json", 79, 79, 1209, 1209},
+		// square is already as tight as the optimiser can make it.
+		{"examples/square_test.json", 22, 22, 413, 413},
+		// The branch at the end of the then block goes straight back to the start of the loop.
+		{"examples/collatz_test.json", 96, 96, 4201, 4184},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			suite, err := spec.Load(tt.spec)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			cycles := func() int {
+				total := 0
+				for _, c := range suite.Cases {
+					result := suite.Run(c)
+					assert.True(t, result.Passed, "%s: %s", c.Name, result.Failure)
+					total += result.Cycles
+				}
+
+				return total
+			}
+
+			before := suite.Instructions()
+			cyclesBefore := cycles()
+
+			after := lmc.Optimise(before)
+			assert.NoError(t, suite.Compile(lmc.Format(after)))
+			cyclesAfter := cycles()
+
+			t.Logf("instructions: %d -> %d, cycles: %d -> %d", len(before), len(after), cyclesBefore, cyclesAfter)
+			assert.Equal(t, tt.instructionsBefore, len(before))
+			assert.Equal(t, tt.instructionsAfter, len(after))
+			assert.Equal(t, tt.cyclesBefore, cyclesBefore)
+			assert.Equal(t, tt.cyclesAfter, cyclesAfter)
+		})
+	}
+}
@@ -8,28 +8,24 @@ type Instruction struct {
 	Mnemonic string
 	Operand  string
 	Opcode   int
-}
 
-// DefaultMnemonicMap maps mnemonics to their opcodes and default operands.
-var DefaultMnemonicMap = map[string]Instruction{
-	"ADD": {"", "ADD", "0", 1},
-	"SUB": {"", "SUB", "0", 2},
-	"STA": {"", "STA", "0", 3},
-	"STO": {"", "STO", "0", 3},
-	"LDA": {"", "LDA", "0", 5},
-	"BRA": {"", "BRA", "0", 6},
-	"BRZ": {"", "BRZ", "0", 7},
-	"BRP": {"", "BRP", "0", 8},
-	"INP": {"", "INP", "1", 9},
-	"OUT": {"", "OUT", "2", 9},
-	"DAT": {"", "DAT", "0", -1}, // DAT is special, doesn't have opcode.
-	"HLT": {"", "HLT", "0", 0},
+	// Tokens the instruction was parsed from, used to map it back to the source. A token's Type is empty
+	// if that part of the instruction wasn't written, such as the operand of INP.
+	LabelToken    Token
+	MnemonicToken Token
+	OperandToken  Token
 }
 
+// DefaultMnemonicMap maps mnemonics to their opcodes and default operands in the default instruction set.
+var DefaultMnemonicMap = DefaultInstructionSet.MnemonicMap()
+
 // Parser converts a stream of tokens into a list of Instructions.
 type Parser struct {
 	lexer *Lexer
 
+	// InstructionSet is the set of mnemonics the parser accepts. If it's nil, DefaultInstructionSet is used.
+	InstructionSet *InstructionSet
+
 	curToken  Token
 	peekToken Token
 
@@ -54,43 +50,53 @@ func (p *Parser) readToken() {
 // Parse converts a stream of tokens into a list of Instructions.
 func (p *Parser) Parse() ([]Instruction, error) {
 	instructions := []Instruction{}
+	set := instructionSet(p.InstructionSet)
 
 	for p.curToken.Type != EOF {
 		switch p.curToken.Type {
 		case ILLEGAL:
-			return nil, fmt.Errorf("illegal token %s in input", p.curToken)
+			return nil, ErrIllegalToken{p.curToken}
 
 		case IDENT:
-			if p.peekToken.Type == IDENT && DefaultMnemonicMap[p.peekToken.Literal].Mnemonic != "" {
+			if _, ok := set.Lookup(p.peekToken.Literal); ok && p.peekToken.Type == IDENT {
 
 				// This is a label before a mnemonic
 				p.curInstruction.Label = p.curToken.Literal
+				p.curInstruction.LabelToken = p.curToken
 				p.readToken()
 
 			} else {
 
 				// This is a mnemonic
-				instruction := DefaultMnemonicMap[p.curToken.Literal]
-				if instruction.Mnemonic == "" {
+				instruction, ok := set.Lookup(p.curToken.Literal)
+				if !ok {
 					// This mnemonic isn't valid/doesn't exist.
-					return nil, fmt.Errorf("invalid mnemonic: %s", p.curToken.Literal)
+					return nil, ErrInvalidMnemonic{p.curToken}
 				}
 
 				// Set mnemonic
 				p.curInstruction.Mnemonic = instruction.Mnemonic
+				p.curInstruction.MnemonicToken = p.curToken
 
 				// Set opcdoe
 				p.curInstruction.Opcode = instruction.Opcode
 
-				// Set special case operands for INP, OUT.
+				// Set fixed operands, such as those of INP and OUT.
 				// Then set other operands.
-				if instruction.Mnemonic == "INP" {
-					p.curInstruction.Operand = "1"
-				} else if instruction.Mnemonic == "OUT" {
-					p.curInstruction.Operand = "2"
+				if instruction.Kind == OperandFixed {
+					p.curInstruction.Operand = fmt.Sprint(instruction.Operand)
 				} else if p.peekToken.Type == INT || p.peekToken.Type == IDENT {
 					p.readToken()
 					p.curInstruction.Operand = p.curToken.Literal
+					p.curInstruction.OperandToken = p.curToken
+				} else if p.peekToken.Type == STRING && instruction.Kind == OperandData {
+					p.readToken()
+					if p.curToken.Literal == "" {
+						return nil, ErrUnexpectedToken{p.curToken}
+					}
+
+					p.curInstruction.Operand = p.curToken.Literal
+					p.curInstruction.OperandToken = p.curToken
 				}
 
 				p.readToken()
@@ -104,17 +110,41 @@ func (p *Parser) Parse() ([]Instruction, error) {
 			}
 
 			p.readToken()
-			instructions = append(instructions, p.curInstruction)
+			instructions = append(instructions, expandString(p.curInstruction)...)
 			p.curInstruction = Instruction{}
 
 		default:
-			return nil, fmt.Errorf("unexpected token in input: %s", p.curToken)
+			return nil, ErrUnexpectedToken{p.curToken}
 		}
 	}
 
 	if p.curInstruction.Mnemonic != "" {
-		instructions = append(instructions, p.curInstruction)
+		instructions = append(instructions, expandString(p.curInstruction)...)
 	}
 
 	return instructions, nil
 }
+
+// expandString turns an instruction with a string operand, such as DAT "hi", into one instruction per character of the
+// string with the character code as the operand. The label is kept on the first. Any other instruction is returned as
+// it is.
+func expandString(instruction Instruction) []Instruction {
+	if instruction.OperandToken.Type != STRING {
+		return []Instruction{instruction}
+	}
+
+	instructions := []Instruction{}
+	for i, r := range instruction.Operand {
+		expanded := instruction
+		expanded.Operand = fmt.Sprint(r)
+
+		if i > 0 {
+			expanded.Label = ""
+			expanded.LabelToken = Token{}
+		}
+
+		instructions = append(instructions, expanded)
+	}
+
+	return instructions
+}
@@ -70,6 +70,21 @@ func TestParserInvalid(t *testing.T) {
 			"STA @",
 			"illegal token @<ILLEGAL>(line=0,col=4-4) in input",
 		},
+		{
+			"unterminated-string",
+			"DAT \"hi\nHLT",
+			"illegal token \"hi<ILLEGAL>(line=0,col=4-6) in input",
+		},
+		{
+			"empty-string",
+			`DAT ""`,
+			"unexpected token in input: <STRING>(line=0,col=4-5)",
+		},
+		{
+			"string-operand",
+			`LDA "hi"`,
+			"unexpected token in input: hi<STRING>(line=0,col=4-7)",
+		},
 	}
 
 	for _, tc := range tests {
@@ -84,3 +99,25 @@ func TestParserInvalid(t *testing.T) {
 	}
 
 }
+
+func TestParserString(t *testing.T) {
+	input := `greeting DAT "Hi\n"
+	DAT 0`
+
+	instructions, err := lmc.NewParser(lmc.NewLexer(input)).Parse()
+	assert.NoError(t, err, "not expecting error when executing parser")
+
+	tests := []lmc.Instruction{
+		{Label: "greeting", Mnemonic: "DAT", Operand: "72", Opcode: -1},
+		{Label: "", Mnemonic: "DAT", Operand: "105", Opcode: -1},
+		{Label: "", Mnemonic: "DAT", Operand: "10", Opcode: -1},
+		{Label: "", Mnemonic: "DAT", Operand: "0", Opcode: -1},
+	}
+
+	assert.Len(t, instructions, len(tests))
+	for i, tc := range tests {
+		assert.Equal(t, tc.Label, instructions[i].Label, "expect label of instruction %d to be correct", i)
+		assert.Equal(t, tc.Operand, instructions[i].Operand, "expect operand of instruction %d to be correct", i)
+		assert.Equal(t, i/3, instructions[i].MnemonicToken.Line, "expect instruction %d to map back to its line", i)
+	}
+}
@@ -0,0 +1,81 @@
+// Package playground serves a web page for writing and running LMC programs in the browser. Everything the page
+// needs is embedded in the binary, so it works without an internet connection.
+package playground
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+//go:embed static
+var static embed.FS
+
+// Server is an http.Handler serving the playground page and the WebSocket API it uses to drive a computer.
+type Server struct {
+	OpcodeSize  int
+	OperandSize int
+
+	mux      *http.ServeMux
+	upgrader websocket.Upgrader
+}
+
+// NewServer returns a new playground server. Programs are assembled with a 1 digit opcode and 2 digit operand unless
+// the sizes are changed before serving requests.
+func NewServer() *Server {
+	s := &Server{
+		OpcodeSize:  1,
+		OperandSize: 2,
+		mux:         http.NewServeMux(),
+	}
+
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+
+	s.mux.Handle("/", http.FileServer(http.FS(files)))
+	s.mux.HandleFunc("/api/ws", s.serveWebSocket)
+
+	return s
+}
+
+// ServeHTTP serves the playground.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// serveWebSocket handles a connection from the page. Each message from the page is a JSON command, and the server
+// replies with JSON state, output and error messages as the program runs.
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var mu sync.Mutex
+	send := func(v interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return conn.WriteJSON(v)
+	}
+
+	sess := newSession(send, s.OpcodeSize, s.OperandSize)
+	defer sess.close()
+
+	for {
+		var cmd command
+		if err := conn.ReadJSON(&cmd); err != nil {
+			return
+		}
+
+		if err := sess.handle(cmd); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package playground
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// command is a message sent by the browser.
+type command struct {
+	Type   string `json:"type"`             // One of load, run, pause, step, speed, input or reset.
+	Source string `json:"source,omitempty"` // Program to assemble, for load.
+	Value  int    `json:"value,omitempty"`  // Value for input.
+	Delay  int    `json:"delay,omitempty"`  // Milliseconds between instructions while running, for speed.
+}
+
+// state is sent to the browser after every instruction so that it can redraw the machine.
+type state struct {
+	Type           string   `json:"type"`
+	Mailboxes      []string `json:"mailboxes"`
+	Accumulator    int      `json:"accumulator"`
+	ProgramCounter int      `json:"programCounter"`
+	Reads          []int    `json:"reads"`  // Mailboxes read by the last instruction.
+	Writes         []int    `json:"writes"` // Mailboxes written by the last instruction.
+	Running        bool     `json:"running"`
+	Halted         bool     `json:"halted"`
+}
+
+// message is any other message sent to the browser, such as an output or an error.
+type message struct {
+	Type    string `json:"type"`
+	Value   int    `json:"value,omitempty"`
+	Message string `json:"message,omitempty"` // Also the character output by OTC.
+	Line    int    `json:"line,omitempty"`    // One-indexed line an error occurred on, 0 if unknown.
+}
+
+// session is a single browser tab's connection to the playground. It keeps track of the program the user loaded and
+// the run of that program currently in progress.
+type session struct {
+	send        func(interface{}) error
+	opcodeSize  int
+	operandSize int
+	delay       time.Duration
+
+	source string
+	run    *run
+}
+
+// newSession returns a session that sends messages to the browser with send, which must be safe to call from
+// multiple goroutines.
+func newSession(send func(interface{}) error, opcodeSize, operandSize int) *session {
+	return &session{
+		send:        send,
+		opcodeSize:  opcodeSize,
+		operandSize: operandSize,
+		delay:       200 * time.Millisecond,
+	}
+}
+
+// handle carries out a command from the browser.
+func (s *session) handle(cmd command) error {
+	switch cmd.Type {
+	case "load":
+		s.source = cmd.Source
+		return s.load()
+
+	case "reset":
+		return s.load()
+
+	case "speed":
+		s.delay = time.Duration(cmd.Delay) * time.Millisecond
+		if s.run != nil {
+			s.run.setDelay(s.delay)
+		}
+
+		return nil
+	}
+
+	if s.run == nil {
+		return s.send(message{Type: "error", Message: "no program has been loaded"})
+	}
+
+	switch cmd.Type {
+	case "run":
+		s.run.setRunning(true)
+	case "pause":
+		s.run.setRunning(false)
+	case "step":
+		s.run.step()
+	case "input":
+		s.run.input(cmd.Value)
+	default:
+		return s.send(message{Type: "error", Message: fmt.Sprintf("unknown command: %s", cmd.Type)})
+	}
+
+	return nil
+}
+
+// load assembles the current source and starts a new run of it, paused before the first instruction.
+func (s *session) load() error {
+	s.close()
+
+	r := newRun(s.send, s.opcodeSize, s.operandSize, s.delay)
+	if err := r.machine.Load(s.source); err != nil {
+		return s.sendError(err)
+	}
+
+	s.run = r
+	go s.run.start()
+
+	return nil
+}
+
+// sendError tells the browser that the program couldn't be assembled.
+func (s *session) sendError(err error) error {
+	msg := message{Type: "error", Message: err.Error()}
+	if tok, ok := lmc.ErrorToken(err); ok {
+		msg.Line = tok.Line + 1
+	}
+
+	return s.send(msg)
+}
+
+// close stops the current run, if there is one.
+func (s *session) close() {
+	if s.run != nil {
+		s.run.stop()
+		s.run = nil
+	}
+}
+
+// run drives a machine for the browser, either continuously at a chosen speed or one instruction at a time.
+type run struct {
+	machine     lmc.Machine
+	send        func(interface{}) error
+	operandSize int
+
+	mu      sync.Mutex
+	running bool
+	delay   time.Duration
+
+	reads  []int
+	writes []int
+
+	wake   chan struct{} // Signalled when running or delay changes.
+	steps  chan struct{} // Signalled when the user asks for a single step.
+	inputs chan int
+	quit   chan struct{}
+	once   sync.Once
+}
+
+func newRun(send func(interface{}) error, opcodeSize, operandSize int, delay time.Duration) *run {
+	r := &run{
+		send:        send,
+		operandSize: operandSize,
+		delay:       delay,
+		wake:        make(chan struct{}, 1),
+		steps:       make(chan struct{}, 1),
+		inputs:      make(chan int, 100),
+		quit:        make(chan struct{}),
+	}
+
+	r.machine = lmc.NewMachine(lmc.WithMemory(10, opcodeSize, operandSize), lmc.WithObserver(r.observe))
+	return r
+}
+
+// start runs the machine until it halts or the run is stopped.
+func (r *run) start() {
+	defer r.machine.Reset()
+
+	for {
+		if r.sendState(false) != nil || !r.wait() {
+			return
+		}
+
+		r.reads, r.writes = nil, nil
+		err := r.machine.Step()
+
+		// The instruction is finished once the browser has given it an input.
+		for {
+			if _, ok := err.(lmc.ErrInputExhausted); !ok {
+				break
+			}
+
+			if r.send(message{Type: "input"}) != nil {
+				return
+			}
+
+			select {
+			case val := <-r.inputs:
+				r.machine.SetInput(val)
+				err = r.machine.Step()
+			case <-r.quit:
+				return
+			}
+		}
+
+		if r.machine.State().Halted {
+			if err != nil {
+				r.send(message{Type: "error", Message: err.Error()})
+			}
+
+			r.sendState(true)
+			return
+		}
+	}
+}
+
+// observe keeps track of the mailboxes the current instruction reads and writes, and sends outputs to the browser.
+func (r *run) observe(msg lmc.Msg) {
+	switch msg.Status {
+	case lmc.MemoryRead, lmc.MemoryWrite:
+		addr, _ := strconv.Atoi(msg.Val)
+		if msg.Status == lmc.MemoryRead {
+			r.reads = append(r.reads, addr)
+		} else {
+			r.writes = append(r.writes, addr)
+		}
+
+	case lmc.Output:
+		val, _ := strconv.Atoi(msg.Val)
+		r.send(message{Type: "output", Value: val})
+
+	case lmc.OutputChar:
+		val, _ := strconv.Atoi(msg.Val)
+		r.send(message{Type: "output", Value: val, Message: string(rune(val))})
+	}
+}
+
+// wait blocks until the next instruction should be executed, returning false if the run was stopped.
+func (r *run) wait() bool {
+	for {
+		r.mu.Lock()
+		running, delay := r.running, r.delay
+		r.mu.Unlock()
+
+		var tick <-chan time.Time
+		if running {
+			tick = time.After(delay)
+		}
+
+		select {
+		case <-tick:
+			return true
+		case <-r.steps:
+			return true
+		case <-r.wake:
+			// Let the browser know whether the computer is running now.
+			if r.sendState(false) != nil {
+				return false
+			}
+
+		case <-r.quit:
+			return false
+		}
+	}
+}
+
+// sendState sends the state of the machine to the browser.
+func (r *run) sendState(halted bool) error {
+	r.mu.Lock()
+	running := r.running && !halted
+	r.mu.Unlock()
+
+	current := r.machine.State()
+
+	return r.send(state{
+		Type:           "state",
+		Mailboxes:      current.Mailboxes[:addressable(r.operandSize)],
+		Accumulator:    current.Accumulator,
+		ProgramCounter: current.ProgramCounter,
+		Reads:          append([]int{}, r.reads...),
+		Writes:         append([]int{}, r.writes...),
+		Running:        running,
+		Halted:         halted,
+	})
+}
+
+func (r *run) setRunning(running bool) {
+	r.mu.Lock()
+	r.running = running
+	r.mu.Unlock()
+
+	r.signal(r.wake)
+}
+
+func (r *run) setDelay(delay time.Duration) {
+	r.mu.Lock()
+	r.delay = delay
+	r.mu.Unlock()
+
+	r.signal(r.wake)
+}
+
+func (r *run) step() {
+	r.signal(r.steps)
+}
+
+func (r *run) input(val int) {
+	select {
+	case r.inputs <- val:
+	default:
+	}
+}
+
+// signal wakes up the run without blocking if it has already been woken.
+func (r *run) signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+func (r *run) stop() {
+	r.once.Do(func() {
+		close(r.quit)
+	})
+}
+
+// addressable returns the number of mailboxes an operand of the size given can refer to.
+func addressable(operandSize int) int {
+	n := 1
+	for i := 0; i < operandSize; i++ {
+		n *= 10
+	}
+
+	return n
+}
@@ -0,0 +1,98 @@
+package playground
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder collects the messages a session sends to the browser.
+type recorder chan interface{}
+
+func (r recorder) send(v interface{}) error {
+	r <- v
+	return nil
+}
+
+// next returns the next message of the type given, skipping any others.
+func (r recorder) next(t *testing.T, typ string) interface{} {
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case v := <-r:
+			switch msg := v.(type) {
+			case state:
+				if msg.Type == typ {
+					return msg
+				}
+			case message:
+				if msg.Type == typ {
+					return msg
+				}
+			}
+
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s message", typ)
+			return nil
+		}
+	}
+}
+
+func TestSessionStep(t *testing.T) {
+	r := make(recorder, 100)
+	sess := newSession(r.send, 1, 2)
+	defer sess.close()
+
+	assert.NoError(t, sess.handle(command{Type: "load", Source: "INP\nSTA 10\nLDA 10\nOUT\nHLT"}))
+
+	initial := r.next(t, "state").(state)
+	assert.Len(t, initial.Mailboxes, 100)
+	assert.Equal(t, []string{"901", "310", "510", "902", "000"}, initial.Mailboxes[:5])
+	assert.Equal(t, 0, initial.ProgramCounter)
+
+	assert.NoError(t, sess.handle(command{Type: "step"}))
+	r.next(t, "input")
+	assert.NoError(t, sess.handle(command{Type: "input", Value: 42}))
+	assert.Equal(t, 42, r.next(t, "state").(state).Accumulator)
+
+	assert.NoError(t, sess.handle(command{Type: "step"}))
+	stored := r.next(t, "state").(state)
+	assert.Equal(t, []int{10}, stored.Writes)
+	assert.Equal(t, "042", stored.Mailboxes[10])
+
+	assert.NoError(t, sess.handle(command{Type: "step"}))
+	assert.Equal(t, []int{10}, r.next(t, "state").(state).Reads)
+
+	assert.NoError(t, sess.handle(command{Type: "speed", Delay: 0}))
+	assert.NoError(t, sess.handle(command{Type: "run"}))
+	assert.Equal(t, 42, r.next(t, "output").(message).Value)
+
+	for !r.next(t, "state").(state).Halted {
+	}
+}
+
+func TestSessionLoadError(t *testing.T) {
+	r := make(recorder, 100)
+	sess := newSession(r.send, 1, 2)
+	defer sess.close()
+
+	assert.NoError(t, sess.handle(command{Type: "load", Source: "INP\nBRA nowhere"}))
+
+	msg := r.next(t, "error").(message)
+	assert.Equal(t, "undefined label: nowhere", msg.Message)
+	assert.Equal(t, 2, msg.Line)
+}
+
+func TestServerStatic(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewServer().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	body, err := ioutil.ReadAll(rec.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, rec.Code)
+	assert.Contains(t, string(body), "<title>Little Man Computer</title>")
+}
@@ -0,0 +1,133 @@
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+
+let socket;
+let cells = [];
+
+function connect() {
+  const protocol = location.protocol === "https:" ? "wss:" : "ws:";
+  socket = new WebSocket(`${protocol}//${location.host}/api/ws`);
+
+  socket.onopen = () => {
+    $("status").textContent = "Connected";
+    load();
+  };
+
+  socket.onclose = () => {
+    $("status").textContent = "Disconnected";
+  };
+
+  socket.onmessage = (event) => {
+    const msg = JSON.parse(event.data);
+
+    switch (msg.type) {
+      case "state":
+        render(msg);
+        break;
+      case "output":
+        // Characters from OTC are collected into a single line until a newline.
+        const last = $("output").lastElementChild;
+        if (msg.message !== undefined && last && last.classList.contains("chars")) {
+          if (msg.message === "\n") {
+            last.classList.remove("chars");
+          } else {
+            last.textContent += msg.message;
+          }
+          break;
+        }
+
+        const item = document.createElement("li");
+        if (msg.message === "\n") {
+          item.textContent = "";
+        } else if (msg.message !== undefined) {
+          item.classList.add("chars");
+          item.textContent = msg.message;
+        } else {
+          item.textContent = msg.value;
+        }
+        $("output").appendChild(item);
+        break;
+      case "input":
+        $("input").disabled = false;
+        $("submit").disabled = false;
+        $("input").focus();
+        break;
+      case "error":
+        $("error").textContent = msg.line ? `Line ${msg.line}: ${msg.message}` : msg.message;
+        break;
+    }
+  };
+}
+
+function send(msg) {
+  if (socket && socket.readyState === WebSocket.OPEN) {
+    socket.send(JSON.stringify(msg));
+  }
+}
+
+function load() {
+  $("error").textContent = "";
+  $("output").innerHTML = "";
+  send({ type: "load", source: $("editor").value });
+  sendSpeed();
+}
+
+function sendSpeed() {
+  // The slider goes from slow to fast, but the server wants a delay.
+  send({ type: "speed", delay: 1000 - Number($("speed").value) });
+}
+
+function render(state) {
+  if (cells.length !== state.mailboxes.length) {
+    const grid = $("mailboxes");
+    grid.innerHTML = "";
+    cells = state.mailboxes.map((_, addr) => {
+      const cell = document.createElement("div");
+      cell.className = "mailbox";
+      cell.innerHTML = `<span class="address">${addr}</span><span class="value"></span>`;
+      grid.appendChild(cell);
+      return cell;
+    });
+  }
+
+  state.mailboxes.forEach((value, addr) => {
+    const cell = cells[addr];
+    cell.querySelector(".value").textContent = value;
+    cell.classList.toggle("pc", addr === state.programCounter && !state.halted);
+    cell.classList.toggle("read", state.reads.includes(addr));
+    cell.classList.toggle("write", state.writes.includes(addr));
+  });
+
+  $("accumulator").textContent = state.accumulator;
+  $("program-counter").textContent = state.programCounter;
+  $("run").disabled = state.running || state.halted;
+  $("pause").disabled = !state.running;
+  $("step").disabled = state.running || state.halted;
+
+  if (state.halted) {
+    $("status").textContent = "Halted";
+  } else {
+    $("status").textContent = state.running ? "Running" : "Paused";
+  }
+}
+
+$("load").onclick = load;
+$("reset").onclick = () => {
+  $("output").innerHTML = "";
+  send({ type: "reset" });
+};
+$("run").onclick = () => send({ type: "run" });
+$("pause").onclick = () => send({ type: "pause" });
+$("step").onclick = () => send({ type: "step" });
+$("speed").oninput = sendSpeed;
+
+$("input-form").onsubmit = (event) => {
+  event.preventDefault();
+  send({ type: "input", value: Number($("input").value) });
+  $("input").value = "";
+  $("input").disabled = true;
+  $("submit").disabled = true;
+};
+
+connect();
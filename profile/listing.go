@@ -0,0 +1,67 @@
+package profile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// listingFormat is the format of each row of the listing: executions, percentage of cycles, branches taken, reads,
+// writes and then the source line.
+const listingFormat = "%8s %6s %9s %6s %6s | %s\n"
+
+// WriteListing writes the source of the program with the number of times each line was executed and the share of
+// the cycles that took, how often the branches on it were taken, and how often the mailboxes it was assembled into
+// were read and written. Mailboxes outside the program that were used are listed afterwards.
+func (p *Profile) WriteListing(w io.Writer, source string) error {
+	bw := bufio.NewWriter(w)
+	lines := p.Lines()
+
+	fmt.Fprintf(bw, listingFormat, "execs", "cycles", "taken", "reads", "writes", "source")
+
+	for i, text := range strings.Split(strings.TrimSuffix(source, "\n"), "\n") {
+		var branch Branch
+		var reads, writes int
+
+		for _, addr := range p.sourceMap.Addresses(i) {
+			branch.Taken += p.Branches[addr].Taken
+			branch.NotTaken += p.Branches[addr].NotTaken
+			reads += p.Reads[addr]
+			writes += p.Writes[addr]
+		}
+
+		execs, share, taken := "", "", ""
+		if lines[i] > 0 {
+			execs = fmt.Sprint(lines[i])
+			share = fmt.Sprintf("%.1f%%", 100*float64(lines[i])/float64(p.Cycles))
+		}
+
+		if total := branch.Taken + branch.NotTaken; total > 0 {
+			taken = fmt.Sprintf("%d/%d", branch.Taken, total)
+		}
+
+		fmt.Fprintf(bw, listingFormat, execs, share, taken, count(reads), count(writes), text)
+	}
+
+	if unmapped := p.Unmapped(); len(unmapped) > 0 {
+		fmt.Fprintf(bw, "\nMailboxes outside the program:\n")
+		fmt.Fprintf(bw, "%8s %6s %6s %6s\n", "mailbox", "execs", "reads", "writes")
+
+		for _, addr := range unmapped {
+			fmt.Fprintf(bw, "%8d %6s %6s %6s\n", addr, count(p.Executions[addr]), count(p.Reads[addr]), count(p.Writes[addr]))
+		}
+	}
+
+	fmt.Fprintf(bw, "\n%d cycles\n", p.Cycles)
+	return bw.Flush()
+}
+
+// count formats a count for the listing, leaving it blank if it's zero.
+func count(n int) string {
+	if n == 0 {
+		return ""
+	}
+
+	return fmt.Sprint(n)
+}
@@ -0,0 +1,228 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+)
+
+// Field numbers from profile.proto in github.com/google/pprof, which describes the format go tool pprof reads.
+const (
+	profileSampleType  = 1
+	profileSample      = 2
+	profileLocation    = 4
+	profileFunction    = 5
+	profileStringTable = 6
+	profilePeriodType  = 11
+	profilePeriod      = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID      = 1
+	locationAddress = 3
+	locationLine    = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID        = 1
+	functionName      = 2
+	functionFilename  = 4
+	functionStartLine = 5
+)
+
+// WritePprof writes the profile in the gzipped protocol buffer format read by go tool pprof, so that
+//
+//	go tool pprof -list . program.pprof
+//
+// shows where the cycles went. Each executed mailbox is a location, and instructions are grouped into functions by
+// the label before them. The filename is used to find the source when showing a listing.
+func (p *Profile) WritePprof(w io.Writer, filename string) error {
+	strs := newStringTable()
+	e := &encoder{}
+
+	valueType := func(typ, unit string) func(*encoder) {
+		return func(e *encoder) {
+			e.int64(valueTypeType, strs.index(typ))
+			e.int64(valueTypeUnit, strs.index(unit))
+		}
+	}
+
+	e.message(profileSampleType, valueType("instructions", "count"))
+
+	addrs := []int{}
+	for addr := range p.Executions {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+
+	functions := p.functions()
+
+	for _, addr := range addrs {
+		id := uint64(addr + 1)
+
+		e.message(profileSample, func(e *encoder) {
+			e.packed(sampleLocationID, []uint64{id})
+			e.packed(sampleValue, []uint64{uint64(p.Executions[addr])})
+		})
+
+		e.message(profileLocation, func(e *encoder) {
+			e.uint64(locationID, id)
+			e.uint64(locationAddress, uint64(addr))
+
+			line, ok := p.sourceMap.Line(addr)
+			if !ok {
+				return
+			}
+
+			e.message(locationLine, func(e *encoder) {
+				e.uint64(lineFunctionID, uint64(functions[addr].id))
+				e.int64(lineLine, int64(line+1))
+			})
+		})
+	}
+
+	written := make(map[int]bool)
+	for _, addr := range addrs {
+		f, ok := functions[addr]
+		if !ok || written[f.id] {
+			continue
+		}
+
+		written[f.id] = true
+		e.message(profileFunction, func(e *encoder) {
+			e.uint64(functionID, uint64(f.id))
+			e.int64(functionName, strs.index(f.name))
+			e.int64(functionFilename, strs.index(filename))
+			e.int64(functionStartLine, int64(f.line+1))
+		})
+	}
+
+	e.message(profilePeriodType, valueType("instructions", "count"))
+	e.int64(profilePeriod, 1)
+
+	for _, s := range strs.strings {
+		e.string(profileStringTable, s)
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(e.buf); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// function is a group of instructions starting at a label, which stands in for a function in pprof.
+type function struct {
+	id   int
+	name string
+	line int // Zero-indexed line of the label.
+}
+
+// functions returns the function each instruction in the program belongs to. Instructions before the first label
+// belong to a function called main.
+func (p *Profile) functions() map[int]function {
+	addrs := []int{}
+	for addr := range p.sourceMap {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+
+	functions := make(map[int]function)
+	current := function{id: 1, name: "main"}
+
+	for _, addr := range addrs {
+		instruction := p.sourceMap[addr]
+
+		if instruction.Label != "" {
+			current = function{id: current.id + 1, name: instruction.Label, line: instruction.MnemonicToken.Line}
+		}
+
+		functions[addr] = current
+	}
+
+	return functions
+}
+
+// stringTable gives each string in a profile an index. The first string must be empty.
+type stringTable struct {
+	strings []string
+	indexes map[string]int64
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{strings: []string{""}, indexes: map[string]int64{"": 0}}
+}
+
+func (t *stringTable) index(s string) int64 {
+	if i, ok := t.indexes[s]; ok {
+		return i
+	}
+
+	t.indexes[s] = int64(len(t.strings))
+	t.strings = append(t.strings, s)
+	return t.indexes[s]
+}
+
+// encoder writes the parts of the protocol buffer wire format needed for a profile.
+type encoder struct {
+	buf []byte
+}
+
+// Wire types.
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (e *encoder) varint(x uint64) {
+	for x >= 0x80 {
+		e.buf = append(e.buf, byte(x)|0x80)
+		x >>= 7
+	}
+
+	e.buf = append(e.buf, byte(x))
+}
+
+func (e *encoder) key(field, wire int) {
+	e.varint(uint64(field<<3 | wire))
+}
+
+func (e *encoder) uint64(field int, x uint64) {
+	e.key(field, wireVarint)
+	e.varint(x)
+}
+
+func (e *encoder) int64(field int, x int64) {
+	e.uint64(field, uint64(x))
+}
+
+func (e *encoder) bytes(field int, b []byte) {
+	e.key(field, wireBytes)
+	e.varint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) string(field int, s string) {
+	e.bytes(field, []byte(s))
+}
+
+func (e *encoder) packed(field int, xs []uint64) {
+	inner := &encoder{}
+	for _, x := range xs {
+		inner.varint(x)
+	}
+
+	e.bytes(field, inner.buf)
+}
+
+func (e *encoder) message(field int, write func(*encoder)) {
+	inner := &encoder{}
+	write(inner)
+	e.bytes(field, inner.buf)
+}
@@ -0,0 +1,161 @@
+// Package profile collects statistics about where a program spends its time while it runs on a Machine, and reports
+// them as an annotated listing of the source or in the format read by go tool pprof.
+package profile
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// Branch counts how often a branch instruction went to its target.
+type Branch struct {
+	Taken    int
+	NotTaken int
+}
+
+// Ratio returns the fraction of the times the branch was executed that it was taken.
+func (b Branch) Ratio() float64 {
+	if b.Taken+b.NotTaken == 0 {
+		return 0
+	}
+
+	return float64(b.Taken) / float64(b.Taken+b.NotTaken)
+}
+
+// Profile records what a program did while it ran. All maps are keyed by mailbox address.
+type Profile struct {
+	Cycles     int            // Number of instructions executed.
+	Executions map[int]int    // Number of times the instruction in each mailbox was executed.
+	Reads      map[int]int    // Number of times each mailbox was read by any instruction that reads memory.
+	Writes     map[int]int    // Number of times each mailbox was written by any instruction that writes memory.
+	Branches   map[int]Branch // How often each branch instruction was taken.
+
+	machine   lmc.Machine
+	sourceMap lmc.SourceMap
+	next      *step // The instruction the computer is waiting to execute, if any.
+}
+
+// step is an instruction the computer is about to execute. It's only counted once the computer carries on, since a
+// run can stop before the instruction it's waiting on, such as when it reaches a cycle limit.
+type step struct {
+	addr   int
+	branch bool // Whether the instruction is a branch, and if so whether it's about to be taken.
+	taken  bool
+}
+
+// New returns a profile for a machine running a program assembled with the source map given. Its Observe method should
+// be called with every message the machine's computer sends, by an observer given to the machine with
+// lmc.WithObserver.
+func New(machine lmc.Machine, sourceMap lmc.SourceMap) *Profile {
+	return &Profile{
+		Executions: make(map[int]int),
+		Reads:      make(map[int]int),
+		Writes:     make(map[int]int),
+		Branches:   make(map[int]Branch),
+		machine:    machine,
+		sourceMap:  sourceMap,
+	}
+}
+
+// Observe records a message from the computer. It must be called before the computer is allowed to carry on, since it
+// looks at the instruction about to be executed and the accumulator to tell whether branches are taken.
+func (p *Profile) Observe(msg lmc.Msg) {
+	// The computer only sends another message once it has carried on with the instruction it was waiting on.
+	if p.next != nil {
+		p.execute(*p.next)
+		p.next = nil
+	}
+
+	addr, err := strconv.Atoi(msg.Val)
+	if err != nil {
+		return
+	}
+
+	switch msg.Status {
+	case lmc.NeedStep:
+		s := step{addr: addr}
+		s.branch, s.taken = p.branch(addr)
+		p.next = &s
+	case lmc.MemoryRead:
+		p.Reads[addr]++
+	case lmc.MemoryWrite:
+		p.Writes[addr]++
+	}
+}
+
+// execute counts an instruction that has been executed.
+func (p *Profile) execute(s step) {
+	p.Cycles++
+	p.Executions[s.addr]++
+
+	if !s.branch {
+		return
+	}
+
+	b := p.Branches[s.addr]
+	if s.taken {
+		b.Taken++
+	} else {
+		b.NotTaken++
+	}
+
+	p.Branches[s.addr] = b
+}
+
+// branch returns whether the instruction at an address is a branch, and if so whether it's about to be taken. The
+// mailbox is decoded rather than looked up in the source map, so that branches written by self-modifying code are
+// counted too.
+func (p *Profile) branch(addr int) (branch, taken bool) {
+	def, _, ok := p.machine.Decode(addr)
+	if !ok {
+		return false, false
+	}
+
+	switch def.Semantics {
+	case lmc.SemanticsBranch:
+		return true, true
+	case lmc.SemanticsBranchZero:
+		return true, p.machine.State().Accumulator == 0
+	case lmc.SemanticsBranchPositive:
+		return true, p.machine.State().Accumulator >= 0
+	}
+
+	return false, false
+}
+
+// Lines returns the number of instructions executed from each zero-indexed line of the source.
+func (p *Profile) Lines() map[int]int {
+	lines := make(map[int]int)
+
+	for addr, count := range p.Executions {
+		if line, ok := p.sourceMap.Line(addr); ok {
+			lines[line] += count
+		}
+	}
+
+	return lines
+}
+
+// Unmapped returns the addresses that were executed, read or written but don't hold an instruction from the source,
+// such as the scratch space used by a sort, in ascending order.
+func (p *Profile) Unmapped() []int {
+	seen := make(map[int]bool)
+
+	for _, counts := range []map[int]int{p.Executions, p.Reads, p.Writes} {
+		for addr := range counts {
+			if _, ok := p.sourceMap[addr]; !ok {
+				seen[addr] = true
+			}
+		}
+	}
+
+	addrs := []int{}
+	for addr := range seen {
+		addrs = append(addrs, addr)
+	}
+
+	sort.Ints(addrs)
+	return addrs
+}
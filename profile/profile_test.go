@@ -0,0 +1,118 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+const countdown = `        INP
+loop    BRZ done
+        OUT
+        SUB one
+        BRA loop
+done    HLT
+one     DAT 1
+`
+
+func profileProgram(t *testing.T, code string, inputs ...int) *Profile {
+	t.Helper()
+
+	instructions, err := lmc.NewParser(lmc.NewLexer(code)).Parse()
+	assert.NoError(t, err)
+
+	_, sourceMap := lmc.AssembleWithSourceMap(instructions, 1, 2)
+
+	var p *Profile
+	m := lmc.NewMachine(lmc.WithMaxCycles(1000), lmc.WithObserver(func(msg lmc.Msg) {
+		p.Observe(msg)
+	}))
+	assert.NoError(t, m.Load(code))
+	p = New(m, sourceMap)
+
+	m.SetInput(inputs...)
+	assert.NoError(t, m.Run(context.Background()))
+
+	return p
+}
+
+func TestProfile(t *testing.T) {
+	p := profileProgram(t, countdown, 3)
+
+	assert.Equal(t, 15, p.Cycles)
+	assert.Equal(t, map[int]int{0: 1, 1: 4, 2: 3, 3: 3, 4: 3, 5: 1}, p.Executions)
+	assert.Equal(t, map[int]int{6: 3}, p.Reads)
+	assert.Equal(t, map[int]int{}, p.Writes)
+	assert.Equal(t, map[int]Branch{1: {1, 3}, 4: {3, 0}}, p.Branches)
+	assert.Equal(t, 0.25, p.Branches[1].Ratio())
+	assert.Equal(t, map[int]int{0: 1, 1: 4, 2: 3, 3: 3, 4: 3, 5: 1}, p.Lines())
+}
+
+func TestProfileCycleLimit(t *testing.T) {
+	instructions, err := lmc.NewParser(lmc.NewLexer("loop BRA loop")).Parse()
+	assert.NoError(t, err)
+
+	_, sourceMap := lmc.AssembleWithSourceMap(instructions, 1, 2)
+
+	var p *Profile
+	m := lmc.NewMachine(lmc.WithMaxCycles(5), lmc.WithObserver(func(msg lmc.Msg) {
+		p.Observe(msg)
+	}))
+	assert.NoError(t, m.Load("loop BRA loop"))
+	p = New(m, sourceMap)
+
+	assert.Equal(t, lmc.ErrCycleLimit{Limit: 5}, m.Run(context.Background()))
+	assert.Equal(t, 5, p.Cycles, "expect the instruction the run stopped at not to be counted")
+	assert.Equal(t, map[int]int{0: 5}, p.Executions)
+	assert.Equal(t, map[int]Branch{0: {5, 0}}, p.Branches)
+}
+
+func TestListing(t *testing.T) {
+	p := profileProgram(t, countdown, 3)
+
+	var b bytes.Buffer
+	assert.NoError(t, p.WriteListing(&b, countdown))
+	assert.Equal(t, `   execs cycles     taken  reads writes | source
+       1   6.7%                         |         INP
+       4  26.7%       1/4               | loop    BRZ done
+       3  20.0%                         |         OUT
+       3  20.0%                         |         SUB one
+       3  20.0%       3/3               |         BRA loop
+       1   6.7%                         | done    HLT
+                               3        | one     DAT 1
+
+15 cycles
+`, b.String())
+}
+
+func TestListingUnmapped(t *testing.T) {
+	p := profileProgram(t, "INP\nSTA 50\nLDA 50\nOUT\nHLT", 7)
+
+	var b bytes.Buffer
+	assert.NoError(t, p.WriteListing(&b, "INP\nSTA 50\nLDA 50\nOUT\nHLT"))
+	assert.Contains(t, b.String(), "Mailboxes outside the program:\n mailbox  execs  reads writes\n      50             1      1\n")
+	assert.Equal(t, []int{50}, p.Unmapped())
+}
+
+func TestPprof(t *testing.T) {
+	p := profileProgram(t, countdown, 3)
+
+	var b bytes.Buffer
+	assert.NoError(t, p.WritePprof(&b, "countdown.lmc"))
+
+	r, err := gzip.NewReader(&b)
+	assert.NoError(t, err)
+
+	raw, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+
+	for _, s := range []string{"instructions", "count", "main", "loop", "done", "countdown.lmc"} {
+		assert.True(t, strings.Contains(string(raw), s), "expecting %q in the string table", s)
+	}
+}
@@ -0,0 +1,232 @@
+// Package record records runs of programs, along with everything given to them and everything they did, so that a run
+// can be replayed exactly and checked against what happened the first time. A recording is saved as a JSON session
+// file, for example:
+//
+//	{
+//	    "version": 1,
+//	    "program": "add.lmc",
+//	    "source": "INP\nSTA 99\nINP\nADD 99\nOUT\nHLT",
+//	    "settings": {"opcodeSize": 1, "operandSize": 2, "radix": 10, "interruptVector": -1},
+//	    "events": [
+//	        {"cycle": 1, "kind": "input", "value": 5},
+//	        {"cycle": 3, "kind": "input", "value": 7},
+//	        {"cycle": 5, "kind": "output", "value": 12}
+//	    ],
+//	    "cycles": 6
+//	}
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// Version is the version of the session format written by Recorder.
+const Version = 1
+
+// Session is a recording of a single run of a program.
+type Session struct {
+	Version  int      `json:"version"`
+	Program  string   `json:"program"` // Path of the program when it was recorded.
+	Source   string   `json:"source"`  // The program itself, so that it can be replayed after the file has changed.
+	Settings Settings `json:"settings"`
+	Events   []Event  `json:"events"`
+	Cycles   int      `json:"cycles"`          // Number of instructions executed.
+	Error    string   `json:"error,omitempty"` // The error the run stopped with, if any.
+}
+
+// Settings are the options a program was run with, which decide how it behaves. They mirror the flags of lmc run.
+type Settings struct {
+	OpcodeSize  int    `json:"opcodeSize"`
+	OperandSize int    `json:"operandSize"`
+	Radix       int    `json:"radix"`
+	Arithmetic  string `json:"arithmetic,omitempty"`
+
+	// InstructionSet is the path of the file defining the instruction set, if one other than the default was used.
+	// The file isn't part of the session, so must still be there to replay it.
+	InstructionSet string `json:"instructionSet,omitempty"`
+
+	Devices       []string `json:"devices,omitempty"` // Each device mapped into the mailboxes, as name@address.
+	Seed          int64    `json:"seed,omitempty"`
+	RandomMax     int      `json:"randomMax,omitempty"`
+	Keys          string   `json:"keys,omitempty"`
+	TimerInterval int      `json:"timerInterval,omitempty"`
+
+	InterruptVector int   `json:"interruptVector"` // Negative if interrupts weren't enabled.
+	InterruptPC     int   `json:"interruptPC,omitempty"`
+	InterruptAcc    int   `json:"interruptAcc,omitempty"`
+	InterruptAt     []int `json:"interruptAt,omitempty"`
+
+	// Step and MicroStep record how the run was stepped through. They don't change what the program does, but a replay
+	// steps through it the same way.
+	Step      bool `json:"step,omitempty"`
+	MicroStep bool `json:"microStep,omitempty"`
+}
+
+// Kind is a kind of event.
+type Kind string
+
+// Kind definitions
+const (
+	Input      Kind = "input"
+	InputChar  Kind = "input-char"
+	Output     Kind = "output"
+	OutputChar Kind = "output-char"
+	Interrupt  Kind = "interrupt" // The value is the address of the instruction that was interrupted.
+
+	// NoInput is a request for input that wasn't given, so that the run stopped with the session's error. It has no
+	// value.
+	NoInput Kind = "no-input"
+)
+
+// Event is something that happened during a run which a replay has to do the same way: an input given to the
+// program, a value it output or an interrupt it took. Events also record the cycle they happened in, so a replay that
+// does the same things at different times diverges.
+type Event struct {
+	Cycle int  `json:"cycle"` // Number of instructions that had been started when it happened.
+	Kind  Kind `json:"kind"`
+	Value int  `json:"value"`
+}
+
+// String returns the event as in output 12 at cycle 5.
+func (e Event) String() string {
+	if e.Kind == NoInput {
+		return fmt.Sprintf("%s at cycle %d", e.Kind, e.Cycle)
+	}
+
+	return fmt.Sprintf("%s %d at cycle %d", e.Kind, e.Value, e.Cycle)
+}
+
+// Inputs returns the values input during the session, in order.
+func (s *Session) Inputs() []int {
+	inputs := []int{}
+	for _, e := range s.Events {
+		if e.Kind == Input || e.Kind == InputChar {
+			inputs = append(inputs, e.Value)
+		}
+	}
+
+	return inputs
+}
+
+// Load reads a session from a file.
+func Load(path string) (*Session, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{}
+	if err := json.Unmarshal(bytes, session); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	if session.Version != Version {
+		return nil, fmt.Errorf("%s: unsupported session version %d, expecting %d", path, session.Version, Version)
+	}
+
+	return session, nil
+}
+
+// Save writes the session to a file.
+func (s *Session) Save(path string) error {
+	bytes, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(bytes, '\n'), 0644)
+}
+
+// Recorder records a run of a program as it happens. Its Observe method should be passed every message the computer
+// sends, and Input called with every value given to the program.
+type Recorder struct {
+	session *Session
+	waiting bool // Whether the program has asked for input that hasn't been given yet.
+}
+
+// NewRecorder returns a recorder for a run of the program at path, whose source is given, run with the settings
+// given.
+func NewRecorder(path, source string, settings Settings) *Recorder {
+	return &Recorder{session: &Session{
+		Version:  Version,
+		Program:  path,
+		Source:   source,
+		Settings: settings,
+		Events:   []Event{},
+	}}
+}
+
+// Observe records a message from the computer.
+func (r *Recorder) Observe(msg lmc.Msg) {
+	s := r.session
+
+	switch msg.Status {
+	case lmc.NeedStep:
+		s.Cycles++
+	case lmc.NeedInput:
+		r.waiting = true
+	case lmc.Output, lmc.OutputChar, lmc.Interrupt:
+		val, err := strconv.Atoi(msg.Val)
+		if err != nil {
+			return
+		}
+
+		s.Events = append(s.Events, Event{s.Cycles, kind(msg), val})
+	}
+}
+
+// Input records a value given to the program. char is true if the program asked for a character.
+func (r *Recorder) Input(val int, char bool) {
+	kind := Input
+	if char {
+		kind = InputChar
+	}
+
+	r.session.Events = append(r.session.Events, Event{r.session.Cycles, kind, val})
+	r.waiting = false
+}
+
+// Finish records the error the run stopped with, if any, and returns the session. If the program was still waiting
+// for input, because none could be given to it, the request is recorded as a NoInput event.
+func (r *Recorder) Finish(err error) *Session {
+	s := r.session
+	if r.waiting {
+		s.Events = append(s.Events, Event{s.Cycles, NoInput, 0})
+		r.waiting = false
+	}
+
+	s.Error = errString(err)
+	return s
+}
+
+// kind returns the kind of event a message from the computer is.
+func kind(msg lmc.Msg) Kind {
+	switch msg.Status {
+	case lmc.NeedInput:
+		if msg.Val == lmc.InputChar {
+			return InputChar
+		}
+
+		return Input
+	case lmc.OutputChar:
+		return OutputChar
+	case lmc.Interrupt:
+		return Interrupt
+	}
+
+	return Output
+}
+
+// errString returns the message of an error, or the empty string if it's nil.
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
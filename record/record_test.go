@@ -0,0 +1,152 @@
+package record
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+const add = `INP
+STA 99
+INP
+ADD 99
+OUT
+HLT
+`
+
+// run runs a program with the inputs given, passing every message from the computer to the observer.
+func run(t *testing.T, code string, inputs []int, observe func(lmc.Msg)) error {
+	t.Helper()
+
+	instructions, err := lmc.NewParser(lmc.NewLexer(code)).Parse()
+	assert.NoError(t, err)
+
+	computer := lmc.NewComputerFromMailboxes(lmc.Assemble(instructions, 1, 2), 1, 2)
+	_, err = computer.RunWithInputs(inputs, 1000, observe)
+
+	return err
+}
+
+// recordRun records a run of a program with the inputs given.
+func recordRun(t *testing.T, code string, inputs ...int) *Session {
+	t.Helper()
+
+	r := NewRecorder("add.lmc", code, Settings{OpcodeSize: 1, OperandSize: 2, Radix: 10, InterruptVector: -1})
+	given := 0
+
+	err := run(t, code, inputs, func(msg lmc.Msg) {
+		r.Observe(msg)
+		if msg.Status == lmc.NeedInput && given < len(inputs) {
+			r.Input(inputs[given], msg.Val == lmc.InputChar)
+			given++
+		}
+	})
+
+	return r.Finish(err)
+}
+
+// replay replays a session against a program, returning how it diverged.
+func replay(t *testing.T, session *Session, code string) error {
+	t.Helper()
+
+	r := NewReplayer(session)
+	inputs := session.Inputs()
+	given := 0
+
+	err := run(t, code, inputs, func(msg lmc.Msg) {
+		r.Observe(msg)
+		if msg.Status == lmc.NeedInput {
+			if val, err := r.Input(msg.Val == lmc.InputChar); err == nil {
+				assert.Equal(t, inputs[given], val)
+				given++
+			}
+		}
+	})
+
+	return r.Finish(err)
+}
+
+func TestRecord(t *testing.T) {
+	session := recordRun(t, add, 5, 7)
+
+	assert.Equal(t, []Event{
+		{1, Input, 5},
+		{3, Input, 7},
+		{5, Output, 12},
+	}, session.Events)
+	assert.Equal(t, 6, session.Cycles)
+	assert.Equal(t, "", session.Error)
+	assert.Equal(t, []int{5, 7}, session.Inputs())
+}
+
+func TestSaveLoad(t *testing.T) {
+	session := recordRun(t, add, 5, 7)
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	assert.NoError(t, session.Save(path))
+
+	loaded, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, session, loaded)
+}
+
+func TestReplay(t *testing.T) {
+	session := recordRun(t, add, 5, 7)
+
+	assert.NoError(t, replay(t, session, add))
+}
+
+func TestReplayInputExhausted(t *testing.T) {
+	session := recordRun(t, add, 5)
+
+	assert.Equal(t, []Event{
+		{1, Input, 5},
+		{3, NoInput, 0},
+	}, session.Events)
+	assert.Equal(t, lmc.ErrInputExhausted{Outputs: 0}.Error(), session.Error)
+
+	assert.NoError(t, replay(t, session, add))
+
+	err := replay(t, session, "INP\nSTA 99\nLDA 99\nINP\nADD 99\nOUT\nHLT\n")
+	assert.Equal(t, ErrDiverged{4, end, "another instruction at address 3"}, err)
+}
+
+func TestReplayDiverged(t *testing.T) {
+	session := recordRun(t, add, 5, 7)
+
+	tests := []struct {
+		name string
+		code string
+		want ErrDiverged
+	}{
+		{
+			"different output",
+			"INP\nSTA 99\nINP\nSUB 99\nOUT\nHLT\n",
+			ErrDiverged{5, "output 12 at cycle 5", "output 2 at cycle 5"},
+		},
+		{
+			"input at a different time",
+			"INP\nSTA 99\nLDA 99\nINP\nADD 99\nOUT\nHLT\n",
+			ErrDiverged{4, "input 7 at cycle 3", "input at cycle 4"},
+		},
+		{
+			"more instructions",
+			"INP\nSTA 99\nINP\nADD 99\nOUT\nLDA 99\nHLT\n",
+			ErrDiverged{7, end, "another instruction at address 6"},
+		},
+		{
+			"missing output",
+			"INP\nSTA 99\nINP\nADD 99\nHLT\n",
+			ErrDiverged{5, "output 12 at cycle 5", end},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := replay(t, session, tt.code)
+			assert.Equal(t, tt.want, err)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package record
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// ErrDiverged occurs when a replayed run does something different to what was recorded.
+type ErrDiverged struct {
+	Cycle    int
+	Recorded string // What the recording has next.
+	Replayed string // What the replay did instead.
+}
+
+// Error returns the error string for ErrDiverged.
+func (e ErrDiverged) Error() string {
+	return fmt.Sprintf(
+		"replay diverged from the recording at cycle %d: recorded %s, replayed %s", e.Cycle, e.Recorded, e.Replayed,
+	)
+}
+
+// end describes the end of a run, for ErrDiverged.
+const end = "the end of the run"
+
+// Replayer checks a run of a program against a recording of it as it happens, and gives the program the inputs that
+// were recorded. Its Observe method should be passed every message the computer sends, and the Input method called
+// whenever the program asks for input. Once the replay has diverged it stays that way, so the run can be stopped.
+type Replayer struct {
+	session *Session
+	cycles  int
+	next    int  // Index of the next event expected.
+	input   int  // Value of the input the program is waiting for.
+	noInput bool // Whether the recorded run was given no input at this point, and stopped instead.
+	err     error
+}
+
+// NewReplayer returns a replayer for the session given.
+func NewReplayer(session *Session) *Replayer {
+	return &Replayer{session: session}
+}
+
+// Observe checks a message from the computer against the recording.
+func (r *Replayer) Observe(msg lmc.Msg) {
+	if r.err != nil {
+		return
+	}
+
+	switch msg.Status {
+	case lmc.NeedStep:
+		r.cycles++
+		if r.cycles > r.session.Cycles {
+			r.err = ErrDiverged{r.cycles, end, fmt.Sprintf("another instruction at address %s", msg.Val)}
+		}
+
+	case lmc.NeedInput:
+		got := Event{r.cycles, kind(msg), 0}
+
+		// The request a recorded run stopped at matches whichever kind of input it was for.
+		if r.next < len(r.session.Events) && r.session.Events[r.next].Kind == NoInput {
+			got.Kind = NoInput
+		}
+
+		if want, ok := r.expect(got, false, fmt.Sprintf("%s at cycle %d", kind(msg), got.Cycle)); ok {
+			r.input = want.Value
+			r.noInput = want.Kind == NoInput
+		}
+
+	case lmc.Output, lmc.OutputChar, lmc.Interrupt:
+		val, err := strconv.Atoi(msg.Val)
+		if err != nil {
+			return
+		}
+
+		got := Event{r.cycles, kind(msg), val}
+		r.expect(got, true, got.String())
+	}
+}
+
+// expect checks that an event is the next one in the recording, comparing its value too if value is true. It returns
+// the recorded event if it is, and records the divergence otherwise.
+func (r *Replayer) expect(got Event, value bool, replayed string) (Event, bool) {
+	recorded := end
+
+	if r.next < len(r.session.Events) {
+		want := r.session.Events[r.next]
+		if want.Kind == got.Kind && want.Cycle == got.Cycle && (!value || want.Value == got.Value) {
+			r.next++
+			return want, true
+		}
+
+		recorded = want.String()
+	}
+
+	r.err = ErrDiverged{got.Cycle, recorded, replayed}
+	return Event{}, false
+}
+
+// Input returns the recorded value of the input the program is asking for. char is ignored, since Observe has
+// already checked that the program asked for the kind of input that was recorded. If the recorded run wasn't given
+// any input at this point, the error it stopped with is returned so that the replay stops the same way.
+func (r *Replayer) Input(char bool) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	if r.noInput {
+		return 0, errors.New(r.session.Error)
+	}
+
+	return r.input, nil
+}
+
+// Err returns how the replay has diverged from the recording, or nil if it hasn't yet.
+func (r *Replayer) Err() error {
+	return r.err
+}
+
+// Finish checks that the run ended the way the recording did, given the error it stopped with, and returns how the
+// replay diverged from the recording if it did.
+func (r *Replayer) Finish(err error) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	s := r.session
+	if r.next < len(s.Events) {
+		return ErrDiverged{r.cycles, s.Events[r.next].String(), end}
+	}
+
+	if r.cycles != s.Cycles {
+		return ErrDiverged{r.cycles, fmt.Sprintf("%d cycles", s.Cycles), fmt.Sprintf("%d cycles", r.cycles)}
+	}
+
+	if errString(err) != s.Error {
+		return ErrDiverged{r.cycles, describe(s.Error), describe(errString(err))}
+	}
+
+	return nil
+}
+
+// describe describes how a run stopped, given the message of the error it stopped with.
+func describe(err string) string {
+	if err == "" {
+		return "halting"
+	}
+
+	return "stopping with the error " + err
+}
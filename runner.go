@@ -0,0 +1,72 @@
+package lmc
+
+import "strconv"
+
+// Result is the outcome of running a program with RunWithInputs.
+type Result struct {
+	Outputs []int // Every value output by the program, in order. Characters output by OTC are given as their codes.
+	Cycles  int   // Number of instructions executed, including the final HLT.
+}
+
+// RunWithInputs runs the computer without any user interaction, giving it the inputs in order and collecting what it
+// outputs. If maxCycles is greater than zero, the program is halted with ErrCycleLimit once it has executed that many
+// instructions. If the program asks for more input than was given it is halted with ErrInputExhausted. In both cases
+// the result so far is returned along with the error.
+//
+// Every message from the computer is passed to the observers before it is handled, which can be used to watch the
+// program run. Observers must not send anything to the computer themselves.
+func (c *Computer) RunWithInputs(inputs []int, maxCycles int, observers ...func(Msg)) (Result, error) {
+	result := Result{Outputs: []int{}}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- c.Run()
+	}()
+
+	halt := func(err error) (Result, error) {
+		c.Halt()
+		<-errs
+		return result, err
+	}
+
+	for {
+		select {
+		case msg := <-c.Messages:
+			for _, observe := range observers {
+				observe(msg)
+			}
+
+			switch msg.Status {
+			case NeedStep:
+				if maxCycles > 0 && result.Cycles >= maxCycles {
+					return halt(ErrCycleLimit{maxCycles})
+				}
+
+				result.Cycles++
+				c.Step <- struct{}{}
+
+			case MicroStep:
+				c.Step <- struct{}{}
+
+			case NeedInput:
+				if len(inputs) == 0 {
+					return halt(ErrInputExhausted{len(result.Outputs)})
+				}
+
+				c.Inbox <- inputs[0]
+				inputs = inputs[1:]
+
+			case Output, OutputChar:
+				val, err := strconv.Atoi(msg.Val)
+				if err != nil {
+					return halt(err)
+				}
+
+				result.Outputs = append(result.Outputs, val)
+			}
+
+		case err := <-errs:
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package lmc_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunWithInputs(t *testing.T) {
+	square, err := ioutil.ReadFile("examples/square.lmc")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		code      string
+		inputs    []int
+		maxCycles int
+		outputs   []int
+		cycles    int
+		err       error
+	}{
+		{"add", "INP\nSTA 01\nINP\nADD 01\nOUT", []int{3, 4}, 0, []int{7}, 6, nil},
+		{"square", string(square), []int{4}, 0, []int{16}, 43, nil},
+		{"cycle-limit", "loop BRA loop", nil, 10, []int{}, 10, lmc.ErrCycleLimit{10}},
+		{"input-exhausted", "INP\nOUT\nINP\nOUT\nHLT", []int{5}, 0, []int{5}, 3, lmc.ErrInputExhausted{1}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			computer, err := lmc.NewComputerFromCode(tc.code, 1, 2)
+			assert.NoError(t, err, "not expecting error creating computer")
+
+			result, err := computer.RunWithInputs(tc.inputs, tc.maxCycles)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.outputs, result.Outputs, "expecting outputs to be correct")
+			assert.Equal(t, tc.cycles, result.Cycles, "expecting cycle count to be correct")
+		})
+	}
+}
+
+func TestRunWithInputsCharacters(t *testing.T) {
+	computer, err := lmc.NewComputerFromCode("INA\nOTC\nOUT\nLDA bang\nOTC\nHLT\nbang DAT \"!\"", 1, 2)
+	assert.NoError(t, err, "not expecting error creating computer")
+
+	chars := []string{}
+	result, err := computer.RunWithInputs([]int{'a'}, 0, func(msg lmc.Msg) {
+		switch msg.Status {
+		case lmc.NeedInput:
+			assert.Equal(t, lmc.InputChar, msg.Val, "expect INA to ask for a character")
+		case lmc.OutputChar:
+			chars = append(chars, msg.Val)
+		}
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{'a', 'a', '!'}, result.Outputs, "expect character codes to be output")
+	assert.Equal(t, []string{"97", "33"}, chars, "expect only OTC to output characters")
+}
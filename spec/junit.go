@@ -0,0 +1,96 @@
+package spec
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes results as JUnit XML, with one test suite per spec file, so that CI systems can display them.
+func WriteJUnit(w io.Writer, results []Result) error {
+	report := junitSuites{}
+	var total time.Duration
+
+	for _, result := range results {
+		n := len(report.Suites)
+		if n == 0 || report.Suites[n-1].Name != result.Suite {
+			report.Suites = append(report.Suites, junitSuite{Name: result.Suite})
+			n++
+		}
+
+		suite := &report.Suites[n-1]
+		c := junitCase{Name: result.Case, ClassName: result.Suite, Time: seconds(result.Duration)}
+
+		if !result.Passed {
+			c.Failure = &junitFailure{Message: strings.SplitN(result.Failure, "\n", 2)[0], Text: result.Failure}
+			suite.Failures++
+			report.Failures++
+		}
+
+		suite.Cases = append(suite.Cases, c)
+		suite.Tests++
+		report.Tests++
+		total += result.Duration
+	}
+
+	for i := range report.Suites {
+		var d time.Duration
+		for _, result := range results {
+			if result.Suite == report.Suites[i].Name {
+				d += result.Duration
+			}
+		}
+
+		report.Suites[i].Time = seconds(d)
+	}
+
+	report.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// seconds formats a duration as a number of seconds, as JUnit expects.
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
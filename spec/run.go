@@ -0,0 +1,223 @@
+package spec
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// Result is the outcome of running a single case.
+type Result struct {
+	Suite    string
+	Case     string
+	Passed   bool
+	Failure  string // Explanation of why the case failed, empty if it passed.
+	Outputs  []int
+	Cycles   int
+	Duration time.Duration
+}
+
+// Run runs every case of every suite, using up to workers goroutines at once. The results are in the same order as
+// the suites and their cases.
+func Run(suites []*Suite, workers int) []Result {
+	type job struct {
+		suite *Suite
+		c     Case
+		index int
+	}
+
+	jobs := []job{}
+	for _, suite := range suites {
+		for _, c := range suite.Cases {
+			jobs = append(jobs, job{suite, c, len(jobs)})
+		}
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]Result, len(jobs))
+	queue := make(chan job)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				results[j.index] = j.suite.Run(j.c)
+			}
+		}()
+	}
+
+	for _, j := range jobs {
+		queue <- j
+	}
+	close(queue)
+
+	wg.Wait()
+	return results
+}
+
+// Run runs a single case against the suite's program. Observers are passed every message from the computer, as with
+// lmc.WithObserver.
+func (s *Suite) Run(c Case, observers ...func(lmc.Msg)) Result {
+	start := time.Now()
+	result := Result{Suite: s.Name(), Case: c.Name}
+
+	maxCycles := c.MaxCycles
+	if maxCycles == 0 {
+		maxCycles = s.MaxCycles
+	}
+
+	m, err := s.acquire(maxCycles)
+	if err != nil {
+		result.Failure = err.Error()
+		return result
+	}
+	defer s.release(m, maxCycles)
+
+	m.observers = observers
+
+	ctx := context.Background()
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	m.SetInput(c.Inputs...)
+	err = m.Run(ctx)
+	if err == context.DeadlineExceeded {
+		err = fmt.Errorf("program did not halt within %s", s.Timeout)
+	}
+
+	state := m.State()
+	result.Outputs = m.Outputs()
+	result.Cycles = state.Cycles
+	result.Duration = time.Since(start)
+
+	failures := []string{}
+	if err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	if c.Outputs != nil {
+		if diff := diffOutputs(c.Outputs, result.Outputs); diff != "" {
+			failures = append(failures, diff)
+		}
+	}
+
+	if diff := diffMailboxes(c.Mailboxes, state.Mailboxes); diff != "" {
+		failures = append(failures, diff)
+	}
+
+	result.Passed = len(failures) == 0
+	result.Failure = strings.Join(failures, "\n")
+
+	return result
+}
+
+// machine is a machine loaded with a suite's program, which passes the messages its computer sends to the observers
+// of the case it's running.
+type machine struct {
+	lmc.Machine
+	observers []func(lmc.Msg)
+}
+
+// acquire returns a machine loaded with the suite's program, with the cycle limit given, that no other case is using.
+// Machines are reused from case to case, being reset rather than loaded again, so a suite whose cases are run one at
+// a time only ever loads one machine for each cycle limit.
+func (s *Suite) acquire(maxCycles int) (*machine, error) {
+	s.mu.Lock()
+	if idle := s.machines[maxCycles]; len(idle) != 0 {
+		m := idle[len(idle)-1]
+		s.machines[maxCycles] = idle[:len(idle)-1]
+		s.mu.Unlock()
+		return m, nil
+	}
+	s.mu.Unlock()
+
+	m := &machine{}
+	m.Machine = lmc.NewMachine(
+		lmc.WithInstructionSet(s.Set()),
+		lmc.WithMemory(10, s.OpcodeSize, s.OperandSize),
+		lmc.WithMaxCycles(maxCycles),
+		lmc.WithCoverage(s.Coverage),
+		lmc.WithObserver(func(msg lmc.Msg) {
+			for _, observe := range m.observers {
+				observe(msg)
+			}
+		}),
+	)
+
+	if err := m.Load(s.source); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// release resets a machine once a case has finished with it, so that it can be used by the next.
+func (s *Suite) release(m *machine, maxCycles int) {
+	m.Reset()
+	m.observers = nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.machines == nil {
+		s.machines = make(map[int][]*machine)
+	}
+
+	s.machines[maxCycles] = append(s.machines[maxCycles], m)
+}
+
+// diffOutputs explains how the outputs of a program differ from what was expected, or returns the empty string if
+// they're the same.
+func diffOutputs(want, got []int) string {
+	first := -1
+	for i := 0; i < len(want) || i < len(got); i++ {
+		if i >= len(want) || i >= len(got) || want[i] != got[i] {
+			first = i
+			break
+		}
+	}
+
+	if first == -1 {
+		return ""
+	}
+
+	return fmt.Sprintf("outputs differ at position %d\n  want: %v\n  got:  %v", first+1, want, got)
+}
+
+// diffMailboxes explains which mailboxes don't have their expected values, or returns the empty string if they all
+// do.
+func diffMailboxes(want map[int]int, mailboxes []string) string {
+	addrs := []int{}
+	for addr := range want {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+
+	lines := []string{}
+	for _, addr := range addrs {
+		if addr < 0 || addr >= len(mailboxes) {
+			lines = append(lines, lmc.ErrInvalidMemory{Attempted: addr}.Error())
+			continue
+		}
+
+		if got, _ := strconv.Atoi(mailboxes[addr]); got != want[addr] {
+			lines = append(lines, fmt.Sprintf("mailbox %d: want %d, got %d", addr, want[addr], got))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,196 @@
+// Package spec runs LMC programs against test cases described in spec files. A spec file is a JSON file named
+// like name_test.json that sits next to the program name.lmc it tests, for example:
+//
+//	{
+//	    "maxCycles": 1000,
+//	    "cases": [
+//	        {"name": "three", "inputs": [3], "outputs": [9]},
+//	        {"name": "stores result", "inputs": [2], "outputs": [4], "mailboxes": {"20": 4}}
+//	    ]
+//	}
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// Suffix is the suffix of spec file names.
+const Suffix = "_test.json"
+
+// DefaultMaxCycles is the cycle budget for cases when the spec file doesn't give one.
+const DefaultMaxCycles = 10000
+
+// Suite is the set of test cases for a single program, loaded from a spec file.
+type Suite struct {
+	Path    string `json:"-"`       // Path of the spec file.
+	Program string `json:"program"` // Path of the program, relative to the spec file.
+
+	OpcodeSize  int `json:"opcodeSize"`
+	OperandSize int `json:"operandSize"`
+	MaxCycles   int `json:"maxCycles"` // Default cycle budget for every case.
+
+	// InstructionSet is the name of a built in instruction set, or the path of one relative to the spec file. The
+	// default instruction set is used if it's empty.
+	InstructionSet string `json:"instructionSet"`
+
+	// Timeout limits how long each case may run for, regardless of cycles. Zero means no limit.
+	Timeout time.Duration `json:"-"`
+
+	Cases []Case `json:"cases"`
+
+	// Coverage, if set, collects the coverage of the program across every case that is run.
+	Coverage *lmc.Coverage `json:"-"`
+
+	source       string
+	instructions []lmc.Instruction
+	set          *lmc.InstructionSet
+
+	mu       sync.Mutex
+	machines map[int][]*machine // Machines loaded with the program that aren't in use, by cycle limit.
+}
+
+// Case is a single test of a program: the inputs to give it and what it should do with them.
+type Case struct {
+	Name      string      `json:"name"`
+	Inputs    []int       `json:"inputs"`
+	Outputs   []int       `json:"outputs"`   // Expected outputs, in order.
+	Mailboxes map[int]int `json:"mailboxes"` // Expected values of mailboxes once the program has halted.
+	MaxCycles int         `json:"maxCycles"` // Cycle budget, overriding the suite's.
+}
+
+// Discover returns the paths of every spec file under the paths given. Paths that are files are returned as they
+// are.
+func Discover(paths ...string) ([]string, error) {
+	found := []string{}
+
+	for _, path := range paths {
+		err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if p == path && !info.IsDir() {
+				found = append(found, p)
+			} else if !info.IsDir() && strings.HasSuffix(p, Suffix) {
+				found = append(found, p)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return found, nil
+}
+
+// Load reads a spec file and the program it tests. If the spec doesn't name a program, it tests the .lmc file with
+// the same name as the spec, without the _test suffix.
+func Load(path string) (*Suite, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	suite := &Suite{Path: path, OpcodeSize: 1, OperandSize: 2, MaxCycles: DefaultMaxCycles}
+	if err := json.Unmarshal(bytes, suite); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	if suite.Program == "" {
+		suite.Program = strings.TrimSuffix(filepath.Base(path), Suffix) + ".lmc"
+	}
+
+	if name := suite.InstructionSet; name != "" {
+		if _, ok := lmc.InstructionSets[name]; !ok {
+			name = filepath.Join(filepath.Dir(path), name)
+		}
+
+		suite.set, err = lmc.LoadInstructionSet(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+	}
+
+	code, err := ioutil.ReadFile(filepath.Join(filepath.Dir(path), suite.Program))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := suite.Compile(string(code)); err != nil {
+		return nil, fmt.Errorf("%s: %s", suite.Program, err)
+	}
+
+	for i, c := range suite.Cases {
+		if c.Name == "" {
+			suite.Cases[i].Name = fmt.Sprintf("case %d", i+1)
+		}
+	}
+
+	return suite, nil
+}
+
+// Compile parses and checks the program the suite tests. Load does this for the program named in the spec file.
+func (s *Suite) Compile(code string) error {
+	parser := lmc.NewParser(lmc.NewLexer(code))
+	parser.InstructionSet = s.Set()
+
+	instructions, err := parser.Parse()
+	if err != nil {
+		return err
+	}
+
+	if errs := s.Set().Validate(instructions, s.OpcodeSize, s.OperandSize); len(errs) != 0 {
+		return errs[0]
+	}
+
+	s.source = code
+	s.instructions = instructions
+
+	s.mu.Lock()
+	s.machines = nil
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Set returns the instruction set the program is written in.
+func (s *Suite) Set() *lmc.InstructionSet {
+	if s.set == nil {
+		return lmc.DefaultInstructionSet
+	}
+
+	return s.set
+}
+
+// Source returns the source code of the compiled program.
+func (s *Suite) Source() string {
+	return s.source
+}
+
+// Instructions returns the instructions of the compiled program.
+func (s *Suite) Instructions() []lmc.Instruction {
+	return s.instructions
+}
+
+// SourceMap returns a map from the mailboxes of the assembled program back to its instructions.
+func (s *Suite) SourceMap() lmc.SourceMap {
+	_, sourceMap := s.Set().AssembleWithSourceMap(s.instructions, s.OpcodeSize, s.OperandSize)
+	return sourceMap
+}
+
+// Name returns the name of the suite, which is the path of the spec file without its suffix.
+func (s *Suite) Name() string {
+	return strings.TrimSuffix(s.Path, Suffix)
+}
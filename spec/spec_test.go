@@ -0,0 +1,93 @@
+package spec
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExamples(t *testing.T) {
+	paths, err := Discover("../examples")
+	assert.NoError(t, err)
+	assert.Len(t, paths, 6)
+
+	suites := []*Suite{}
+	for _, path := range paths {
+		suite, err := Load(path)
+		assert.NoError(t, err, "not expecting error loading %s", path)
+		suites = append(suites, suite)
+	}
+
+	for _, result := range Run(suites, 4) {
+		assert.True(t, result.Passed, "expecting %s %s to pass: %s", result.Suite, result.Case, result.Failure)
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spec")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "double.lmc"), []byte(`
+		INP
+		STA n
+		ADD n
+		OUT
+loop    BRA loop
+n       DAT`), 0644))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "double_test.json"), []byte(`{
+		"maxCycles": 50,
+		"cases": [
+			{"inputs": [2], "outputs": [5], "mailboxes": {"5": 3}},
+			{"name": "no input", "outputs": [4]}
+		]
+	}`), 0644))
+
+	suite, err := Load(filepath.Join(dir, "double_test.json"))
+	assert.NoError(t, err)
+
+	results := Run([]*Suite{suite}, 2)
+	assert.Len(t, results, 2)
+
+	assert.Equal(t, "case 1", results[0].Case)
+	assert.False(t, results[0].Passed)
+	assert.Equal(t, []int{4}, results[0].Outputs)
+	assert.Equal(t, 50, results[0].Cycles)
+	assert.Equal(t, "program did not halt within 50 cycles\n"+
+		"outputs differ at position 1\n  want: [5]\n  got:  [4]\n"+
+		"mailbox 5: want 3, got 2", results[0].Failure)
+
+	assert.Equal(t, "no input", results[1].Case)
+	assert.Equal(t, "program asked for more input after 0 outputs\n"+
+		"outputs differ at position 1\n  want: [4]\n  got:  []", results[1].Failure)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, WriteJUnit(out, results))
+	assert.Contains(t, out.String(), `<testsuites tests="2" failures="2"`)
+	assert.Contains(t, out.String(), `<failure message="program did not halt within 50 cycles">`)
+}
+
+func TestRunReusesMachine(t *testing.T) {
+	suite := &Suite{OpcodeSize: 1, OperandSize: 2, MaxCycles: 100}
+	assert.NoError(t, suite.Compile("INP\nADD n\nSTA n\nOUT\nHLT\nn DAT 1"))
+
+	messages := 0
+	for _, c := range []Case{
+		{Name: "first", Inputs: []int{2}, Outputs: []int{3}, Mailboxes: map[int]int{5: 3}},
+		{Name: "second", Inputs: []int{4}, Outputs: []int{5}, Mailboxes: map[int]int{5: 5}},
+		{Name: "limit", Inputs: []int{4}, MaxCycles: 2},
+	} {
+		result := suite.Run(c, func(lmc.Msg) { messages++ })
+		assert.Equal(t, c.Name != "limit", result.Passed, "expect %s not to see earlier cases: %s", c.Name, result.Failure)
+	}
+
+	assert.True(t, messages > 0)
+	assert.Len(t, suite.machines[100], 1, "expect the cases to share a machine")
+	assert.Len(t, suite.machines[2], 1)
+}
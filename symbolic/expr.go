@@ -0,0 +1,191 @@
+package symbolic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Expr is a linear expression over the program's inputs: a constant plus a multiple of each input. Inputs are
+// numbered from zero in the order the program reads them. The zero value is the constant 0.
+type Expr struct {
+	Const  int
+	Coeffs map[int]int // Coefficient of each input, with no zero entries.
+}
+
+// Int returns a constant expression.
+func Int(n int) Expr {
+	return Expr{Const: n}
+}
+
+// Input returns the expression for the nth input read by the program, counting from zero.
+func Input(n int) Expr {
+	return Expr{Coeffs: map[int]int{n: 1}}
+}
+
+// IsConst returns true if the expression doesn't depend on any inputs.
+func (e Expr) IsConst() bool {
+	return len(e.Coeffs) == 0
+}
+
+// Add returns e + o.
+func (e Expr) Add(o Expr) Expr {
+	return e.combine(o, 1)
+}
+
+// Sub returns e - o.
+func (e Expr) Sub(o Expr) Expr {
+	return e.combine(o, -1)
+}
+
+// Neg returns -e.
+func (e Expr) Neg() Expr {
+	return Int(0).Sub(e)
+}
+
+// combine returns e + sign*o.
+func (e Expr) combine(o Expr, sign int) Expr {
+	r := Expr{Const: e.Const + sign*o.Const, Coeffs: make(map[int]int)}
+
+	for v, a := range e.Coeffs {
+		r.Coeffs[v] = a
+	}
+
+	for v, a := range o.Coeffs {
+		r.Coeffs[v] += sign * a
+		if r.Coeffs[v] == 0 {
+			delete(r.Coeffs, v)
+		}
+	}
+
+	if len(r.Coeffs) == 0 {
+		r.Coeffs = nil
+	}
+
+	return r
+}
+
+// Eval returns the value of the expression for the inputs given. Inputs missing from the slice count as zero.
+func (e Expr) Eval(inputs []int) int {
+	n := e.Const
+
+	for v, a := range e.Coeffs {
+		if v < len(inputs) {
+			n += a * inputs[v]
+		}
+	}
+
+	return n
+}
+
+// String returns the expression in a form like 2*in0 + in1 - 3.
+func (e Expr) String() string {
+	vars := []int{}
+	for v := range e.Coeffs {
+		vars = append(vars, v)
+	}
+	sort.Ints(vars)
+
+	var b strings.Builder
+	term := func(a int, s string) {
+		switch {
+		case b.Len() == 0 && a < 0:
+			b.WriteString("-")
+		case b.Len() > 0 && a < 0:
+			b.WriteString(" - ")
+		case b.Len() > 0:
+			b.WriteString(" + ")
+		}
+
+		if a < 0 {
+			a = -a
+		}
+
+		if s == "" {
+			b.WriteString(fmt.Sprint(a))
+		} else if a == 1 {
+			b.WriteString(s)
+		} else {
+			b.WriteString(fmt.Sprintf("%d*%s", a, s))
+		}
+	}
+
+	for _, v := range vars {
+		term(e.Coeffs[v], fmt.Sprintf("in%d", v))
+	}
+
+	if e.Const != 0 || b.Len() == 0 {
+		term(e.Const, "")
+	}
+
+	return b.String()
+}
+
+// Op is a comparison between two expressions.
+type Op string
+
+// Comparisons that can be made between expressions.
+const (
+	Eq Op = "=="
+	Ne Op = "!="
+	Lt Op = "<"
+	Le Op = "<="
+	Gt Op = ">"
+	Ge Op = ">="
+)
+
+// negations gives the comparison that holds exactly when each one doesn't.
+var negations = map[Op]Op{Eq: Ne, Ne: Eq, Lt: Ge, Ge: Lt, Gt: Le, Le: Gt}
+
+// Constraint is a comparison between two expressions, such as the condition for a branch to be taken or something
+// that must be true of a program's outputs.
+type Constraint struct {
+	Left  Expr
+	Op    Op
+	Right Expr
+
+	Label string // Optional description used when explaining why a constraint doesn't hold.
+}
+
+// Compare returns the constraint left op right.
+func Compare(left Expr, op Op, right Expr) Constraint {
+	return Constraint{Left: left, Op: op, Right: right}
+}
+
+// Not returns the constraint that holds exactly when c doesn't.
+func (c Constraint) Not() Constraint {
+	c.Op = negations[c.Op]
+	return c
+}
+
+// Holds returns true if the constraint is true for the inputs given.
+func (c Constraint) Holds(inputs []int) bool {
+	l, r := c.Left.Eval(inputs), c.Right.Eval(inputs)
+
+	switch c.Op {
+	case Eq:
+		return l == r
+	case Ne:
+		return l != r
+	case Lt:
+		return l < r
+	case Le:
+		return l <= r
+	case Gt:
+		return l > r
+	case Ge:
+		return l >= r
+	}
+
+	return false
+}
+
+// String returns the constraint in a form like in0 + in1 >= 10, prefixed with its label if it has one.
+func (c Constraint) String() string {
+	s := fmt.Sprintf("%s %s %s", c.Left, c.Op, c.Right)
+	if c.Label != "" {
+		return c.Label + ": " + s
+	}
+
+	return s
+}
@@ -0,0 +1,90 @@
+package symbolic
+
+import (
+	"fmt"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// Assertion returns the constraints that must hold at the end of a path for a program to be correct, usually in terms
+// of the path's outputs and the inputs it read.
+type Assertion func(p Path) []Constraint
+
+// Outputs returns an assertion that a program outputs exactly the values given, in order.
+func Outputs(want ...Expr) Assertion {
+	return func(p Path) []Constraint {
+		count := Compare(Int(len(p.Outputs)), Eq, Int(len(want)))
+		count.Label = "number of outputs"
+
+		if len(p.Outputs) != len(want) {
+			return []Constraint{count}
+		}
+
+		cons := []Constraint{}
+		for i, w := range want {
+			c := Compare(p.Outputs[i], Eq, w)
+			c.Label = fmt.Sprintf("output %d", i+1)
+			cons = append(cons, c)
+		}
+
+		return cons
+	}
+}
+
+// Result is the outcome of trying to prove an assertion.
+type Result struct {
+	// Proved is true if every path through the program was explored and satisfies the assertion, so it holds for
+	// every input in the range.
+	Proved bool
+
+	// Counterexample is a sequence of inputs for which the assertion doesn't hold, or nil if none was found. Path is the
+	// path the program takes with those inputs, and Reason explains what went wrong.
+	Counterexample []int
+	Path           *Path
+	Reason         string
+
+	Paths int // Number of paths explored.
+}
+
+// Prove checks that an assertion holds at the end of every path through a program, for every sequence of inputs in
+// the range given by the options. A path that doesn't reach a HLT, because it runs for too long or needs too many
+// inputs, counts as breaking the assertion. If the program has more paths than the options allow and no
+// counterexample is found in those explored, the result is neither proved nor has a counterexample.
+func Prove(mailboxes *lmc.Mailboxes, opts Options, assertion Assertion) Result {
+	opts = opts.withDefaults()
+
+	paths, complete := Explore(mailboxes, opts)
+	result := Result{Paths: len(paths)}
+
+	for i := range paths {
+		p := &paths[i]
+
+		if p.Err != nil {
+			if inputs, ok := solve(p.Constraints, p.Inputs, opts.Min, opts.Max); ok {
+				return result.counterexample(inputs, p, p.Err.Error())
+			}
+
+			continue
+		}
+
+		for _, c := range assertion(*p) {
+			cons := append(append([]Constraint{}, p.Constraints...), c.Not())
+
+			if inputs, ok := solve(cons, p.Inputs, opts.Min, opts.Max); ok {
+				return result.counterexample(inputs, p, fmt.Sprintf("%s does not hold", c))
+			}
+		}
+	}
+
+	result.Proved = complete
+	return result
+}
+
+// counterexample returns the result with a counterexample filled in. Only the inputs the path reads are kept.
+func (r Result) counterexample(inputs []int, p *Path, reason string) Result {
+	r.Counterexample = inputs[:p.Inputs]
+	r.Path = p
+	r.Reason = reason
+
+	return r
+}
@@ -0,0 +1,258 @@
+package symbolic
+
+// The solver decides whether a set of constraints can be satisfied by inputs in a range and finds inputs that do. The
+// inputs are integers with finite ranges, so it can always give an answer: it narrows the range of each input using
+// the constraints until nothing changes, then splits the widest range it's unsure about in half and tries each half.
+
+// relation is how a normalised constraint compares its expression to zero.
+type relation int
+
+const (
+	atLeastZero relation = iota // e >= 0
+	isZero                      // e == 0
+	notZero                     // e != 0
+)
+
+// normalised is a constraint rearranged to compare a single expression with zero.
+type normalised struct {
+	e   Expr
+	rel relation
+}
+
+func normalise(c Constraint) normalised {
+	d := c.Left.Sub(c.Right)
+
+	switch c.Op {
+	case Eq:
+		return normalised{d, isZero}
+	case Ne:
+		return normalised{d, notZero}
+	case Gt:
+		return normalised{d.Sub(Int(1)), atLeastZero}
+	case Le:
+		return normalised{d.Neg(), atLeastZero}
+	case Lt:
+		return normalised{d.Neg().Sub(Int(1)), atLeastZero}
+	}
+
+	return normalised{d, atLeastZero}
+}
+
+// interval is the range of values an input could still take.
+type interval struct {
+	lo, hi int
+}
+
+// solve finds inputs between min and max (inclusive) that satisfy every constraint, returning false if there are
+// none. The inputs are numbered from zero up to n-1; any that aren't constrained are given the value min.
+func solve(constraints []Constraint, n, min, max int) ([]int, bool) {
+	cons := make([]normalised, len(constraints))
+	for i, c := range constraints {
+		cons[i] = normalise(c)
+
+		for v := range c.Left.Coeffs {
+			if v >= n {
+				n = v + 1
+			}
+		}
+
+		for v := range c.Right.Coeffs {
+			if v >= n {
+				n = v + 1
+			}
+		}
+	}
+
+	domains := make([]interval, n)
+	for i := range domains {
+		domains[i] = interval{min, max}
+	}
+
+	return search(cons, domains)
+}
+
+// search narrows the domains, then splits one in half and searches each half if there's still a choice to make.
+func search(cons []normalised, domains []interval) ([]int, bool) {
+	if !propagate(cons, domains) {
+		return nil, false
+	}
+
+	// Split the input with the narrowest range that's still undecided and appears in a constraint.
+	split := -1
+	for _, c := range cons {
+		for v := range c.e.Coeffs {
+			d := domains[v]
+			if d.lo < d.hi && (split == -1 || d.hi-d.lo < domains[split].hi-domains[split].lo) {
+				split = v
+			}
+		}
+	}
+
+	if split == -1 {
+		values := make([]int, len(domains))
+		for i, d := range domains {
+			values[i] = d.lo
+		}
+
+		return values, true
+	}
+
+	d := domains[split]
+	mid := d.lo + (d.hi-d.lo)/2
+
+	for _, half := range []interval{{d.lo, mid}, {mid + 1, d.hi}} {
+		next := append([]interval{}, domains...)
+		next[split] = half
+
+		if values, ok := search(cons, next); ok {
+			return values, true
+		}
+	}
+
+	return nil, false
+}
+
+// propagate narrows the domains of the inputs to remove values that can't satisfy the constraints, until nothing
+// changes. It returns false if a domain becomes empty, which means the constraints can't be satisfied.
+func propagate(cons []normalised, domains []interval) bool {
+	for changed := true; changed; {
+		changed = false
+
+		for _, c := range cons {
+			var ok, narrowed bool
+
+			switch c.rel {
+			case atLeastZero:
+				ok, narrowed = atLeast(c.e, domains)
+			case isZero:
+				ok, narrowed = atLeast(c.e, domains)
+				if ok {
+					var more bool
+					ok, more = atLeast(c.e.Neg(), domains)
+					narrowed = narrowed || more
+				}
+			case notZero:
+				ok, narrowed = nonZero(c.e, domains)
+			}
+
+			if !ok {
+				return false
+			}
+
+			changed = changed || narrowed
+		}
+	}
+
+	return true
+}
+
+// bounds returns the smallest and largest values a*x can take for x in the domain given.
+func bounds(a int, d interval) (int, int) {
+	if a >= 0 {
+		return a * d.lo, a * d.hi
+	}
+
+	return a * d.hi, a * d.lo
+}
+
+// atLeast narrows the domains so that e >= 0 could hold. Each input's term must be at least the negative of the
+// largest value the rest of the expression can take.
+func atLeast(e Expr, domains []interval) (ok, narrowed bool) {
+	max := e.Const
+	for v, a := range e.Coeffs {
+		_, hi := bounds(a, domains[v])
+		max += hi
+	}
+
+	if max < 0 {
+		return false, false
+	}
+
+	for v, a := range e.Coeffs {
+		_, hi := bounds(a, domains[v])
+		need := -(max - hi) // a*x >= need
+
+		d := domains[v]
+		if a > 0 {
+			if lo := ceilDiv(need, a); lo > d.lo {
+				d.lo = lo
+			}
+		} else {
+			if hi := floorDiv(need, a); hi < d.hi {
+				d.hi = hi
+			}
+		}
+
+		if d.lo > d.hi {
+			return false, true
+		}
+
+		if d != domains[v] {
+			domains[v] = d
+			narrowed = true
+		}
+	}
+
+	return true, narrowed
+}
+
+// nonZero narrows the domains so that e != 0 could hold. This only helps once all but one input is decided, when the
+// value that would make e zero can be removed if it's at either end of the last input's range.
+func nonZero(e Expr, domains []interval) (ok, narrowed bool) {
+	rest := e.Const
+	free := -1
+
+	for v, a := range e.Coeffs {
+		d := domains[v]
+		if d.lo == d.hi {
+			rest += a * d.lo
+			continue
+		}
+
+		if free != -1 {
+			return true, false
+		}
+
+		free = v
+	}
+
+	if free == -1 {
+		return rest != 0, false
+	}
+
+	// a*x + rest == 0 only when x == -rest/a.
+	a := e.Coeffs[free]
+	if rest%a != 0 {
+		return true, false
+	}
+
+	x := -rest / a
+	d := domains[free]
+
+	switch x {
+	case d.lo:
+		d.lo++
+	case d.hi:
+		d.hi--
+	default:
+		return true, false
+	}
+
+	domains[free] = d
+	return d.lo <= d.hi, true
+}
+
+// floorDiv divides rounding towards negative infinity.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+
+	return q
+}
+
+// ceilDiv divides rounding towards positive infinity.
+func ceilDiv(a, b int) int {
+	return -floorDiv(-a, b)
+}
@@ -0,0 +1,252 @@
+// Package symbolic runs LMC programs on symbolic inputs to find out what they do for every input at once. Each INP
+// reads a new unknown, the accumulator and mailboxes hold linear expressions over those unknowns, and a BRZ or BRP
+// that depends on them splits the run into one path where the branch is taken and one where it isn't. Each path
+// records the constraints its inputs must satisfy to follow it.
+//
+// LMC only adds and subtracts, so every value is linear in the inputs and the constraints can be solved exactly for
+// inputs in a finite range, without an external solver. Values that don't depend on the inputs are treated as plain
+// numbers, so self-modifying code works as long as the instructions it writes don't depend on the inputs.
+//
+// The accumulator is treated as an unbounded integer, as Computer does while it is in the accumulator.
+package symbolic
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/ollybritton/go-lmc"
+)
+
+// Defaults used for zero fields of Options.
+const (
+	DefaultMaxInputs = 10
+	DefaultMaxSteps  = 10000
+	DefaultMaxPaths  = 1000
+)
+
+// Options controls how a program is explored. Zero fields take their default values.
+type Options struct {
+	OpcodeSize  int // Defaults to 1.
+	OperandSize int // Defaults to 2.
+
+	// Min and Max are the range of every input, inclusive. Max defaults to the largest value a mailbox can hold.
+	Min, Max int
+
+	MaxInputs int // Number of inputs a path can read before it's stopped with lmc.ErrInputExhausted.
+	MaxSteps  int // Number of instructions a path can execute before it's stopped with lmc.ErrCycleLimit.
+	MaxPaths  int // Number of paths to explore before giving up.
+}
+
+// withDefaults returns the options with zero fields replaced by their defaults.
+func (o Options) withDefaults() Options {
+	if o.OpcodeSize == 0 {
+		o.OpcodeSize = 1
+	}
+
+	if o.OperandSize == 0 {
+		o.OperandSize = 2
+	}
+
+	if o.Max == 0 {
+		o.Max = int(math.Pow(10, float64(o.OpcodeSize+o.OperandSize))) - 1
+	}
+
+	if o.MaxInputs == 0 {
+		o.MaxInputs = DefaultMaxInputs
+	}
+
+	if o.MaxSteps == 0 {
+		o.MaxSteps = DefaultMaxSteps
+	}
+
+	if o.MaxPaths == 0 {
+		o.MaxPaths = DefaultMaxPaths
+	}
+
+	return o
+}
+
+// Path is one way through a program, followed by every input that satisfies its constraints.
+type Path struct {
+	Inputs      int    // Number of inputs read.
+	Outputs     []Expr // Values output, in order.
+	Constraints []Constraint
+	Steps       int // Number of instructions executed.
+
+	// Err is why the path stopped early, or nil if it reached a HLT.
+	Err error
+}
+
+// ErrSymbolicInstruction is returned when a program tries to execute a mailbox whose contents depend on the inputs.
+type ErrSymbolicInstruction struct {
+	Addr  int
+	Value Expr
+}
+
+// Error returns the error string for ErrSymbolicInstruction.
+func (e ErrSymbolicInstruction) Error() string {
+	return fmt.Sprintf("mailbox %d holds %s, which depends on the inputs, and was executed", e.Addr, e.Value)
+}
+
+// state is a path part of the way through being explored.
+type state struct {
+	Path
+
+	pc  int
+	acc Expr
+	mem []Expr
+}
+
+// fork returns a copy of the state that can be changed independently, with an extra constraint.
+func (s *state) fork(c Constraint) *state {
+	f := *s
+	f.mem = append([]Expr{}, s.mem...)
+	f.Outputs = append([]Expr{}, s.Outputs...)
+	f.Constraints = append(append([]Constraint{}, s.Constraints...), c)
+
+	return &f
+}
+
+// Explore finds every path through a program, up to the limit in the options. The second result is false if there
+// were more paths than the limit, in which case the paths found so far are returned.
+func Explore(mailboxes *lmc.Mailboxes, opts Options) ([]Path, bool) {
+	opts = opts.withDefaults()
+
+	size := int(math.Pow(10, float64(opts.OpcodeSize+opts.OperandSize)))
+	mem := make([]Expr, size)
+
+	for i := range mem {
+		val, err := mailboxes.Get(i)
+		if err != nil {
+			break
+		}
+
+		n, _ := strconv.Atoi(val)
+		mem[i] = Int(n)
+	}
+
+	paths := []Path{}
+	stack := []*state{{mem: mem}}
+
+	for len(stack) > 0 {
+		if len(paths) >= opts.MaxPaths {
+			return paths, false
+		}
+
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		next := s.run(opts)
+		if next == nil {
+			paths = append(paths, s.Path)
+			continue
+		}
+
+		stack = append(stack, next...)
+	}
+
+	return paths, true
+}
+
+// run executes the state until its path ends or it reaches a branch that could go either way. It returns nil if the
+// path ended, or a state for each way the branch could go.
+func (s *state) run(opts Options) []*state {
+	scale := int(math.Pow(10, float64(opts.OperandSize)))
+
+	for {
+		if s.pc < 0 || s.pc >= len(s.mem) {
+			s.Err = lmc.ErrInvalidMemory{Attempted: s.pc}
+			return nil
+		}
+
+		if s.Steps >= opts.MaxSteps {
+			s.Err = lmc.ErrCycleLimit{Limit: opts.MaxSteps}
+			return nil
+		}
+
+		val := s.mem[s.pc]
+		if !val.IsConst() {
+			s.Err = ErrSymbolicInstruction{s.pc, val}
+			return nil
+		}
+
+		s.Steps++
+		opcode, operand := val.Const/scale, val.Const%scale
+
+		switch opcode {
+		case 1, 2, 3, 5:
+			if operand >= len(s.mem) {
+				s.Err = lmc.ErrInvalidMemory{Attempted: operand}
+				return nil
+			}
+		}
+
+		switch opcode {
+		case 1: // ADD
+			s.acc = s.acc.Add(s.mem[operand])
+		case 2: // SUB
+			s.acc = s.acc.Sub(s.mem[operand])
+		case 3: // STA
+			s.mem[operand] = s.acc
+		case 5: // LDA
+			s.acc = s.mem[operand]
+
+		case 6: // BRA
+			s.pc = operand
+			continue
+
+		case 7, 8: // BRZ, BRP
+			taken := Compare(s.acc, Eq, Int(0))
+			if opcode == 8 {
+				taken = Compare(s.acc, Ge, Int(0))
+			}
+
+			if s.acc.IsConst() {
+				if taken.Holds(nil) {
+					s.pc = operand
+					continue
+				}
+
+				break
+			}
+
+			next := []*state{}
+			for _, c := range []Constraint{taken.Not(), taken} {
+				f := s.fork(c)
+				if _, ok := solve(f.Constraints, f.Inputs, opts.Min, opts.Max); !ok {
+					continue
+				}
+
+				f.pc++
+				if c.Op == taken.Op {
+					f.pc = operand
+				}
+
+				next = append(next, f)
+			}
+
+			return next
+
+		case 9: // INP, OUT
+			switch operand {
+			case 1:
+				if s.Inputs >= opts.MaxInputs {
+					s.Err = lmc.ErrInputExhausted{Outputs: len(s.Outputs)}
+					return nil
+				}
+
+				s.acc = Input(s.Inputs)
+				s.Inputs++
+			case 2:
+				s.Outputs = append(s.Outputs, s.acc)
+			}
+		}
+
+		if opcode == 0 && operand == 0 {
+			return nil
+		}
+
+		s.pc++
+	}
+}
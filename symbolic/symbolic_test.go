@@ -0,0 +1,252 @@
+package symbolic
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/ollybritton/go-lmc"
+	"github.com/stretchr/testify/assert"
+)
+
+func assemble(t *testing.T, code string) *lmc.Mailboxes {
+	t.Helper()
+
+	instructions, err := lmc.NewParser(lmc.NewLexer(code)).Parse()
+	assert.NoError(t, err)
+	assert.Empty(t, lmc.Validate(instructions, 1, 2))
+
+	return lmc.Assemble(instructions, 1, 2)
+}
+
+// run runs a program concretely, to check counterexamples against the real computer.
+func run(t *testing.T, code string, inputs []int) []int {
+	t.Helper()
+
+	computer, err := lmc.NewComputerFromCode(code, 1, 2)
+	assert.NoError(t, err)
+
+	result, err := computer.RunWithInputs(inputs, 10000)
+	assert.NoError(t, err)
+
+	return result.Outputs
+}
+
+const add = `
+        INP
+        STA x
+        INP
+        ADD x
+        OUT
+        HLT
+x       DAT
+`
+
+const max = `
+        INP
+        STA a
+        INP
+        STA b
+        SUB a
+        BRP second
+        LDA a
+        OUT
+        HLT
+second  LDA b
+        OUT
+        HLT
+a       DAT
+b       DAT
+`
+
+// countdown outputs every number from its input down to 1.
+const countdown = `
+        INP
+loop    BRZ done
+        OUT
+        SUB one
+        BRA loop
+done    HLT
+one     DAT 1
+`
+
+func TestExprString(t *testing.T) {
+	tests := []struct {
+		e    Expr
+		want string
+	}{
+		{Int(0), "0"},
+		{Int(-3), "-3"},
+		{Input(0), "in0"},
+		{Input(1).Add(Input(0)).Add(Input(0)).Sub(Int(3)), "2*in0 + in1 - 3"},
+		{Input(2).Neg().Add(Int(5)), "-in2 + 5"},
+		{Input(0).Sub(Input(0)), "0"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.e.String())
+	}
+}
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints []Constraint
+		n, min, max int
+		ok          bool
+	}{
+		{"unconstrained", nil, 2, 0, 9, true},
+		{"sum", []Constraint{Compare(Input(0).Add(Input(1)), Eq, Int(17))}, 2, 0, 9, true},
+		{"sum too large", []Constraint{Compare(Input(0).Add(Input(1)), Eq, Int(19))}, 2, 0, 9, false},
+		{"between", []Constraint{Compare(Input(0), Gt, Int(3)), Compare(Input(0), Lt, Int(5))}, 1, 0, 9, true},
+		{"empty range", []Constraint{Compare(Input(0), Gt, Int(3)), Compare(Input(0), Lt, Int(4))}, 1, 0, 9, false},
+		{"not equal", []Constraint{Compare(Input(0), Ne, Int(0)), Compare(Input(0), Le, Int(1))}, 1, 0, 9, true},
+		{"not equal everywhere", []Constraint{Compare(Input(0), Ne, Input(1)), Compare(Input(0), Eq, Input(1))}, 2, 0, 9, false},
+		{"odd", []Constraint{Compare(Input(0).Add(Input(0)), Eq, Int(7))}, 1, 0, 9, false},
+		{"negative", []Constraint{Compare(Input(0).Sub(Input(1)), Le, Int(-998))}, 2, 0, 999, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs, ok := solve(tt.constraints, tt.n, tt.min, tt.max)
+			assert.Equal(t, tt.ok, ok)
+
+			for _, c := range tt.constraints {
+				if ok {
+					assert.True(t, c.Holds(inputs), "%s with %v", c, inputs)
+				}
+			}
+		})
+	}
+}
+
+func TestExplore(t *testing.T) {
+	paths, complete := Explore(assemble(t, max), Options{})
+	assert.True(t, complete)
+	assert.Len(t, paths, 2)
+
+	for _, p := range paths {
+		assert.Equal(t, 2, p.Inputs)
+		assert.Len(t, p.Constraints, 1)
+		assert.NoError(t, p.Err)
+	}
+
+	paths, complete = Explore(assemble(t, countdown), Options{Max: 5})
+	assert.True(t, complete)
+	assert.Len(t, paths, 6)
+
+	paths, complete = Explore(assemble(t, countdown), Options{Max: 5, MaxPaths: 3})
+	assert.False(t, complete)
+	assert.Len(t, paths, 3)
+}
+
+func TestProve(t *testing.T) {
+	result := Prove(assemble(t, add), Options{}, Outputs(Input(0).Add(Input(1))))
+	assert.True(t, result.Proved)
+	assert.Nil(t, result.Counterexample)
+
+	atLeastBoth := func(p Path) []Constraint {
+		return []Constraint{
+			Compare(p.Outputs[0], Ge, Input(0)),
+			Compare(p.Outputs[0], Ge, Input(1)),
+		}
+	}
+
+	result = Prove(assemble(t, max), Options{}, atLeastBoth)
+	assert.True(t, result.Proved)
+	assert.Equal(t, 2, result.Paths)
+}
+
+func TestProveCounterexample(t *testing.T) {
+	// Wrong when the inputs add up to more than 500.
+	result := Prove(assemble(t, add), Options{}, func(p Path) []Constraint {
+		return []Constraint{Compare(p.Outputs[0], Le, Int(500))}
+	})
+
+	assert.False(t, result.Proved)
+	assert.Equal(t, "in0 + in1 <= 500 does not hold", result.Reason)
+	assert.Len(t, result.Counterexample, 2)
+	assert.Greater(t, run(t, add, result.Counterexample)[0], 500)
+
+	// Uses BRZ rather than BRP, so outputs the first input even if the second is larger.
+	buggy := `
+        INP
+        STA a
+        INP
+        STA b
+        SUB a
+        BRZ second
+        LDA a
+        OUT
+        HLT
+second  LDA b
+        OUT
+        HLT
+a       DAT
+b       DAT
+`
+	result = Prove(assemble(t, buggy), Options{Max: 99}, func(p Path) []Constraint {
+		return []Constraint{Compare(p.Outputs[0], Ge, Input(1))}
+	})
+
+	assert.False(t, result.Proved)
+	in := result.Counterexample
+	assert.Greater(t, in[1], in[0])
+	assert.Equal(t, []int{in[0]}, run(t, buggy, in))
+}
+
+func TestProveNoHalt(t *testing.T) {
+	// Loops forever on zero.
+	loop := `
+        INP
+loop    BRZ loop
+        HLT
+`
+	result := Prove(assemble(t, loop), Options{MaxSteps: 100}, Outputs())
+	assert.False(t, result.Proved)
+	assert.Equal(t, []int{0}, result.Counterexample)
+	assert.Equal(t, "program did not halt within 100 cycles", result.Reason)
+
+	result = Prove(assemble(t, countdown), Options{Max: 20}, func(p Path) []Constraint {
+		return []Constraint{Compare(Int(len(p.Outputs)), Eq, Input(0))}
+	})
+	assert.True(t, result.Proved)
+	assert.Equal(t, 21, result.Paths)
+}
+
+func TestSelfModifying(t *testing.T) {
+	// Builds an OUT instruction and runs it, which works because the instruction doesn't depend on the input.
+	concrete := `
+        LDA out
+        STA slot
+        INP
+slot    DAT
+        HLT
+out     DAT 902
+`
+	result := Prove(assemble(t, concrete), Options{}, Outputs(Input(0)))
+	assert.True(t, result.Proved)
+
+	// Runs the input as an instruction.
+	symbolic := `
+        INP
+        STA slot
+slot    DAT
+        HLT
+`
+	result = Prove(assemble(t, symbolic), Options{}, Outputs())
+	assert.False(t, result.Proved)
+	assert.Equal(t, "mailbox 2 holds in0, which depends on the inputs, and was executed", result.Reason)
+}
+
+func TestProveSquare(t *testing.T) {
+	code, err := ioutil.ReadFile("../examples/square.lmc")
+	assert.NoError(t, err)
+
+	// The square isn't linear, but each path goes round the loop a fixed number of times so the output is a multiple
+	// of the input. Inputs 0 and 1 both go round once.
+	result := Prove(assemble(t, string(code)), Options{Max: 30}, func(p Path) []Constraint {
+		return []Constraint{Compare(p.Outputs[0], Ge, Input(0))}
+	})
+	assert.True(t, result.Proved)
+	assert.Equal(t, 30, result.Paths)
+}
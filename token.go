@@ -12,8 +12,9 @@ const (
 
 	NEWLINE TokenType = "NEWLINE"
 
-	IDENT TokenType = "IDENT"
-	INT   TokenType = "INT"
+	IDENT  TokenType = "IDENT"
+	INT    TokenType = "INT"
+	STRING TokenType = "STRING" // The literal is the contents of the string, without quotes or escapes.
 )
 
 // Token represents a small, easily categorizable chunk of text within the assembly.
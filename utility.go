@@ -1,7 +1,7 @@
 package lmc
 
 import (
-	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -25,7 +25,7 @@ func isLetter(ch byte) bool {
 func isIdentifier(s string) bool {
 	bs := []byte(s)
 
-	if !isLetter(bs[0]) {
+	if len(bs) == 0 || !isLetter(bs[0]) {
 		return false
 	}
 
@@ -43,8 +43,68 @@ func isDigit(ch byte) bool {
 	return ch >= '0' && ch <= '9'
 }
 
-// leftPadInt takes an integer and prepends zeros until it's the desired length.
+// isRadixDigit returns true if the input character is a digit in the radix given, which is at most 36.
+func isRadixDigit(ch byte, radix int) bool {
+	switch {
+	case isDigit(ch):
+		return int(ch-'0') < radix
+	case ch >= 'a' && ch <= 'z':
+		return int(ch-'a')+10 < radix
+	case ch >= 'A' && ch <= 'Z':
+		return int(ch-'A')+10 < radix
+	}
+
+	return false
+}
+
+// leftPadInt takes an integer and prepends zeros until it's the desired length. Negative numbers keep their sign in
+// front of the zeros, and numbers that are already long enough are left as they are.
 func leftPadInt(n int, size int) string {
-	s := fmt.Sprint(n)
+	return formatInt(n, 10, size)
+}
+
+// formatInt is like leftPadInt, but writes the integer in the radix given.
+func formatInt(n int, radix int, size int) string {
+	if n < 0 {
+		return "-" + leftPad(strconv.FormatInt(int64(-n), radix), size-1)
+	}
+
+	return leftPad(strconv.FormatInt(int64(n), radix), size)
+}
+
+// literalRadixes are the prefixes of integer literals written in a radix other than 10.
+var literalRadixes = map[string]int{"0x": 16, "0X": 16, "0o": 8, "0O": 8, "0b": 2, "0B": 2}
+
+// ParseInt returns the value of an integer literal, such as the operand of an instruction. Literals are decimal unless
+// they start with 0x for hexadecimal, 0o for octal or 0b for binary.
+func ParseInt(lit string) (int, error) {
+	radix := 10
+	if len(lit) > 2 {
+		if r, ok := literalRadixes[lit[:2]]; ok {
+			lit, radix = lit[2:], r
+		}
+	}
+
+	n, err := strconv.ParseInt(lit, radix, 64)
+	return int(n), err
+}
+
+// pow returns radix to the power of n, such as the number of values that fit in n digits.
+func pow(radix, n int) int {
+	result := 1
+	for i := 0; i < n; i++ {
+		result *= radix
+	}
+
+	return result
+}
+
+// leftPad prepends zeros to a string until it's the desired length. Strings that are already long enough are left as
+// they are.
+func leftPad(s string, size int) string {
+	if len(s) >= size {
+		return s
+	}
+
 	return strings.Repeat("0", size-len(s)) + s
 }